@@ -52,6 +52,23 @@ func main() {
 
 	a.Isr.ActiveAll("trigger incremental rendering by startup")
 
+	// statics are deduplicated by unique content hash
+	if err := a.Repository.StaticRepository.EnsureIndexes(ctx); err != nil {
+		slog.Error("Failed to ensure static indexes", "err", err)
+	}
+
+	// chunk uploads expired while stopped are aborted, their staged parts are discarded
+	a.Svr.ChunkUpload.Sweep(ctx)
+
+	// startup scheduled backup
+	a.Svr.BackupScheduler.Start()
+
+	// startup scheduled static gc
+	a.Svr.StaticGCService.Start()
+
+	// startup scheduled publishing, overdue schedules are applied at once
+	a.Scheduler.Start(ctx)
+
 	// startup gin server
 	addr := ":" + strconv.Itoa(cfg.Server.Port)
 	fmt.Println(`
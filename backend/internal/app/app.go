@@ -18,6 +18,7 @@ type App struct {
 	Database   *mongo.Database
 	Isr        *event.IsrEventBus
 	Script     *event.ScriptEngine
+	Scheduler  *event.PublishScheduler
 	Logger     *apm.Logger
 }
 
@@ -29,6 +30,7 @@ func New(
 	database *mongo.Database,
 	isr *event.IsrEventBus,
 	script *event.ScriptEngine,
+	scheduler *event.PublishScheduler,
 	logger *apm.Logger,
 ) *App {
 	return &App{
@@ -39,6 +41,7 @@ func New(
 		Database:   database,
 		Isr:        isr,
 		Script:     script,
+		Scheduler:  scheduler,
 		Logger:     logger,
 	}
 }
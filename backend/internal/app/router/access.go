@@ -0,0 +1,193 @@
+package router
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/svr"
+	"cc.allio/fusion/internal/token"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	AdminPathPrefix = "/api/admin"
+	// AuthUserKey the key of authenticated user in gin.Context
+	AuthUserKey = "authUser"
+)
+
+// anonymousRoutes admin routes that can be accessed without token
+var anonymousRoutes = []string{
+	routeKey(http.MethodPost, AuthPathPrefix+"/login"),
+	routeKey(http.MethodPost, AuthPathPrefix+"/restore"),
+	// caddy asks whether certificate can be issued on demand without token
+	routeKey(http.MethodGet, CaddyPathPrefix+"/ask"),
+}
+
+// collaboratorRoutes admin routes that any collaborator can be accessed
+var collaboratorRoutes = []string{
+	routeKey(http.MethodGet, MetaPathPrefix),
+	routeKey(http.MethodGet, AuthPathPrefix+"/logout"),
+	routeKey(http.MethodGet, ArticlePathPrefix),
+	routeKey(http.MethodGet, ArticlePathPrefix+"/:id"),
+	routeKey(http.MethodPost, ArticlePathPrefix+"/searchByLink"),
+	routeKey(http.MethodPost, ArticlePathPrefix+"/query"),
+	routeKey(http.MethodGet, ArticlePathPrefix+"/:id/revision"),
+	routeKey(http.MethodGet, ArticlePathPrefix+"/:id/revision/diff"),
+	routeKey(http.MethodGet, ArticlePathPrefix+"/:id/revision/:revisionId"),
+	routeKey(http.MethodGet, DraftPathPrefix),
+	routeKey(http.MethodGet, DraftPathPrefix+"/:id"),
+	routeKey(http.MethodPost, DraftPathPrefix+"/query"),
+	routeKey(http.MethodGet, CategoryPathPrefix+"/all"),
+	routeKey(http.MethodGet, TagPathPrefix+"/all"),
+	routeKey(http.MethodGet, imagePathPrefix),
+	routeKey(http.MethodGet, imagePathPrefix+"/all"),
+	routeKey(http.MethodPost, imagePathPrefix+"/upload"),
+	routeKey(http.MethodGet, imagePathPrefix+"/resize/:sign"),
+	routeKey(http.MethodPost, UploadPathPrefix+"/chunk"),
+	routeKey(http.MethodGet, UploadPathPrefix+"/chunk/:id"),
+	routeKey(http.MethodPut, UploadPathPrefix+"/chunk/:id/:part"),
+	routeKey(http.MethodPost, UploadPathPrefix+"/chunk/:id/complete"),
+	routeKey(http.MethodDelete, UploadPathPrefix+"/chunk/:id"),
+	routeKey(http.MethodGet, FileManagerPathPrefix),
+	routeKey(http.MethodPost, FileManagerPathPrefix+"/dir"),
+	routeKey(http.MethodPost, FileManagerPathPrefix+"/upload"),
+	routeKey(http.MethodGet, CollaboratorPathPrefix+"/list"),
+}
+
+// permissionRoutes admin routes that collaborator need specifies permission
+var permissionRoutes = map[string]domain.Permission{
+	routeKey(http.MethodPost, ArticlePathPrefix):              domain.ArticleCreatePermission,
+	routeKey(http.MethodDelete, ArticlePathPrefix+"/:id"):     domain.ArticleDeletePermission,
+	routeKey(http.MethodPut, ArticlePathPrefix+"/:id"):        domain.ArticleUpdatePermission,
+	routeKey(http.MethodPost, DraftPathPrefix+"/publish/:id"): domain.DraftPublishPermission,
+	routeKey(http.MethodPost, DraftPathPrefix+"/:id"):         domain.DraftCreatePermission,
+	routeKey(http.MethodDelete, DraftPathPrefix+"/:id"):       domain.DraftDeletePermission,
+	routeKey(http.MethodPut, DraftPathPrefix+"/:id"):          domain.DraftUpdatePermission,
+	routeKey(http.MethodDelete, imagePathPrefix+"/:sign"):     domain.ImgDeletePermission,
+	routeKey(http.MethodPut, FileManagerPathPrefix+"/move"):   domain.ImgDeletePermission,
+	routeKey(http.MethodDelete, FileManagerPathPrefix):        domain.ImgDeletePermission,
+	// restore revision overwrites article
+	routeKey(http.MethodPost, ArticlePathPrefix+"/:id/revision/:revisionId/restore"): domain.ArticleUpdatePermission,
+	// cancel scheduled publish of draft
+	routeKey(http.MethodDelete, DraftPathPrefix+"/publish/:id"): domain.DraftPublishPermission,
+	// cancel schedule of article
+	routeKey(http.MethodDelete, ArticlePathPrefix+"/:id/schedule"): domain.ArticleUpdatePermission,
+}
+
+// AccessGuard authentication and authorization for all admin routes
+type AccessGuard struct {
+	Cfg          *config.Config
+	UserService  *svr.UserService
+	TokenService *svr.TokenService
+
+	// findToken and findUser default to TokenService and UserService, tests replace them without mongo
+	findToken func(token string) (*domain.Token, error)
+	findUser  func(id uint64) (*domain.User, error)
+}
+
+var AccessGuardSet = wire.NewSet(wire.Struct(new(AccessGuard), "Cfg", "UserService", "TokenService"))
+
+// Handle returns middleware that validate request token, put authenticated user into gin.Context
+// and check collaborator permissions. routes outside AdminPathPrefix keep anonymous.
+func (a *AccessGuard) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// route not found will be handled by NoRoute
+		fullPath := c.FullPath()
+		if lo.IsEmpty(fullPath) || !strings.HasPrefix(fullPath, AdminPathPrefix) {
+			c.Next()
+			return
+		}
+		key := routeKey(c.Request.Method, fullPath)
+		if lo.Contains(anonymousRoutes, key) {
+			c.Next()
+			return
+		}
+		user, err := a.authenticate(c)
+		if err != nil {
+			slog.Debug("Failed to authenticate request", "err", err, "route", key)
+			Write(c, AuthenticationError(err))
+			c.Abort()
+			return
+		}
+		if !a.authorize(user, key) {
+			Write(c, Error(http.StatusForbidden, errors.New("无权限访问")))
+			c.Abort()
+			return
+		}
+		c.Set(AuthUserKey, user)
+		c.Next()
+	}
+}
+
+// authenticate parse jwt token from request header and find the latest user
+func (a *AccessGuard) authenticate(c *gin.Context) (*domain.User, error) {
+	tokenString := token.GetTokenByRequest(c)
+	if lo.IsEmpty(tokenString) {
+		return nil, errors.New("无登录凭证")
+	}
+	jwtClaims, err := token.ParseJwtToken(tokenString, a.Cfg.Token.SignedKey)
+	if err != nil {
+		return nil, errors.Join(errors.New("登录凭证无效"), err)
+	}
+	claims, ok := jwtClaims.(*token.Claims)
+	if !ok {
+		return nil, errors.New("登录凭证无效")
+	}
+	findToken, findUser := a.TokenService.FindAvailableToken, a.UserService.GetUserById
+	if a.findToken != nil {
+		findToken = a.findToken
+	}
+	if a.findUser != nil {
+		findUser = a.findUser
+	}
+	// token maybe disabled by logout or restore
+	if _, err := findToken(tokenString); err != nil {
+		return nil, errors.Join(errors.New("登录凭证已失效"), err)
+	}
+	// permissions must be the latest, rather than obtain from token
+	user, err := findUser(claims.Id)
+	if err != nil {
+		return nil, errors.Join(errors.New("用户不存在"), err)
+	}
+	return user, nil
+}
+
+// authorize admin can access all routes, collaborator will be checked through domain.User Permissions
+func (a *AccessGuard) authorize(user *domain.User, key string) bool {
+	if user.Id == svr.AdminId || user.Type == domain.AdminUserType {
+		return true
+	}
+	if lo.Contains(collaboratorRoutes, key) {
+		return true
+	}
+	if lo.Contains(user.Permissions, domain.AllPermission) {
+		return true
+	}
+	permission, ok := permissionRoutes[key]
+	if !ok {
+		return false
+	}
+	return lo.Contains(user.Permissions, permission)
+}
+
+// GetAuthUser get authenticated user from gin.Context, returns nil if not exist
+func GetAuthUser(c *gin.Context) *domain.User {
+	value, exists := c.Get(AuthUserKey)
+	if !exists {
+		return nil
+	}
+	user, ok := value.(*domain.User)
+	if !ok {
+		return nil
+	}
+	return user
+}
+
+func routeKey(method string, path string) string {
+	return method + "-" + path
+}
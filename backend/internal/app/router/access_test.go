@@ -0,0 +1,103 @@
+package router
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/token"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSignedKey = "access-guard-test"
+
+func newTestAccessGuard(users map[uint64]*domain.User, disabled map[string]bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	guard := &AccessGuard{
+		Cfg: &config.Config{Token: config.Token{SignedKey: testSignedKey}},
+		findToken: func(tokenString string) (*domain.Token, error) {
+			if disabled[tokenString] {
+				return nil, errors.New("token disabled")
+			}
+			return &domain.Token{Token: tokenString}, nil
+		},
+		findUser: func(id uint64) (*domain.User, error) {
+			if user, ok := users[id]; ok {
+				return user, nil
+			}
+			return nil, errors.New("user not found")
+		},
+	}
+	engine := gin.New()
+	engine.Use(guard.Handle())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	engine.POST(AuthPathPrefix+"/login", ok)
+	engine.GET(CaddyPathPrefix+"/ask", ok)
+	engine.GET(CollaboratorPathPrefix+"/list", ok)
+	engine.PUT(ArticlePathPrefix+"/:id", ok)
+	engine.GET(BackupPathPrefix+"/export", ok)
+	engine.GET(PublicPathPrefix+"/article", ok)
+	return engine
+}
+
+func signTestToken(t *testing.T, id uint64) string {
+	now := float64(time.Now().Unix())
+	signed, err := token.CreateJwtToken(token.Claims{Id: id, Iat: now, Nbf: now, Exp: now + 3600}, testSignedKey)
+	assert.NoError(t, err)
+	return signed
+}
+
+func serveAccess(engine *gin.Engine, method string, path string, tokenString string) int {
+	request := httptest.NewRequest(method, path, nil)
+	if tokenString != "" {
+		request.Header.Set(token.TOKEN_LITERAL, tokenString)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func TestAccessGuard_Handle(t *testing.T) {
+	users := map[uint64]*domain.User{
+		0: {Id: 0, Type: domain.AdminUserType},
+		2: {Id: 2, Type: domain.CollaborateUserType},
+		3: {Id: 3, Type: domain.CollaborateUserType, Permissions: []domain.Permission{domain.ArticleUpdatePermission}},
+		4: {Id: 4, Type: domain.CollaborateUserType, Permissions: []domain.Permission{domain.AllPermission}},
+		5: {Id: 5, Type: domain.CollaborateUserType, Permissions: []domain.Permission{domain.ArticleUpdatePermission}},
+	}
+	admin, collaborator, updater, all := signTestToken(t, 0), signTestToken(t, 2), signTestToken(t, 3), signTestToken(t, 4)
+	disabled, missing := signTestToken(t, 5), signTestToken(t, 9)
+	engine := newTestAccessGuard(users, map[string]bool{disabled: true})
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		code   int
+	}{
+		{"anonymous login", http.MethodPost, AuthPathPrefix + "/login", "", http.StatusOK},
+		{"anonymous caddy ask", http.MethodGet, CaddyPathPrefix + "/ask", "", http.StatusOK},
+		{"public route", http.MethodGet, PublicPathPrefix + "/article", "", http.StatusOK},
+		{"missing token", http.MethodGet, CollaboratorPathPrefix + "/list", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, CollaboratorPathPrefix + "/list", "invalid", http.StatusUnauthorized},
+		{"disabled token", http.MethodPut, ArticlePathPrefix + "/1", disabled, http.StatusUnauthorized},
+		{"deleted user", http.MethodGet, CollaboratorPathPrefix + "/list", missing, http.StatusUnauthorized},
+		{"admin route by admin", http.MethodGet, BackupPathPrefix + "/export", admin, http.StatusOK},
+		{"collaborator route", http.MethodGet, CollaboratorPathPrefix + "/list", collaborator, http.StatusOK},
+		{"permission denied", http.MethodPut, ArticlePathPrefix + "/1", collaborator, http.StatusForbidden},
+		{"permission granted", http.MethodPut, ArticlePathPrefix + "/1", updater, http.StatusOK},
+		{"admin route by collaborator", http.MethodGet, BackupPathPrefix + "/export", updater, http.StatusForbidden},
+		{"all permission", http.MethodPut, ArticlePathPrefix + "/1", all, http.StatusOK},
+		{"all permission admin route", http.MethodGet, BackupPathPrefix + "/export", all, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.code, serveAccess(engine, c.method, c.path, c.token))
+		})
+	}
+}
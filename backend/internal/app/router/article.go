@@ -6,19 +6,23 @@ import (
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/internal/event"
 	"cc.allio/fusion/internal/svr"
+	"cc.allio/fusion/pkg/diff"
 	"cc.allio/fusion/pkg/web"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"net/http"
 )
 
 const ArticlePathPrefix = "/api/admin/article"
 
 type ArticleRoute struct {
-	Cfg        *config.Config
-	ArticleSvr *svr.ArticleService
-	Isr        *event.IsrEventBus
-	Script     *event.ScriptEngine
+	Cfg         *config.Config
+	ArticleSvr  *svr.ArticleService
+	RevisionSvr *svr.RevisionService
+	ScheduleSvr *svr.ScheduleService
+	Isr         *event.IsrEventBus
+	Script      *event.ScriptEngine
 }
 
 var ArticleRouterSet = wire.NewSet(wire.Struct(new(ArticleRoute), "*"))
@@ -109,6 +113,9 @@ func (a *ArticleRoute) UpdateArticleById(c *gin.Context) *R {
 	id := web.ParseNumberForPath(c, "id", 0)
 	a.Script.DispatchBeforeUpdateArticleEvent(article)
 	updated := a.ArticleSvr.UpdateById(uint64(id), article)
+	if article.PublishAt != nil || article.UnpublishAt != nil {
+		a.ScheduleSvr.Notify()
+	}
 	a.Isr.ActiveAll("trigger incremental rendering by update article", article)
 	a.Script.DispatchAfterUpdateArticleEvent(article, updated)
 	return Ok(updated)
@@ -138,6 +145,9 @@ func (a *ArticleRoute) CreateArticle(c *gin.Context) *R {
 	if err != nil {
 		return InternalError(err)
 	}
+	if article.PublishAt != nil || article.UnpublishAt != nil {
+		a.ScheduleSvr.Notify()
+	}
 	a.Script.DispatchAfterUpdateArticleEvent(article, create)
 	a.Isr.ActiveAll("trigger incremental rendering by create article", article)
 	return Ok(create)
@@ -182,6 +192,156 @@ func (a *ArticleRoute) GetArticlesByLink(c *gin.Context) *R {
 	return Ok(articles)
 }
 
+// QueryArticle
+// @Summary query articles by composable filter
+// @Schemes
+// @Description query articles by nested and, or and not filters of tags, category, author, word count and time ranges with multiple sort keys, facet counts of tags and category are returned along with page
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param        query   body      credential.QueryCredential  true  "query"
+// @Success 200 {object} domain.ArticleQueryResult
+// @Router /api/admin/article/query [POST]
+func (a *ArticleRoute) QueryArticle(c *gin.Context) *R {
+	query := &credential.QueryCredential{}
+	if err := c.ShouldBindJSON(query); err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	result, err := a.ArticleSvr.Query(query)
+	if errors.Is(err, svr.ErrInvalidQuery) {
+		return Error(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(result)
+}
+
+// ListRevisions
+// @Summary list revisions of article
+// @Schemes
+// @Description list revisions of article newest first, content of revisions is omitted
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param        id   path      int  true  "article id"
+// @Success 200 {object} []domain.ArticleRevision
+// @Router /api/admin/article/:id/revision [Get]
+func (a *ArticleRoute) ListRevisions(c *gin.Context) *R {
+	id := web.ParseNumberForPath(c, "id", 0)
+	revisions, err := a.RevisionSvr.List(uint64(id))
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(revisions)
+}
+
+// GetRevision
+// @Summary get revision of article
+// @Schemes
+// @Description get revision of article with content
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param        id           path      int  true  "article id"
+// @Param        revisionId   path      int  true  "revision id"
+// @Success 200 {object} domain.ArticleRevision
+// @Router /api/admin/article/:id/revision/:revisionId [Get]
+func (a *ArticleRoute) GetRevision(c *gin.Context) *R {
+	id := web.ParseNumberForPath(c, "id", 0)
+	revisionId := web.ParseNumberForPath(c, "revisionId", 0)
+	revision, err := a.RevisionSvr.Get(uint64(id), uint64(revisionId))
+	if errors.Is(err, svr.ErrRevisionNotFound) {
+		return Error(http.StatusNotFound, err)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(revision)
+}
+
+// DiffRevision
+// @Summary diff two revisions of article
+// @Schemes
+// @Description diff title and content from one revision of article to another, content is compared by 'line' or 'word'
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param        id     path      int     true   "article id"
+// @Param        from   query     int     true   "revision id diff from"
+// @Param        to     query     int     true   "revision id diff to"
+// @Param        mode   query     string  false  "line or word, default is line"
+// @Success 200 {object} credential.RevisionDiff
+// @Router /api/admin/article/:id/revision/diff [Get]
+func (a *ArticleRoute) DiffRevision(c *gin.Context) *R {
+	id := web.ParseNumberForPath(c, "id", 0)
+	from := web.ParseNumberForQuery(c, "from", 0)
+	to := web.ParseNumberForQuery(c, "to", 0)
+	mode := c.DefaultQuery("mode", diff.LineMode)
+	revisionDiff, err := a.RevisionSvr.Diff(uint64(id), uint64(from), uint64(to), mode)
+	if errors.Is(err, svr.ErrRevisionNotFound) {
+		return Error(http.StatusNotFound, err)
+	}
+	if errors.Is(err, diff.ErrUnknownMode) {
+		return Error(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(revisionDiff)
+}
+
+// RestoreRevision
+// @Summary restore revision of article
+// @Schemes
+// @Description restore title, content, tags, category and author of article to revision, it is recorded as a new revision
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param        id           path      int  true  "article id"
+// @Param        revisionId   path      int  true  "revision id"
+// @Success 200 {object} domain.Article
+// @Router /api/admin/article/:id/revision/:revisionId/restore [Post]
+func (a *ArticleRoute) RestoreRevision(c *gin.Context) *R {
+	if a.Cfg.Demo {
+		return Error(401, errors.New("演示站禁止修改文章！！"))
+	}
+	id := web.ParseNumberForPath(c, "id", 0)
+	revisionId := web.ParseNumberForPath(c, "revisionId", 0)
+	article, err := a.ArticleSvr.RestoreRevision(uint64(id), uint64(revisionId))
+	if errors.Is(err, svr.ErrRevisionNotFound) {
+		return Error(http.StatusNotFound, err)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+	a.Isr.ActiveAll("trigger incremental rendering by restore article", article)
+	a.Script.DispatchAfterUpdateArticleEvent(article, true)
+	return Ok(article)
+}
+
+// CancelSchedule
+// @Summary cancel scheduled publishing and unpublishing of article
+// @Schemes
+// @Description cancel scheduled publishing and unpublishing of article, article hidden until publish keeps hidden
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Success 200 {object} bool
+// @Router /api/admin/article/:id/schedule [Delete]
+func (a *ArticleRoute) CancelSchedule(c *gin.Context) *R {
+	if a.Cfg.Demo {
+		return Error(401, errors.New("演示站禁止修改文章！！"))
+	}
+	id := web.ParseNumberForPath(c, "id", -1)
+	canceled, err := a.ArticleSvr.CancelSchedule(uint64(id))
+	if err != nil {
+		return InternalError(err)
+	}
+	a.ScheduleSvr.Notify()
+	return Ok(canceled)
+}
+
 func (a *ArticleRoute) Register(r *gin.Engine) {
 	r.GET(ArticlePathPrefix, Handle(a.GetArticleByOption))
 	r.GET(ArticlePathPrefix+"/:id", Handle(a.GetOneById))
@@ -189,4 +349,10 @@ func (a *ArticleRoute) Register(r *gin.Engine) {
 	r.POST(ArticlePathPrefix+"", Handle(a.CreateArticle))
 	r.DELETE(ArticlePathPrefix+"/:id", Handle(a.DeleteArticle))
 	r.POST(ArticlePathPrefix+"/searchByLink", Handle(a.GetArticlesByLink))
+	r.POST(ArticlePathPrefix+"/query", Handle(a.QueryArticle))
+	r.GET(ArticlePathPrefix+"/:id/revision", Handle(a.ListRevisions))
+	r.GET(ArticlePathPrefix+"/:id/revision/diff", Handle(a.DiffRevision))
+	r.GET(ArticlePathPrefix+"/:id/revision/:revisionId", Handle(a.GetRevision))
+	r.POST(ArticlePathPrefix+"/:id/revision/:revisionId/restore", Handle(a.RestoreRevision))
+	r.DELETE(ArticlePathPrefix+"/:id/schedule", Handle(a.CancelSchedule))
 }
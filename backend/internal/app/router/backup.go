@@ -3,28 +3,26 @@ package router
 import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/event"
 	"cc.allio/fusion/internal/svr"
 	"cc.allio/fusion/pkg/web"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/goccy/go-json"
 	"github.com/google/wire"
-	"os"
+	"golang.org/x/exp/slog"
+	"io"
+	"net/http"
+	"time"
 )
 
 const BackupPathPrefix = "/api/admin/backup"
 
 type BackupRoute struct {
-	Cfg         *config.Config
-	UserSvr     *svr.UserService
-	MetaSvr     *svr.MetaService
-	VisitSvr    *svr.VisitService
-	ViewerSvr   *svr.ViewerService
-	ArticleSvr  *svr.ArticleService
-	CategorySvr *svr.CategoryService
-	TagSvr      *svr.TagService
-	DraftSvr    *svr.DraftService
-	SettingSvr  *svr.SettingService
-	StaticSvr   *svr.StaticService
+	Cfg             *config.Config
+	BackupSvr       *svr.BackupService
+	BackupScheduler *svr.BackupScheduler
+	Isr             *event.IsrEventBus
 }
 
 var BackupRouterSet = wire.NewSet(wire.Struct(new(BackupRoute), "*"))
@@ -32,81 +30,51 @@ var BackupRouterSet = wire.NewSet(wire.Struct(new(BackupRoute), "*"))
 // ExportBackup
 // @Summary 导出系统数据
 // @Schemes
-// @Description 导出系统数据
+// @Description 导出系统数据为.tar.gz归档，包含manifest、各集合ndjson以及静态文件
 // @Tags Backup
 // @Accept json
-// @Produce json
-// @Success 200 {object} nil
+// @Produce application/gzip
+// @Success 200 {file} file
 // @Router /api/admin/backup/export [Get]
 func (a *BackupRoute) ExportBackup(c *gin.Context) *R {
-	backup := &Backup{}
-	{
-		articles := a.ArticleSvr.GetAll("admin", true, false)
-		backup.Articles = articles
-	}
-	{
-		categories := a.CategorySvr.GetAllCategories()
-		backup.Categories = categories
-	}
-	{
-		tags := a.TagSvr.GetAllTags(true)
-		backup.Tags = tags
-	}
-	{
-		meta := a.MetaSvr.GetMeta()
-		backup.Meta = meta
-	}
-	{
-		drafts := a.DraftSvr.GetAll()
-		backup.Drafts = drafts
-	}
-	{
-		user := a.UserSvr.GetUser()
-		backup.User = user
-	}
-	{
-		viewers := a.ViewerSvr.GetAll()
-		backup.Viewers = viewers
-	}
-	{
-		visits := a.VisitSvr.GetAll()
-		backup.Visits = visits
-	}
-	{
-		statics := a.StaticSvr.GetAll("")
-		backup.Static = statics
-	}
-	{
-		staticSetting := a.SettingSvr.FindStaticSetting()
-		backup.Setting.Static = staticSetting
-	}
-	jsonBytes, err := json.Marshal(backup)
-	if err != nil {
-		return InternalError(err)
-	}
-	err = os.WriteFile("backup.json", jsonBytes, 0777)
-	if err != nil {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	filename := svr.SnapshotName(time.Now())
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if err := a.BackupSvr.Export(ctx, c.Writer); err != nil {
+		slog.Error("Failed to export backup", "err", err)
+		// archive already partial written, can't response error
+		if c.Writer.Written() {
+			return nil
+		}
 		return InternalError(err)
 	}
-	c.FileAttachment("backup.json", "backup.json")
-	// 删除文件
-	defer func() {
-		os.Remove("backup.json")
-	}()
 	return nil
 }
 
 // ImportBackup
 // @Summary 导入系统数据
 // @Schemes
-// @Description 导入系统数据
+// @Description 导入系统数据，支持.tar.gz归档以及旧版backup.json
 // @Tags Backup
-// @Accept json
+// @Accept multipart/form-data
 // @Produce json
 // @Param        file              formData      file        true       "file"
-// @Success 200 {object} nil
+// @Param        mode              query         string      false      "merge or replace"     merge
+// @Param        dryRun            query         bool        false      "dryRun"               false
+// @Success 200 {object} domain.BackupImportReport
 // @Router /api/admin/backup/import [Post]
 func (a *BackupRoute) ImportBackup(c *gin.Context) *R {
+	if a.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止导入数据！"))
+	}
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	mode := c.DefaultQuery("mode", domain.MergeImportMode)
+	dryRun := web.ParseBoolForQuery(c, "dryRun", false)
 	filePart, err := c.FormFile("file")
 	if err != nil {
 		return InternalError(err)
@@ -115,35 +83,104 @@ func (a *BackupRoute) ImportBackup(c *gin.Context) *R {
 	if err != nil {
 		return InternalError(err)
 	}
-	body, err := web.ReadMultipartFile(file)
+	defer file.Close()
+	report, err := a.BackupSvr.ImportFrom(ctx, file, mode, dryRun)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !dryRun {
+		a.Isr.ActiveAll("trigger incremental rendering by import backup")
+	}
+	return Ok(report)
+}
+
+// GetSnapshots
+// @Summary 获取备份快照列表
+// @Schemes
+// @Description 获取通过定时任务或手动存储的备份快照，按时间倒序
+// @Tags Backup
+// @Accept json
+// @Produce json
+// @Success 200 {object} []domain.BackupSnapshot
+// @Router /api/admin/backup/snapshot [Get]
+func (a *BackupRoute) GetSnapshots(c *gin.Context) *R {
+	snapshots, err := a.BackupSvr.ListSnapshots(c.Request.Context())
 	if err != nil {
 		return InternalError(err)
 	}
-	backup := &Backup{}
-	err = json.Unmarshal(body, backup)
+	return Ok(snapshots)
+}
+
+// CreateSnapshot
+// @Summary 立即存储备份快照
+// @Schemes
+// @Description 立即导出备份并通过存储驱动保存，随后按保留策略清理旧快照
+// @Tags Backup
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.BackupSnapshot
+// @Router /api/admin/backup/snapshot [Post]
+func (a *BackupRoute) CreateSnapshot(c *gin.Context) *R {
+	if a.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止备份数据！"))
+	}
+	snapshot, err := a.BackupScheduler.Run(c.Request.Context())
 	if err != nil {
 		return InternalError(err)
 	}
-	// TODO 需要对系统内每一个写入导入方法
+	return Ok(snapshot)
+}
+
+// DownloadSnapshot
+// @Summary 下载备份快照
+// @Schemes
+// @Description 下载备份快照
+// @Tags Backup
+// @Accept json
+// @Produce application/gzip
+// @Param        name       path      string   true  "name"
+// @Success 200 {file} file
+// @Router /api/admin/backup/snapshot/{name} [Get]
+func (a *BackupRoute) DownloadSnapshot(c *gin.Context) *R {
+	name := c.Param("name")
+	content, err := a.BackupSvr.DownloadSnapshot(c.Request.Context(), name)
+	if err != nil {
+		return Error(http.StatusNotFound, err)
+	}
+	defer content.Close()
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	if _, err := io.Copy(c.Writer, content); err != nil {
+		slog.Error("Failed to download backup snapshot", "err", err, "name", name)
+	}
 	return nil
 }
 
+// DeleteSnapshot
+// @Summary 删除备份快照
+// @Schemes
+// @Description 删除备份快照
+// @Tags Backup
+// @Accept json
+// @Produce json
+// @Param        name       path      string   true  "name"
+// @Success 200 {object} bool
+// @Router /api/admin/backup/snapshot/{name} [Delete]
+func (a *BackupRoute) DeleteSnapshot(c *gin.Context) *R {
+	if a.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止删除备份！"))
+	}
+	if err := a.BackupSvr.DeleteSnapshot(c.Request.Context(), c.Param("name")); err != nil {
+		return InternalError(err)
+	}
+	return Ok(true)
+}
+
 func (a *BackupRoute) Register(r *gin.Engine) {
 	r.GET(BackupPathPrefix+"/export", Handle(a.ExportBackup))
 	r.POST(BackupPathPrefix+"/import", Handle(a.ImportBackup))
-}
-
-type Backup struct {
-	Articles   []*domain.Article  `json:"articles"`
-	Categories []*domain.Category `json:"categories"`
-	Tags       []string           `json:"tags"`
-	Meta       *domain.Meta       `json:"meta"`
-	Drafts     []*domain.Draft    `json:"drafts"`
-	User       *domain.User       `json:"user"`
-	Viewers    []*domain.Viewer   `json:"viewers"`
-	Visits     []*domain.Visit    `json:"visits"`
-	Static     []*domain.Static   `json:"static"`
-	Setting    struct {
-		Static *domain.StaticSetting `json:"static"`
-	} `json:"setting"`
+	r.GET(BackupPathPrefix+"/snapshot", Handle(a.GetSnapshots))
+	r.POST(BackupPathPrefix+"/snapshot", Handle(a.CreateSnapshot))
+	r.GET(BackupPathPrefix+"/snapshot/:name", Handle(a.DownloadSnapshot))
+	r.DELETE(BackupPathPrefix+"/snapshot/:name", Handle(a.DeleteSnapshot))
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"net/http"
+	"time"
 )
 
 const DraftPathPrefix = "/api/admin/draft"
@@ -18,6 +19,7 @@ const DraftPathPrefix = "/api/admin/draft"
 type DraftRoute struct {
 	Cfg          *config.Config
 	DraftService *svr.DraftService
+	ScheduleSvr  *svr.ScheduleService
 	Isr          *event.IsrEventBus
 	Script       *event.ScriptEngine
 }
@@ -156,11 +158,11 @@ func (d *DraftRoute) DeleteDraft(c *gin.Context) *R {
 // PublishDraft
 // @Summary publish and update draft
 // @Schemes
-// @Description publish and update draft
+// @Description publish and update draft, draft is scheduled and returned instead when publishAt is in the future
 // @Tags Draft
 // @Accept json
 // @Produce json
-// @Param        draft   body      domain.Draft   true  "draft"
+// @Param        draft   body      credential.DraftPublishCredential   true  "draft"
 // @Success 200 {object} domain.Draft
 // @Router /api/admin/draft/publish/:id [Post]
 func (d *DraftRoute) PublishDraft(c *gin.Context) *R {
@@ -173,6 +175,15 @@ func (d *DraftRoute) PublishDraft(c *gin.Context) *R {
 		return InternalError(err)
 	}
 	d.Script.DispatchBeforeUpdateDraftEvent(option)
+	if option.PublishAt != nil && option.PublishAt.After(time.Now()) {
+		draft, err := d.DraftService.SchedulePublish(int64(id), option)
+		if err != nil {
+			return InternalError(err)
+		}
+		d.ScheduleSvr.Notify()
+		d.Script.DispatchAfterUpdateDraftEvent(draft)
+		return Ok(draft)
+	}
 	newDraft, err := d.DraftService.Publish(int64(id), option)
 	if err != nil {
 		return InternalError(err)
@@ -182,8 +193,56 @@ func (d *DraftRoute) PublishDraft(c *gin.Context) *R {
 	return Ok(newDraft)
 }
 
+// QueryDraft
+// @Summary query drafts by composable filter
+// @Schemes
+// @Description query drafts by nested and, or and not filters of tags, category, author, word count and time ranges with multiple sort keys, facet counts of tags and category are returned along with page
+// @Tags Draft
+// @Accept json
+// @Produce json
+// @Param        query   body      credential.QueryCredential  true  "query"
+// @Success 200 {object} domain.DraftQueryResult
+// @Router /api/admin/draft/query [POST]
+func (d *DraftRoute) QueryDraft(c *gin.Context) *R {
+	query := &credential.QueryCredential{}
+	if err := c.ShouldBindJSON(query); err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	result, err := d.DraftService.Query(query)
+	if errors.Is(err, svr.ErrInvalidQuery) {
+		return Error(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(result)
+}
+
+// CancelScheduledPublish
+// @Summary cancel scheduled publishing of draft
+// @Schemes
+// @Description cancel scheduled publishing of draft
+// @Tags Draft
+// @Accept json
+// @Produce json
+// @Success 200 {object} bool
+// @Router /api/admin/draft/publish/:id [Delete]
+func (d *DraftRoute) CancelScheduledPublish(c *gin.Context) *R {
+	if d.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止发布草稿！"))
+	}
+	id := web.ParseNumberForPath(c, "id", -1)
+	canceled, err := d.DraftService.CancelSchedule(int64(id))
+	if err != nil {
+		return InternalError(err)
+	}
+	d.ScheduleSvr.Notify()
+	return Ok(canceled)
+}
+
 func (d *DraftRoute) Register(r *gin.Engine) {
 	r.GET(DraftPathPrefix, Handle(d.GetDraftByOption))
+	r.POST(DraftPathPrefix+"/query", Handle(d.QueryDraft))
 
 	r.GET(DraftPathPrefix+"/:id", Handle(d.GetDraft))
 	r.PUT(DraftPathPrefix+"/:id", Handle(d.UpdateDraft))
@@ -191,4 +250,5 @@ func (d *DraftRoute) Register(r *gin.Engine) {
 	r.DELETE(DraftPathPrefix+"/:id", Handle(d.DeleteDraft))
 
 	r.POST(DraftPathPrefix+"/publish/:id", Handle(d.PublishDraft))
+	r.DELETE(DraftPathPrefix+"/publish/:id", Handle(d.CancelScheduledPublish))
 }
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"cc.allio/fusion/internal/svr"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"net/http"
+)
+
+type FeedRoute struct {
+	FeedService *svr.FeedService
+}
+
+var FeedRouterSet = wire.NewSet(wire.Struct(new(FeedRoute), "*"))
+
+// GetRss
+// @Summary get rss feed
+// @Schemes
+// @Description get rss 2.0 feed of recent public articles
+// @Tags Public
+// @Produce xml
+// @Success 200 {string} string
+// @Router /feed.xml [Get]
+func (f *FeedRoute) GetRss(c *gin.Context) *R {
+	return f.write(c, svr.RssFeedFormat, "application/rss+xml; charset=utf-8")
+}
+
+// GetAtom
+// @Summary get atom feed
+// @Schemes
+// @Description get atom feed of recent public articles
+// @Tags Public
+// @Produce xml
+// @Success 200 {string} string
+// @Router /atom.xml [Get]
+func (f *FeedRoute) GetAtom(c *gin.Context) *R {
+	return f.write(c, svr.AtomFeedFormat, "application/atom+xml; charset=utf-8")
+}
+
+// GetJson
+// @Summary get json feed
+// @Schemes
+// @Description get json feed of recent public articles
+// @Tags Public
+// @Produce json
+// @Success 200 {string} string
+// @Router /feed.json [Get]
+func (f *FeedRoute) GetJson(c *gin.Context) *R {
+	return f.write(c, svr.JsonFeedFormat, "application/feed+json; charset=utf-8")
+}
+
+func (f *FeedRoute) write(c *gin.Context, format svr.FeedFormat, contentType string) *R {
+	feed, err := f.FeedService.Feed(format)
+	if errors.Is(err, svr.ErrFeedDisabled) {
+		return Error(http.StatusNotFound, err)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+	c.Data(http.StatusOK, contentType, []byte(feed))
+	return nil
+}
+
+func (f *FeedRoute) Register(r *gin.Engine) {
+	r.GET("/feed.xml", Handle(f.GetRss))
+	r.GET("/atom.xml", Handle(f.GetAtom))
+	r.GET("/feed.json", Handle(f.GetJson))
+}
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/event"
+	"cc.allio/fusion/internal/svr"
+	"cc.allio/fusion/pkg/storage"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"net/http"
+	"path/filepath"
+)
+
+const FileManagerPathPrefix = "/api/admin/file"
+
+type FileManagerRoute struct {
+	Cfg            *config.Config
+	FileManagerSvr *svr.FileManagerService
+	Isr            *event.IsrEventBus
+}
+
+var FileManagerRouterSet = wire.NewSet(wire.Struct(new(FileManagerRoute), "*"))
+
+// List
+// @Summary 浏览目录
+// @Schemes
+// @Description 列出目录下的文件以及子目录，已记录的文件附带静态文件信息，缩略图等衍生文件不会列出
+// @Tags File
+// @Accept json
+// @Produce json
+// @Param        path   query      string   false  "目录，默认为/fusion"
+// @Success 200 {object} []domain.FileEntry
+// @Router /api/admin/file [Get]
+func (f *FileManagerRoute) List(c *gin.Context) *R {
+	dir := c.DefaultQuery("path", storage.OsPathPrefix)
+	entries, err := f.FileManagerSvr.List(c.Request.Context(), dir)
+	if err != nil {
+		return fileManagerError(err)
+	}
+	return Ok(entries)
+}
+
+// Mkdir
+// @Summary 创建目录
+// @Schemes
+// @Description 创建目录以及不存在的上级目录
+// @Tags File
+// @Accept json
+// @Produce json
+// @Param        dir   body      credential.FileDirCredential   true  "dir"
+// @Success 200 {object} bool
+// @Router /api/admin/file/dir [Post]
+func (f *FileManagerRoute) Mkdir(c *gin.Context) *R {
+	if f.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止创建目录！"))
+	}
+	dir := &credential.FileDirCredential{}
+	if err := c.Bind(dir); err != nil {
+		return InternalError(err)
+	}
+	if err := f.FileManagerSvr.Mkdir(c.Request.Context(), dir.Path); err != nil {
+		return fileManagerError(err)
+	}
+	return Ok(true)
+}
+
+// Move
+// @Summary 重命名或移动文件
+// @Schemes
+// @Description 重命名或移动文件、目录，静态文件记录以及缩略图等衍生文件随之移动。文章、草稿、自定义页面以及元信息中的原链接随之更新
+// @Tags File
+// @Accept json
+// @Produce json
+// @Param        move   body      credential.FileMoveCredential   true  "move"
+// @Success 200 {object} bool
+// @Router /api/admin/file/move [Put]
+func (f *FileManagerRoute) Move(c *gin.Context) *R {
+	if f.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止移动文件！"))
+	}
+	move := &credential.FileMoveCredential{}
+	if err := c.Bind(move); err != nil {
+		return InternalError(err)
+	}
+	rewritten, err := f.FileManagerSvr.Move(c.Request.Context(), move.Src, move.Dest)
+	if err != nil {
+		return fileManagerError(err)
+	}
+	if rewritten > 0 {
+		f.Isr.ActiveAll("trigger incremental rendering by move static")
+	}
+	return Ok(true)
+}
+
+// Delete
+// @Summary 删除文件或目录
+// @Schemes
+// @Description 删除文件或目录，同时删除对应的静态文件记录
+// @Tags File
+// @Accept json
+// @Produce json
+// @Param        path   query      string   true  "path"
+// @Success 200 {object} bool
+// @Router /api/admin/file [Delete]
+func (f *FileManagerRoute) Delete(c *gin.Context) *R {
+	if f.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止删除文件！"))
+	}
+	if err := f.FileManagerSvr.Delete(c.Request.Context(), c.Query("path")); err != nil {
+		return fileManagerError(err)
+	}
+	return Ok(true)
+}
+
+// Upload
+// @Summary 上传文件到目录
+// @Schemes
+// @Description 上传文件到指定目录并记录为静态文件，同名文件已存在时拒绝上传，内容已上传过时返回已有的静态文件
+// @Tags File
+// @Accept multipart/form-data
+// @Produce json
+// @Param        file   formData   file     true   "file"
+// @Param        path   formData   string   false  "目录，默认为/fusion"
+// @Success 200 {object} credential.FilePathCredential
+// @Router /api/admin/file/upload [Post]
+func (f *FileManagerRoute) Upload(c *gin.Context) *R {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return InternalError(err)
+	}
+	fileHeader := &storage.FileHeader{
+		Filename: header.Filename,
+		FilePath: "/" + header.Filename,
+		Ext:      filepath.Ext(header.Filename),
+		Size:     uint64(header.Size),
+		File:     file,
+		Header:   header.Header,
+	}
+	dir := c.DefaultPostForm("path", storage.OsPathPrefix)
+	static, err := f.FileManagerSvr.Upload(c.Request.Context(), dir, fileHeader, GetAuthUser(c))
+	if err != nil {
+		return fileManagerError(err)
+	}
+	return Ok(static)
+}
+
+func fileManagerError(err error) *R {
+	switch {
+	case errors.Is(err, svr.ErrFileNotFound):
+		return Error(http.StatusNotFound, err)
+	case errors.Is(err, svr.ErrFileExists):
+		return Error(http.StatusConflict, err)
+	case errors.Is(err, svr.ErrInvalidFilePath):
+		return Error(http.StatusBadRequest, err)
+	default:
+		return uploadError(err)
+	}
+}
+
+func (f *FileManagerRoute) Register(r *gin.Engine) {
+	r.GET(FileManagerPathPrefix, Handle(f.List))
+	r.POST(FileManagerPathPrefix+"/dir", Handle(f.Mkdir))
+	r.PUT(FileManagerPathPrefix+"/move", Handle(f.Move))
+	r.DELETE(FileManagerPathPrefix, Handle(f.Delete))
+	r.POST(FileManagerPathPrefix+"/upload", Handle(f.Upload))
+}
@@ -1,8 +1,6 @@
 package router
 
 import (
-	"bufio"
-	"bytes"
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/credential"
 	"cc.allio/fusion/internal/domain"
@@ -16,16 +14,18 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"io"
-	"mime/multipart"
+	"net/http"
 	"path/filepath"
 )
 
 const imagePathPrefix = "/api/admin/img"
 
 type ImgRoute struct {
-	Cfg            *config.Config
-	StaticService  *svr.StaticService
-	SettingService *svr.SettingService
+	Cfg              *config.Config
+	StaticService    *svr.StaticService
+	SettingService   *svr.SettingService
+	WatermarkService *svr.WatermarkService
+	ResizeService    *svr.ImageResizeService
 }
 
 var ImgRouterSet = wire.NewSet(wire.Struct(new(ImgRoute), "*"))
@@ -38,8 +38,8 @@ var ImgRouterSet = wire.NewSet(wire.Struct(new(ImgRoute), "*"))
 // @Accept multipart/form-data
 // @Produce json
 // @Param       file              formData     file       true     "file"
-// @Param       waterMarkText     formData     string     true     "waterMarkText"
-// @Param       withWaterMark     formData     bool       true     "withWaterMark"
+// @Param       waterMarkText     formData     string     false    "waterMarkText, default is waterMarkText of static setting"
+// @Param       withWaterMark     formData     bool       false    "withWaterMark, default is enableWaterMark of static setting"
 // @Success 200 {object} bool
 // @Router /api/admin/img/upload [POST]
 func (i *ImgRoute) Upload(c *gin.Context) *R {
@@ -69,22 +69,20 @@ func (i *ImgRoute) Upload(c *gin.Context) *R {
 		Header:   header.Header,
 	}
 
-	// determine whether file going on append watermark by file extension and with withWaterMark
-	withWaterMark := web.ParseBoolForQuery(c, "withWaterMark", false)
-	if format, err := imaging.FormatFromFilename(filename); err == nil && withWaterMark {
+	// determine whether file going on append watermark by withWaterMark, watermark is drawn after upload policy checked
+	setting := i.SettingService.FindStaticSetting()
+	transforms := make([]svr.ImageTransform, 0)
+	if web.ParseBoolForQuery(c, "withWaterMark", setting.EnableWaterMark) {
 		waterMarkText := c.Query("waterMarkText")
-		// append watermark
-		newFile, err := i.addWatermark(file, waterMarkText, format)
-		if err != nil {
-			return InternalError(err)
-		}
-		fileHeader.File = io.NopCloser(newFile)
+		transforms = append(transforms, func(ctx context.Context, src io.Reader, format imaging.Format) (io.Reader, error) {
+			return i.WatermarkService.Apply(ctx, src, format, waterMarkText)
+		})
 	}
 
 	// upload
-	static, err := i.StaticService.CreateStatic(ctx, fileHeader)
+	static, err := i.StaticService.CreateImage(ctx, fileHeader, GetAuthUser(c), transforms...)
 	if err != nil {
-		return InternalError(err)
+		return uploadError(err)
 	}
 	return Ok(static)
 }
@@ -172,20 +170,55 @@ func (i *ImgRoute) GetByOption(c *gin.Context) *R {
 	return Ok(statics)
 }
 
-// addWatermark by file and text
-func (i *ImgRoute) addWatermark(file multipart.File, text string, format imaging.Format) (io.Reader, error) {
-	watermark, err := img.NewWatermark(file)
+// ResizeUrl
+// @Summary Get signed url of resized image
+// @Schemes
+// @Description Sign resize options that not match any preset, size is still limited by max resize dimension
+// @Tags Static
+// @Accept json
+// @Produce json
+// @Param        sign       path      string   true   "sign"
+// @Param        w          query     int      false  "width, zero means not limited"
+// @Param        h          query     int      false  "height, zero means not limited"
+// @Param        fit        query     string   false  "fit or fill"
+// @Param        format     query     string   false  "jpeg, png, gif, webp or avif, empty keeps original format"
+// @Success 200 {object} string
+// @Router /api/admin/img/resize/{sign} [Get]
+func (i *ImgRoute) ResizeUrl(c *gin.Context) *R {
+	query := &struct {
+		Width  int    `form:"w"`
+		Height int    `form:"h"`
+		Fit    string `form:"fit"`
+		Format string `form:"format"`
+	}{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	static, err := i.StaticService.GetBySign(c.Param("sign"))
 	if err != nil {
-		return nil, err
+		return Error(http.StatusNotFound, err)
 	}
-	buf := &bytes.Buffer{}
-
-	err = watermark.Encode(buf, text, img.Center, format)
+	url, err := i.ResizeService.SignedUrl(static, &img.ResizeOptions{Width: query.Width, Height: query.Height, Fit: query.Fit, Format: query.Format})
 	if err != nil {
-		return nil, err
+		return Error(http.StatusBadRequest, err)
+	}
+	return Ok(url)
+}
+
+// uploadError convert upload policy violation to client error
+func uploadError(err error) *R {
+	switch {
+	case errors.Is(err, svr.ErrUploadTooLarge):
+		return Error(http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, svr.ErrUploadMimeType):
+		return Error(http.StatusUnsupportedMediaType, err)
+	case errors.Is(err, img.ErrSvgScript):
+		return Error(http.StatusBadRequest, err)
+	case errors.Is(err, svr.ErrUploadQuota):
+		return Error(http.StatusForbidden, err)
+	default:
+		return InternalError(err)
 	}
-	reader := bufio.NewReader(buf)
-	return reader, nil
 }
 
 func (i *ImgRoute) Register(r *gin.Engine) {
@@ -194,4 +227,5 @@ func (i *ImgRoute) Register(r *gin.Engine) {
 	r.DELETE(imagePathPrefix+"/all/delete", Handle(i.DeleteAll))
 	r.DELETE(imagePathPrefix+"/:sign", Handle(i.DeleteBySign))
 	r.GET(imagePathPrefix, Handle(i.GetByOption))
+	r.GET(imagePathPrefix+"/resize/:sign", Handle(i.ResizeUrl))
 }
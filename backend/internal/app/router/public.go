@@ -1,16 +1,21 @@
 package router
 
 import (
+	"bytes"
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/credential"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/internal/svr"
 	"cc.allio/fusion/pkg/env"
+	"cc.allio/fusion/pkg/markdown"
 	"cc.allio/fusion/pkg/web"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 const PublicPathPrefix = "/api/public"
@@ -25,6 +30,7 @@ type PublicRoute struct {
 	SettingService    *svr.SettingService
 	CustomPageService *svr.CustomPageService
 	CategoryService   *svr.CategoryService
+	SearchService     *svr.SearchService
 }
 
 var PublicRouterSet = wire.NewSet(wire.Struct(new(PublicRoute), "*"))
@@ -96,20 +102,40 @@ func (p *PublicRoute) getArticleByIdOrPathnameWithPassword(c *gin.Context) *R {
 	return Ok(article)
 }
 
-// getArticleByIdOrPathnameWithPassword
-// @Summary get article by id or pathname
+// searchArticle
+// @Summary full-text search articles
 // @Schemes
-// @Description get article by id or pathname
+// @Description search visible articles ranked by BM25 with highlighted snippets, chinese is supported. boost overrides weight of fields title, content, tags and category like 'title:5,content:1'
 // @Tags Public
 // @Accept json
 // @Produce json
-// @Param        value   path      string  true  "value"
-// @Success 200 {object} []domain.Article
+// @Param        value      query      string  true   "value"
+// @Param        page       query      int     false  "page"
+// @Param        pageSize   query      int     false  "pageSize"
+// @Param        boost      query      string  false  "boost"
+// @Success 200 {object} domain.ArticleSearchResult
 // @Router /api/public/search [Get]
 func (p *PublicRoute) searchArticle(c *gin.Context) *R {
 	text := c.Query("value")
-	articles := p.ArticleService.SearchByText(text, false)
-	return Ok(articles)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	boosts := make(map[string]float64)
+	for _, boost := range strings.Split(c.Query("boost"), ",") {
+		field, weight, found := strings.Cut(boost, ":")
+		if !found {
+			continue
+		}
+		value, err := strconv.ParseFloat(weight, 64)
+		if err != nil || value < 0 {
+			return Error(http.StatusBadRequest, errors.New("invalid boost "+boost))
+		}
+		boosts[strings.TrimSpace(field)] = value
+	}
+	result, err := p.SearchService.Search(text, boosts, page, pageSize)
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(result)
 }
 
 // addViewer
@@ -352,6 +378,25 @@ func (p *PublicRoute) getBuildMeta(c *gin.Context) *R {
 	return Ok(siteMeta)
 }
 
+// getHighlightCss
+// @Summary get css of highlighted code
+// @Schemes
+// @Description get css of code blocks in rendered article, style like 'github' or 'monokai', default is 'github'
+// @Tags Public
+// @Produce css
+// @Param        style   query      string  false  "style"
+// @Success 200 {string} string
+// @Router /api/public/highlight.css [Get]
+func (p *PublicRoute) getHighlightCss(c *gin.Context) *R {
+	buf := &bytes.Buffer{}
+	if err := markdown.HighlightCSS(buf, c.DefaultQuery("style", "github")); err != nil {
+		return InternalError(err)
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/css; charset=utf-8", buf.Bytes())
+	return nil
+}
+
 func (p *PublicRoute) Register(r *gin.Engine) {
 	r.GET(PublicPathPrefix+"/customPage/all", Handle(p.getAll))
 	r.GET(PublicPathPrefix+"/customPage", Handle(p.getOneByPath))
@@ -372,4 +417,6 @@ func (p *PublicRoute) Register(r *gin.Engine) {
 	r.GET(PublicPathPrefix+"/category", Handle(p.getArticlesByCategory))
 
 	r.GET(PublicPathPrefix+"/meta", Handle(p.getBuildMeta))
+
+	r.GET(PublicPathPrefix+"/highlight.css", Handle(p.getHighlightCss))
 }
@@ -40,6 +40,13 @@ type Router struct {
 	IsrRouter          *IsrRoute
 	ImgRouter          *ImgRoute
 	LogRoute           *LogRoute
+	StorageRouter      *StorageRoute
+	StaticRouter       *StaticRoute
+	UploadRouter       *UploadRoute
+	FileManagerRouter  *FileManagerRoute
+	FeedRouter         *FeedRoute
+	SitemapRouter      *SitemapRoute
+	AccessGuard        *AccessGuard
 }
 
 var Set = wire.NewSet(
@@ -68,6 +75,13 @@ var Set = wire.NewSet(
 	IsrRouterSet,
 	ImgRouterSet,
 	LogRouteSet,
+	StorageRouterSet,
+	StaticRouterSet,
+	UploadRouterSet,
+	FileManagerRouterSet,
+	FeedRouterSet,
+	SitemapRouterSet,
+	AccessGuardSet,
 	wire.Struct(new(Router), "*"),
 )
 
@@ -77,6 +91,7 @@ func (router *Router) Init(r *gin.Engine) {
 	// middleware setup
 	r.Use(gin.Recovery())
 	r.Use(middleware.Logging())
+	r.Use(router.AccessGuard.Handle())
 
 	// swagger
 	docs.SwaggerInfo.Title = "Fusion"
@@ -114,6 +129,12 @@ func (router *Router) Init(r *gin.Engine) {
 		router.IsrRouter.Register(r)
 		router.ImgRouter.Register(r)
 		router.LogRoute.Register(r)
+		router.StorageRouter.Register(r)
+		router.StaticRouter.Register(r)
+		router.UploadRouter.Register(r)
+		router.FileManagerRouter.Register(r)
+		router.FeedRouter.Register(r)
+		router.SitemapRouter.Register(r)
 	}
 
 	// route or method not found
@@ -14,9 +14,12 @@ import (
 const SettingPathPrefix = "/api/admin/setting"
 
 type SettingRoute struct {
-	Cfg            *config.Config
-	SettingService *svr.SettingService
-	Isr            *event.IsrEventBus
+	Cfg              *config.Config
+	SettingService   *svr.SettingService
+	BackupScheduler  *svr.BackupScheduler
+	StaticGCService  *svr.StaticGCService
+	WatermarkService *svr.WatermarkService
+	Isr              *event.IsrEventBus
 }
 
 var SettingRouterSet = wire.NewSet(wire.Struct(new(SettingRoute), "*"))
@@ -57,6 +60,8 @@ func (s *SettingRoute) UpdateStaticSetting(c *gin.Context) *R {
 	if err != nil {
 		return InternalError(err)
 	}
+	// font or logo may be replaced
+	s.WatermarkService.Invalidate()
 	return Ok(successed)
 }
 
@@ -178,6 +183,181 @@ func (s *SettingRoute) UpdateLoginSetting(c *gin.Context) *R {
 	return Ok(successed)
 }
 
+// GetBackupSetting
+// @Summary get backup setting
+// @Schemes
+// @Description get scheduled backup and retention setting
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.BackupSetting
+// @Router /api/admin/setting/backup [Get]
+func (s *SettingRoute) GetBackupSetting(c *gin.Context) *R {
+	backup := s.SettingService.FindBackupSetting()
+	return Ok(backup)
+}
+
+// UpdateBackupSetting
+// @Summary save or update backup setting
+// @Schemes
+// @Description save or update scheduled backup and retention setting, scheduler will be reloaded
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Param        backup   body      domain.BackupSetting   true  "backup"
+// @Success 200 {object} bool
+// @Router /api/admin/setting/backup [Put]
+func (s *SettingRoute) UpdateBackupSetting(c *gin.Context) *R {
+	if s.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止修改此项！"))
+	}
+	backup := &domain.BackupSetting{}
+	if err := c.Bind(backup); err != nil {
+		return InternalError(err)
+	}
+	if err := svr.ValidateCron(backup.Cron); err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	successed, err := s.SettingService.SaveOrUpdateBackupSetting(backup)
+	if err != nil {
+		return InternalError(err)
+	}
+	if err := s.BackupScheduler.Reload(); err != nil {
+		return InternalError(err)
+	}
+	return Ok(successed)
+}
+
+// GetStaticGCSetting
+// @Summary get static gc setting
+// @Schemes
+// @Description get scheduled garbage collection setting of orphaned static files
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.StaticGCSetting
+// @Router /api/admin/setting/staticGC [Get]
+func (s *SettingRoute) GetStaticGCSetting(c *gin.Context) *R {
+	gc := s.SettingService.FindStaticGCSetting()
+	return Ok(gc)
+}
+
+// UpdateStaticGCSetting
+// @Summary save or update static gc setting
+// @Schemes
+// @Description save or update scheduled garbage collection setting of orphaned static files, scheduler will be reloaded
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Param        gc   body      domain.StaticGCSetting   true  "gc"
+// @Success 200 {object} bool
+// @Router /api/admin/setting/staticGC [Put]
+func (s *SettingRoute) UpdateStaticGCSetting(c *gin.Context) *R {
+	if s.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止修改此项！"))
+	}
+	gc := &domain.StaticGCSetting{}
+	if err := c.Bind(gc); err != nil {
+		return InternalError(err)
+	}
+	if err := svr.ValidateCron(gc.Cron); err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	if gc.GraceDays < 0 {
+		return Error(http.StatusBadRequest, errors.New("grace days must not be negative"))
+	}
+	successed, err := s.SettingService.SaveOrUpdateStaticGCSetting(gc)
+	if err != nil {
+		return InternalError(err)
+	}
+	if err := s.StaticGCService.Reload(); err != nil {
+		return InternalError(err)
+	}
+	return Ok(successed)
+}
+
+// GetFeedSetting
+// @Summary get feed setting
+// @Schemes
+// @Description get rss, atom and json feed setting
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.FeedSetting
+// @Router /api/admin/setting/feed [Get]
+func (s *SettingRoute) GetFeedSetting(c *gin.Context) *R {
+	feed := s.SettingService.FindFeedSetting()
+	return Ok(feed)
+}
+
+// UpdateFeedSetting
+// @Summary save or update feed setting
+// @Schemes
+// @Description save or update rss, atom and json feed setting, feeds will be regenerated
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Param        feed   body      domain.FeedSetting   true  "feed"
+// @Success 200 {object} bool
+// @Router /api/admin/setting/feed [Put]
+func (s *SettingRoute) UpdateFeedSetting(c *gin.Context) *R {
+	if s.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止修改此项！"))
+	}
+	feed := &domain.FeedSetting{}
+	if err := c.Bind(feed); err != nil {
+		return InternalError(err)
+	}
+	if feed.Limit < 0 || feed.SummaryLength < 0 {
+		return Error(http.StatusBadRequest, errors.New("limit and summary length must not be negative"))
+	}
+	successed, err := s.SettingService.SaveOrUpdateFeedSetting(feed)
+	if err != nil {
+		return InternalError(err)
+	}
+	s.Isr.ActiveFeed()
+	return Ok(successed)
+}
+
+// GetRobotsSetting
+// @Summary get robots setting
+// @Schemes
+// @Description get robots.txt setting
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.RobotsSetting
+// @Router /api/admin/setting/robots [Get]
+func (s *SettingRoute) GetRobotsSetting(c *gin.Context) *R {
+	robots := s.SettingService.FindRobotsSetting()
+	return Ok(robots)
+}
+
+// UpdateRobotsSetting
+// @Summary save or update robots setting
+// @Schemes
+// @Description save or update robots.txt setting
+// @Tags Setting
+// @Accept json
+// @Produce json
+// @Param        robots   body      domain.RobotsSetting   true  "robots"
+// @Success 200 {object} bool
+// @Router /api/admin/setting/robots [Put]
+func (s *SettingRoute) UpdateRobotsSetting(c *gin.Context) *R {
+	if s.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止修改此项！"))
+	}
+	robots := &domain.RobotsSetting{}
+	if err := c.Bind(robots); err != nil {
+		return InternalError(err)
+	}
+	successed, err := s.SettingService.SaveOrUpdateRobotsSetting(robots)
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(successed)
+}
+
 func (s *SettingRoute) Register(r *gin.Engine) {
 	r.GET(SettingPathPrefix+"/static", Handle(s.GetStaticSetting))
 	r.PUT(SettingPathPrefix+"/static", Handle(s.UpdateStaticSetting))
@@ -190,4 +370,16 @@ func (s *SettingRoute) Register(r *gin.Engine) {
 
 	r.GET(SettingPathPrefix+"/login", Handle(s.GetLoginSetting))
 	r.PUT(SettingPathPrefix+"/login", Handle(s.UpdateLoginSetting))
+
+	r.GET(SettingPathPrefix+"/backup", Handle(s.GetBackupSetting))
+	r.PUT(SettingPathPrefix+"/backup", Handle(s.UpdateBackupSetting))
+
+	r.GET(SettingPathPrefix+"/staticGC", Handle(s.GetStaticGCSetting))
+	r.PUT(SettingPathPrefix+"/staticGC", Handle(s.UpdateStaticGCSetting))
+
+	r.GET(SettingPathPrefix+"/feed", Handle(s.GetFeedSetting))
+	r.PUT(SettingPathPrefix+"/feed", Handle(s.UpdateFeedSetting))
+
+	r.GET(SettingPathPrefix+"/robots", Handle(s.GetRobotsSetting))
+	r.PUT(SettingPathPrefix+"/robots", Handle(s.UpdateRobotsSetting))
 }
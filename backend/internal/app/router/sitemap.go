@@ -0,0 +1,91 @@
+package router
+
+import (
+	"cc.allio/fusion/internal/svr"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const xmlContentType = "application/xml; charset=utf-8"
+
+type SitemapRoute struct {
+	SitemapService *svr.SitemapService
+}
+
+var SitemapRouterSet = wire.NewSet(wire.Struct(new(SitemapRoute), "*"))
+
+// GetSitemap
+// @Summary get sitemap
+// @Schemes
+// @Description get sitemap of site, it is a sitemap index referencing parts when urls exceed 50000
+// @Tags Public
+// @Produce xml
+// @Success 200 {string} string
+// @Router /sitemap.xml [Get]
+func (s *SitemapRoute) GetSitemap(c *gin.Context) *R {
+	sitemap, err := s.SitemapService.Sitemap()
+	if err != nil {
+		return InternalError(err)
+	}
+	c.Data(http.StatusOK, xmlContentType, sitemap.Index)
+	return nil
+}
+
+// GetSitemapPart
+// @Summary get sitemap part
+// @Schemes
+// @Description get part of sitemap referenced by sitemap index, like sitemap-1.xml
+// @Tags Public
+// @Produce xml
+// @Param        name   path      string  true  "name"
+// @Success 200 {string} string
+// @Router /sitemap/{name} [Get]
+func (s *SitemapRoute) GetSitemapPart(c *gin.Context) *R {
+	name := c.Param("name")
+	notFound := Error(http.StatusNotFound, errors.New("sitemap not found"))
+	number, ok := strings.CutPrefix(name, "sitemap-")
+	if !ok {
+		return notFound
+	}
+	number, ok = strings.CutSuffix(number, ".xml")
+	if !ok {
+		return notFound
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return notFound
+	}
+	sitemap, err := s.SitemapService.Sitemap()
+	if err != nil {
+		return InternalError(err)
+	}
+	part, ok := sitemap.Part(n)
+	if !ok {
+		return notFound
+	}
+	c.Data(http.StatusOK, xmlContentType, part)
+	return nil
+}
+
+// GetRobots
+// @Summary get robots.txt
+// @Schemes
+// @Description get configured robots.txt
+// @Tags Public
+// @Produce plain
+// @Success 200 {string} string
+// @Router /robots.txt [Get]
+func (s *SitemapRoute) GetRobots(c *gin.Context) *R {
+	c.String(http.StatusOK, s.SitemapService.Robots())
+	return nil
+}
+
+func (s *SitemapRoute) Register(r *gin.Engine) {
+	r.GET("/sitemap.xml", Handle(s.GetSitemap))
+	r.GET("/sitemap/:name", Handle(s.GetSitemapPart))
+	r.GET("/robots.txt", Handle(s.GetRobots))
+}
@@ -0,0 +1,199 @@
+package router
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/svr"
+	"cc.allio/fusion/pkg/img"
+	"cc.allio/fusion/pkg/storage"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/exp/slog"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// staticMaxAge cache seconds of static file that served by backend
+const staticMaxAge = 7 * 24 * 60 * 60
+
+// signRegexp static sign is hex sha256
+var signRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type StaticRoute struct {
+	Cfg                *config.Config
+	StaticService      *svr.StaticService
+	ImageResizeService *svr.ImageResizeService
+}
+
+var StaticRouterSet = wire.NewSet(wire.Struct(new(StaticRoute), "*"))
+
+// ServeByPath
+// @Summary 获取静态文件
+// @Schemes
+// @Description 根据静态文件路径获取文件内容，支持Range、ETag以及条件请求，对象存储将重定向到签名地址
+// @Tags Static
+// @Produce octet-stream
+// @Param        filepath       path      string   true  "filepath"
+// @Param        thumb          query     string   false "缩略图名称，为空时获取原图"
+// @Success 200 {file} file
+// @Success 302 {string} string
+// @Router /static/{filepath} [Get]
+func (s *StaticRoute) ServeByPath(c *gin.Context) *R {
+	realPath := path.Clean("/" + c.Param("filepath"))
+	// '/static/img/:sign' shares catch-all route with reality path, legacy file inside 'img' dir is not a sign
+	if sign, ok := strings.CutPrefix(svr.StaticUrlPrefix+realPath, svr.ResizeUrlPrefix); ok && signRegexp.MatchString(sign) {
+		if static, err := s.StaticService.GetBySign(sign); err == nil {
+			return s.resize(c, static)
+		}
+	}
+	static, err := s.StaticService.GetByRealPath(realPath)
+	if err != nil {
+		return s.notFound(err)
+	}
+	return s.serve(c, static)
+}
+
+// ServeBySign
+// @Summary 根据签名获取静态文件
+// @Schemes
+// @Description 根据静态文件签名获取文件内容，支持Range、ETag以及条件请求，对象存储将重定向到签名地址
+// @Tags Static
+// @Produce octet-stream
+// @Param        sign       path      string   true  "sign"
+// @Param        thumb      query     string   false "缩略图名称，为空时获取原图"
+// @Success 200 {file} file
+// @Success 302 {string} string
+// @Router /api/public/static/{sign} [Get]
+func (s *StaticRoute) ServeBySign(c *gin.Context) *R {
+	static, err := s.StaticService.GetBySign(c.Param("sign"))
+	if err != nil {
+		return s.notFound(err)
+	}
+	return s.serve(c, static)
+}
+
+// serve static content through storage driver, http.ServeContent handle range and conditional request.
+// query 'thumb' present will serve thumbnail of static instead, otherwise converted variant is negotiated by Accept header
+func (s *StaticRoute) serve(c *gin.Context, static *domain.Static) *R {
+	var content *storage.ContentResponse
+	var err error
+	name := static.Filename()
+	if thumb, ok := c.GetQuery("thumb"); ok {
+		content, err = s.StaticService.Thumb(c.Request.Context(), static, thumb)
+	} else if static.Meta != nil && len(static.Meta.Variants) > 0 {
+		c.Header("Vary", "Accept")
+		if variant := s.StaticService.Variant(static, c.GetHeader("Accept")); variant != nil {
+			content, err = s.StaticService.VariantContent(c.Request.Context(), variant)
+			name = strings.TrimSuffix(name, path.Ext(name)) + "." + variant.Format
+			c.Header("Content-Type", variant.MimeType)
+		} else {
+			content, err = s.StaticService.Content(c.Request.Context(), static)
+		}
+	} else {
+		content, err = s.StaticService.Content(c.Request.Context(), static)
+	}
+	if err != nil {
+		return s.notFound(err)
+	}
+	return s.write(c, content, name, static)
+}
+
+// write content response, object store redirects to signed url
+func (s *StaticRoute) write(c *gin.Context, content *storage.ContentResponse, name string, static *domain.Static) *R {
+	if content.Redirect {
+		// signed url will be expired, only cache redirect half of its lifetime
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", content.MaxAge/2))
+		c.Redirect(http.StatusFound, content.URL)
+		return nil
+	}
+	defer content.Content.Close()
+
+	size, err := content.Content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return InternalError(err)
+	}
+	if _, err := content.Content.Seek(0, io.SeekStart); err != nil {
+		return InternalError(err)
+	}
+	modTime := content.LastModified
+	if modTime.IsZero() {
+		modTime = static.UpdatedAt
+	}
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, modTime.UnixNano(), size))
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", staticMaxAge))
+	http.ServeContent(c.Writer, c.Request, name, modTime, content.Content)
+	return nil
+}
+
+// Resize
+// @Summary 获取缩放后的图片
+// @Schemes
+// @Description 按尺寸缩放、裁剪并转换图片格式，结果会缓存到存储中。尺寸需与预设匹配，或者携带管理后台生成的签名
+// @Tags Static
+// @Produce octet-stream
+// @Param        sign       path      string   true  "sign"
+// @Param        preset     query     string   false "预设名称，优先于w、h、fit"
+// @Param        w          query     int      false "宽度，0表示不限制"
+// @Param        h          query     int      false "高度，0表示不限制"
+// @Param        fit        query     string   false "fit或者fill"
+// @Param        format     query     string   false "jpeg、png、gif、webp或者avif，为空时保持原格式"
+// @Param        s          query     string   false "签名"
+// @Success 200 {file} file
+// @Success 302 {string} string
+// @Router /static/img/{sign} [Get]
+func (s *StaticRoute) resize(c *gin.Context, static *domain.Static) *R {
+	query := &struct {
+		Preset    string `form:"preset"`
+		Width     int    `form:"w"`
+		Height    int    `form:"h"`
+		Fit       string `form:"fit"`
+		Format    string `form:"format"`
+		Signature string `form:"s"`
+	}{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	if query.Preset == "" && query.Width == 0 && query.Height == 0 && query.Format == "" {
+		return s.serve(c, static)
+	}
+	opts := &img.ResizeOptions{Width: query.Width, Height: query.Height, Fit: query.Fit, Format: query.Format}
+	opts, err := s.ImageResizeService.Options(static, query.Preset, opts, query.Signature)
+	switch {
+	case errors.Is(err, svr.ErrResizeNotAllowed):
+		return Error(http.StatusForbidden, err)
+	case err != nil:
+		return Error(http.StatusBadRequest, err)
+	}
+	content, err := s.ImageResizeService.Content(c.Request.Context(), static, opts)
+	if err != nil {
+		if errors.Is(err, img.ErrInvalidResize) {
+			return Error(http.StatusBadRequest, err)
+		}
+		return s.notFound(err)
+	}
+	name := strings.TrimSuffix(static.Filename(), path.Ext(static.Filename())) + "." + opts.Format
+	c.Header("Content-Type", img.ResizeMimeType(opts.Format))
+	return s.write(c, content, name, static)
+}
+
+func (s *StaticRoute) notFound(err error) *R {
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, fs.ErrNotExist) {
+		return Error(http.StatusNotFound, errors.New("static file not found"))
+	}
+	slog.Error("Failed to serve static file", "err", err)
+	return InternalError(err)
+}
+
+func (s *StaticRoute) Register(r *gin.Engine) {
+	r.GET(svr.StaticUrlPrefix+"/*filepath", Handle(s.ServeByPath))
+	r.HEAD(svr.StaticUrlPrefix+"/*filepath", Handle(s.ServeByPath))
+	r.GET(PublicPathPrefix+"/static/:sign", Handle(s.ServeBySign))
+	r.HEAD(PublicPathPrefix+"/static/:sign", Handle(s.ServeBySign))
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/event"
+	"cc.allio/fusion/internal/svr"
+	"cc.allio/fusion/pkg/web"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"net/http"
+)
+
+const StoragePathPrefix = "/api/admin/storage"
+
+type StorageRoute struct {
+	Cfg                 *config.Config
+	StorageMigrationSvr *svr.StorageMigrationService
+	StaticGCSvr         *svr.StaticGCService
+	Isr                 *event.IsrEventBus
+}
+
+var StorageRouterSet = wire.NewSet(wire.Struct(new(StorageRoute), "*"))
+
+// StartMigration
+// @Summary 迁移静态文件存储
+// @Schemes
+// @Description 将所有静态文件从当前存储复制到目标存储，并改写静态文件记录以及文章、草稿中的链接。已存在于目标存储的文件会被跳过，失败后可重新执行
+// @Tags Storage
+// @Accept json
+// @Produce json
+// @Param        migration   body      credential.StorageMigrationCredential   true  "migration"
+// @Success 200 {object} bool
+// @Router /api/admin/storage/migration [Post]
+func (s *StorageRoute) StartMigration(c *gin.Context) *R {
+	if s.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止迁移存储！"))
+	}
+	migration := &credential.StorageMigrationCredential{}
+	if err := c.Bind(migration); err != nil {
+		return InternalError(err)
+	}
+	finished := func(progress *domain.StorageMigrationProgress) {
+		if progress.Rewritten > 0 {
+			s.Isr.ActiveAll("trigger incremental rendering by storage migration")
+		}
+	}
+	if err := s.StorageMigrationSvr.Start(migration, finished); err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	return Ok(true)
+}
+
+// GetMigrationProgress
+// @Summary 获取静态文件存储迁移进度
+// @Schemes
+// @Description 获取最近一次存储迁移的进度以及失败文件
+// @Tags Storage
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.StorageMigrationProgress
+// @Router /api/admin/storage/migration [Get]
+func (s *StorageRoute) GetMigrationProgress(c *gin.Context) *R {
+	progress := s.StorageMigrationSvr.Progress()
+	return Ok(progress)
+}
+
+// RunGC
+// @Summary 清理孤立静态文件
+// @Schemes
+// @Description 扫描文章、草稿、自定义页面、元信息以及设置中引用的静态文件，报告未被引用的静态文件、存储中没有记录的文件以及文件丢失的记录。delete为true时删除超过保留天数的孤立文件
+// @Tags Storage
+// @Accept json
+// @Produce json
+// @Param        delete   query      bool   false  "delete"
+// @Success 200 {object} domain.StaticGCReport
+// @Router /api/admin/storage/gc [Post]
+func (s *StorageRoute) RunGC(c *gin.Context) *R {
+	remove := web.ParseBoolForQuery(c, "delete", false)
+	if remove && s.Cfg.Demo {
+		return Error(http.StatusUnauthorized, errors.New("演示站禁止删除静态文件！"))
+	}
+	report, err := s.StaticGCSvr.Run(c.Request.Context(), remove)
+	if err != nil {
+		return InternalError(err)
+	}
+	return Ok(report)
+}
+
+// GetGCReport
+// @Summary 获取孤立静态文件报告
+// @Schemes
+// @Description 获取最近一次清理孤立静态文件的报告
+// @Tags Storage
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.StaticGCReport
+// @Router /api/admin/storage/gc [Get]
+func (s *StorageRoute) GetGCReport(c *gin.Context) *R {
+	report := s.StaticGCSvr.Report()
+	return Ok(report)
+}
+
+func (s *StorageRoute) Register(r *gin.Engine) {
+	r.POST(StoragePathPrefix+"/migration", Handle(s.StartMigration))
+	r.GET(StoragePathPrefix+"/migration", Handle(s.GetMigrationProgress))
+	r.POST(StoragePathPrefix+"/gc", Handle(s.RunGC))
+	r.GET(StoragePathPrefix+"/gc", Handle(s.GetGCReport))
+}
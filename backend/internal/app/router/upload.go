@@ -0,0 +1,151 @@
+package router
+
+import (
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/svr"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const UploadPathPrefix = "/api/admin/upload"
+
+type UploadRoute struct {
+	ChunkUploadSvr *svr.ChunkUploadService
+}
+
+var UploadRouterSet = wire.NewSet(wire.Struct(new(UploadRoute), "*"))
+
+// InitChunk
+// @Summary Init chunk upload
+// @Schemes
+// @Description Start resumable upload of large file, then upload parts by returned id and chunk size
+// @Tags Static
+// @Accept json
+// @Produce json
+// @Param        upload   body      credential.ChunkUploadCredential   true  "upload"
+// @Success 200 {object} domain.ChunkUpload
+// @Router /api/admin/upload/chunk [POST]
+func (u *UploadRoute) InitChunk(c *gin.Context) *R {
+	cred := &credential.ChunkUploadCredential{}
+	if err := c.Bind(cred); err != nil {
+		return InternalError(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	upload, err := u.ChunkUploadSvr.Init(ctx, cred, GetAuthUser(c))
+	if err != nil {
+		return chunkUploadError(err)
+	}
+	return Ok(upload)
+}
+
+// UploadChunk
+// @Summary Upload part of chunk upload
+// @Schemes
+// @Description Upload raw bytes of part, part number starts from 1. every part except the last one must be chunk size
+// @Tags Static
+// @Accept octet-stream
+// @Produce json
+// @Param        id     path      string   true  "upload id"
+// @Param        part   path      int      true  "part number"
+// @Success 200 {object} domain.ChunkUpload
+// @Router /api/admin/upload/chunk/{id}/{part} [PUT]
+func (u *UploadRoute) UploadChunk(c *gin.Context) *R {
+	number, err := strconv.Atoi(c.Param("part"))
+	if err != nil {
+		return Error(http.StatusBadRequest, err)
+	}
+	// read one more byte to find out exceeded part
+	part, err := io.ReadAll(io.LimitReader(c.Request.Body, svr.MaxChunkSize+1))
+	if err != nil {
+		return InternalError(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	upload, err := u.ChunkUploadSvr.UploadPart(ctx, c.Param("id"), number, part, GetAuthUser(c))
+	if err != nil {
+		return chunkUploadError(err)
+	}
+	return Ok(upload)
+}
+
+// GetChunk
+// @Summary Get chunk upload
+// @Schemes
+// @Description Get uploaded parts of chunk upload, client resumes upload by sending the rest
+// @Tags Static
+// @Accept json
+// @Produce json
+// @Param        id     path      string   true  "upload id"
+// @Success 200 {object} domain.ChunkUpload
+// @Router /api/admin/upload/chunk/{id} [GET]
+func (u *UploadRoute) GetChunk(c *gin.Context) *R {
+	upload, err := u.ChunkUploadSvr.Status(c.Param("id"), GetAuthUser(c))
+	if err != nil {
+		return chunkUploadError(err)
+	}
+	return Ok(upload)
+}
+
+// CompleteChunk
+// @Summary Complete chunk upload
+// @Schemes
+// @Description Assemble all parts and save as static
+// @Tags Static
+// @Accept json
+// @Produce json
+// @Param        id     path      string   true  "upload id"
+// @Success 200 {object} credential.FilePathCredential
+// @Router /api/admin/upload/chunk/{id}/complete [POST]
+func (u *UploadRoute) CompleteChunk(c *gin.Context) *R {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	static, err := u.ChunkUploadSvr.Complete(ctx, c.Param("id"), GetAuthUser(c))
+	if err != nil {
+		return chunkUploadError(err)
+	}
+	return Ok(static)
+}
+
+// AbortChunk
+// @Summary Abort chunk upload
+// @Schemes
+// @Description Discard chunk upload and its uploaded parts
+// @Tags Static
+// @Accept json
+// @Produce json
+// @Param        id     path      string   true  "upload id"
+// @Success 200 {object} bool
+// @Router /api/admin/upload/chunk/{id} [DELETE]
+func (u *UploadRoute) AbortChunk(c *gin.Context) *R {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := u.ChunkUploadSvr.Abort(ctx, c.Param("id"), GetAuthUser(c)); err != nil {
+		return chunkUploadError(err)
+	}
+	return Ok(true)
+}
+
+func chunkUploadError(err error) *R {
+	switch {
+	case errors.Is(err, svr.ErrChunkUploadNotFound):
+		return Error(http.StatusNotFound, err)
+	case errors.Is(err, svr.ErrChunkUploadInvalid):
+		return Error(http.StatusBadRequest, err)
+	default:
+		return uploadError(err)
+	}
+}
+
+func (u *UploadRoute) Register(r *gin.Engine) {
+	r.POST(UploadPathPrefix+"/chunk", Handle(u.InitChunk))
+	r.GET(UploadPathPrefix+"/chunk/:id", Handle(u.GetChunk))
+	r.PUT(UploadPathPrefix+"/chunk/:id/:part", Handle(u.UploadChunk))
+	r.POST(UploadPathPrefix+"/chunk/:id/complete", Handle(u.CompleteChunk))
+	r.DELETE(UploadPathPrefix+"/chunk/:id", Handle(u.AbortChunk))
+}
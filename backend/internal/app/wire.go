@@ -28,6 +28,7 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		router.Set,
 		event.IsrEventBusSet,
 		event.ScriptEngineSet,
+		event.PublishSchedulerSet,
 	))
 }
 
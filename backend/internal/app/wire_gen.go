@@ -95,11 +95,25 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		Cfg: cfg,
 		Db:  database,
 	}
+	searchService := &svr.SearchService{
+		ArticleRepo:  articleRepository,
+		CategoryRepo: categoryRepository,
+	}
+	revisionRepository := &repo.RevisionRepository{
+		Cfg: cfg,
+		Db:  database,
+	}
+	revisionService := &svr.RevisionService{
+		ArticleRepo:  articleRepository,
+		RevisionRepo: revisionRepository,
+	}
 	articleService := &svr.ArticleService{
 		Cfg:          cfg,
 		MetaService:  metaService,
 		ArticleRepo:  articleRepository,
 		CategoryRepo: categoryRepository,
+		SearchSvr:    searchService,
+		RevisionSvr:  revisionService,
 	}
 	visitService := &svr.VisitService{
 		Cfg:            cfg,
@@ -129,9 +143,10 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		Db:  database,
 	}
 	draftService := &svr.DraftService{
-		Cfg:        cfg,
-		DraftRepo:  draftRepository,
-		ArticleSvr: articleService,
+		Cfg:         cfg,
+		DraftRepo:   draftRepository,
+		ArticleSvr:  articleService,
+		RevisionSvr: revisionService,
 	}
 	staticRepository := &repo.StaticRepository{
 		Cfg: cfg,
@@ -143,9 +158,10 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		SettingService:   settingService,
 	}
 	staticService := &svr.StaticService{
-		Cfg:         cfg,
-		FileService: fileService,
-		StaticRepo:  staticRepository,
+		Cfg:            cfg,
+		FileService:    fileService,
+		SettingService: settingService,
+		StaticRepo:     staticRepository,
 	}
 	svrSettingService := svr.SettingService{
 		Cfg:         cfg,
@@ -171,6 +187,119 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		Cfg:                cfg,
 		PipelineRepository: pipelineRepository,
 	}
+	backupRepository := &repo.BackupRepository{
+		Cfg:            cfg,
+		Db:             database,
+		ArticleRepo:    articleRepository,
+		CategoryRepo:   categoryRepository,
+		CustomPageRepo: customPageRepository,
+		DraftRepo:      draftRepository,
+		MetaRepo:       metaRepository,
+		PipelineRepo:   pipelineRepository,
+		RevisionRepo:   revisionRepository,
+		SettingsRepo:   settingsRepository,
+		StaticRepo:     staticRepository,
+		UserRepo:       userRepository,
+		ViewerRepo:     viewerRepository,
+		VisitRepo:      visitRepository,
+	}
+	backupService := &svr.BackupService{
+		Cfg:            cfg,
+		BackupRepo:     backupRepository,
+		ArticleRepo:    articleRepository,
+		CategoryRepo:   categoryRepository,
+		CustomPageRepo: customPageRepository,
+		DraftRepo:      draftRepository,
+		PipelineRepo:   pipelineRepository,
+		RevisionRepo:   revisionRepository,
+		SettingsRepo:   settingsRepository,
+		StaticRepo:     staticRepository,
+		UserRepo:       userRepository,
+		ViewerRepo:     viewerRepository,
+		VisitRepo:      visitRepository,
+		MetaSvr:        metaService,
+		TagSvr:         tagService,
+		FileSvr:        fileService,
+		SearchSvr:      searchService,
+	}
+	backupScheduler := &svr.BackupScheduler{
+		Cfg:        cfg,
+		BackupSvr:  backupService,
+		SettingSvr: settingService,
+	}
+	urlRewriteService := &svr.UrlRewriteService{
+		ArticleRepo:    articleRepository,
+		DraftRepo:      draftRepository,
+		CustomPageRepo: customPageRepository,
+		MetaRepo:       metaRepository,
+		RevisionRepo:   revisionRepository,
+		SearchSvr:      searchService,
+	}
+	storageMigrationService := &svr.StorageMigrationService{
+		Cfg:           cfg,
+		StaticRepo:    staticRepository,
+		FileSvr:       fileService,
+		SettingSvr:    settingService,
+		UrlRewriteSvr: urlRewriteService,
+	}
+	watermarkService := &svr.WatermarkService{
+		FileService:    fileService,
+		StaticService:  staticService,
+		SettingService: settingService,
+	}
+	staticGCService := &svr.StaticGCService{
+		Cfg:            cfg,
+		StaticRepo:     staticRepository,
+		ArticleRepo:    articleRepository,
+		DraftRepo:      draftRepository,
+		CustomPageRepo: customPageRepository,
+		MetaRepo:       metaRepository,
+		SettingRepo:    settingsRepository,
+		RevisionRepo:   revisionRepository,
+		FileSvr:        fileService,
+		SettingSvr:     settingService,
+	}
+	chunkUploadRepository := &repo.ChunkUploadRepository{
+		Cfg: cfg,
+		Db:  database,
+	}
+	chunkUploadService := &svr.ChunkUploadService{
+		FileSvr:         fileService,
+		StaticSvr:       staticService,
+		SettingSvr:      settingService,
+		ChunkUploadRepo: chunkUploadRepository,
+	}
+	imageResizeService := &svr.ImageResizeService{
+		Cfg:        cfg,
+		FileSvr:    fileService,
+		SettingSvr: settingService,
+		StaticRepo: staticRepository,
+	}
+	fileManagerService := &svr.FileManagerService{
+		FileSvr:       fileService,
+		StaticSvr:     staticService,
+		UrlRewriteSvr: urlRewriteService,
+		StaticRepo:    staticRepository,
+	}
+	feedService := &svr.FeedService{
+		ArticleSvr:  articleService,
+		CategorySvr: categoryService,
+		MetaSvr:     metaService,
+		SettingSvr:  settingService,
+	}
+	sitemapService := &svr.SitemapService{
+		ArticleSvr:    articleService,
+		CategorySvr:   categoryService,
+		CustomPageSvr: customPageService,
+		MetaSvr:       metaService,
+		SettingSvr:    settingService,
+	}
+	scheduleService := &svr.ScheduleService{
+		ArticleRepo: articleRepository,
+		DraftRepo:   draftRepository,
+		DraftSvr:    draftService,
+		SearchSvr:   searchService,
+	}
 	service := &svr.Service{
 		UserService:       userService,
 		TokenService:      tokenService,
@@ -190,6 +319,19 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		PipelineService:   pipelineService,
 		FileService:       fileService,
 		LogService:        logService,
+		BackupService:     backupService,
+		BackupScheduler:   backupScheduler,
+		StorageMigration:  storageMigrationService,
+		WatermarkService:  watermarkService,
+		StaticGCService:   staticGCService,
+		ChunkUpload:       chunkUploadService,
+		ImageResize:       imageResizeService,
+		FileManager:       fileManagerService,
+		FeedService:       feedService,
+		SitemapService:    sitemapService,
+		SearchService:     searchService,
+		RevisionService:   revisionService,
+		ScheduleService:   scheduleService,
 	}
 	isrEventBus := event.NewIsrEventBus(bus, service)
 	aboutRoute := &router.AboutRoute{
@@ -203,10 +345,12 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 	}
 	scriptEngine := event.NewScripEngine(pipelineService)
 	articleRoute := &router.ArticleRoute{
-		Cfg:        cfg,
-		ArticleSvr: articleService,
-		Isr:        isrEventBus,
-		Script:     scriptEngine,
+		Cfg:         cfg,
+		ArticleSvr:  articleService,
+		RevisionSvr: revisionService,
+		ScheduleSvr: scheduleService,
+		Isr:         isrEventBus,
+		Script:      scriptEngine,
 	}
 	authRoute := &router.AuthRoute{
 		Cfg:      cfg,
@@ -216,17 +360,10 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		Script:   scriptEngine,
 	}
 	backupRoute := &router.BackupRoute{
-		Cfg:         cfg,
-		UserSvr:     userService,
-		MetaSvr:     metaService,
-		VisitSvr:    visitService,
-		ViewerSvr:   viewerService,
-		ArticleSvr:  articleService,
-		CategorySvr: categoryService,
-		TagSvr:      tagService,
-		DraftSvr:    draftService,
-		SettingSvr:  settingService,
-		StaticSvr:   staticService,
+		Cfg:             cfg,
+		BackupSvr:       backupService,
+		BackupScheduler: backupScheduler,
+		Isr:             isrEventBus,
 	}
 	userRoute := &router.UserRoute{
 		Cfg:     cfg,
@@ -255,6 +392,7 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 	draftRoute := &router.DraftRoute{
 		Cfg:          cfg,
 		DraftService: draftService,
+		ScheduleSvr:  scheduleService,
 		Isr:          isrEventBus,
 		Script:       scriptEngine,
 	}
@@ -276,9 +414,12 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		MetaService: metaService,
 	}
 	settingRoute := &router.SettingRoute{
-		Cfg:            cfg,
-		SettingService: settingService,
-		Isr:            isrEventBus,
+		Cfg:              cfg,
+		SettingService:   settingService,
+		BackupScheduler:  backupScheduler,
+		StaticGCService:  staticGCService,
+		WatermarkService: watermarkService,
+		Isr:              isrEventBus,
 	}
 	siteRoute := &router.SiteRoute{
 		Cfg:         cfg,
@@ -310,6 +451,7 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		SettingService:    settingService,
 		CustomPageService: customPageService,
 		CategoryService:   categoryService,
+		SearchService:     searchService,
 	}
 	pipelineRoute := &router.PipelineRoute{
 		Cfg:             cfg,
@@ -322,14 +464,46 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		Isr:            isrEventBus,
 	}
 	imgRoute := &router.ImgRoute{
-		Cfg:            cfg,
-		StaticService:  staticService,
-		SettingService: settingService,
+		Cfg:              cfg,
+		StaticService:    staticService,
+		SettingService:   settingService,
+		WatermarkService: watermarkService,
+		ResizeService:    imageResizeService,
 	}
 	logRoute := &router.LogRoute{
 		Cfg:        cfg,
 		LogService: logService,
 	}
+	storageRoute := &router.StorageRoute{
+		Cfg:                 cfg,
+		StorageMigrationSvr: storageMigrationService,
+		StaticGCSvr:         staticGCService,
+		Isr:                 isrEventBus,
+	}
+	staticRoute := &router.StaticRoute{
+		Cfg:                cfg,
+		StaticService:      staticService,
+		ImageResizeService: imageResizeService,
+	}
+	uploadRoute := &router.UploadRoute{
+		ChunkUploadSvr: chunkUploadService,
+	}
+	fileManagerRoute := &router.FileManagerRoute{
+		Cfg:            cfg,
+		FileManagerSvr: fileManagerService,
+		Isr:            isrEventBus,
+	}
+	feedRoute := &router.FeedRoute{
+		FeedService: feedService,
+	}
+	sitemapRoute := &router.SitemapRoute{
+		SitemapService: sitemapService,
+	}
+	accessGuard := &router.AccessGuard{
+		Cfg:          cfg,
+		UserService:  userService,
+		TokenService: tokenService,
+	}
 	routerRouter := &router.Router{
 		AboutRouter:        aboutRoute,
 		AnalysisRouter:     analysisRoute,
@@ -356,22 +530,37 @@ func InitApp(ctx context.Context, cfg *config.Config) (*App, func(), error) {
 		IsrRouter:          isrRoute,
 		ImgRouter:          imgRoute,
 		LogRoute:           logRoute,
+		StorageRouter:      storageRoute,
+		StaticRouter:       staticRoute,
+		UploadRouter:       uploadRoute,
+		FileManagerRouter:  fileManagerRoute,
+		FeedRouter:         feedRoute,
+		SitemapRouter:      sitemapRoute,
+		AccessGuard:        accessGuard,
 	}
 	repository := &repo.Repository{
-		ArticleRepository:    articleRepository,
-		CategoryRepository:   categoryRepository,
-		DraftRepository:      draftRepository,
-		MetaRepository:       metaRepository,
-		SettingsRepository:   settingsRepository,
-		StaticRepository:     staticRepository,
-		TokenRepository:      tokenRepository,
-		UserRepository:       userRepository,
-		ViewerRepository:     viewerRepository,
-		VisitRepository:      visitRepository,
-		CustomPageRepository: customPageRepository,
-		PipelineRepository:   pipelineRepository,
+		ArticleRepository:     articleRepository,
+		CategoryRepository:    categoryRepository,
+		DraftRepository:       draftRepository,
+		MetaRepository:        metaRepository,
+		SettingsRepository:    settingsRepository,
+		StaticRepository:      staticRepository,
+		TokenRepository:       tokenRepository,
+		UserRepository:        userRepository,
+		ViewerRepository:      viewerRepository,
+		VisitRepository:       visitRepository,
+		CustomPageRepository:  customPageRepository,
+		PipelineRepository:    pipelineRepository,
+		BackupRepository:      backupRepository,
+		RevisionRepository:    revisionRepository,
+		ChunkUploadRepository: chunkUploadRepository,
+	}
+	publishScheduler := &event.PublishScheduler{
+		Service: service,
+		Isr:     isrEventBus,
+		Script:  scriptEngine,
 	}
-	app := New(cfg, routerRouter, service, repository, database, isrEventBus, scriptEngine, logger)
+	app := New(cfg, routerRouter, service, repository, database, isrEventBus, scriptEngine, publishScheduler, logger)
 	return app, func() {
 		cleanup()
 	}, nil
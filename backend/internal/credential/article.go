@@ -1,6 +1,10 @@
 package credential
 
-import "cc.allio/fusion/internal/domain"
+import (
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/diff"
+	"cc.allio/fusion/pkg/markdown"
+)
 
 type ArticleSearchOptionCredential struct {
 	Page          int              `json:"page"`
@@ -31,4 +35,14 @@ type AlternateArticle struct {
 	Pre     *domain.Article `json:"pre"`
 	Article *domain.Article `json:"article"`
 	Next    *domain.Article `json:"next"`
+	// Rendered html, table of contents and excerpt of article content, omitted when content is invisible
+	Rendered *markdown.Rendered `json:"rendered,omitempty"`
+}
+
+// RevisionDiff difference from one revision of article to another, content of revisions is omitted
+type RevisionDiff struct {
+	From    *domain.ArticleRevision `json:"from"`
+	To      *domain.ArticleRevision `json:"to"`
+	Title   *diff.Result            `json:"title"`
+	Content *diff.Result            `json:"content"`
 }
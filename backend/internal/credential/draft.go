@@ -1,6 +1,9 @@
 package credential
 
-import "cc.allio/fusion/internal/domain"
+import (
+	"cc.allio/fusion/internal/domain"
+	"time"
+)
 
 type DraftSearchOptionCredential struct {
 	Page          int              `json:"page"`
@@ -20,4 +23,8 @@ type DraftPublishCredential struct {
 	Private   bool   `json:"private"`
 	Password  string `json:"password"`
 	Copyright string `json:"copyright"`
+	// PublishAt draft is published at then if it is in the future, otherwise published immediately
+	PublishAt *time.Time `json:"publishAt"`
+	// UnpublishAt published article turns hidden at then
+	UnpublishAt *time.Time `json:"unpublishAt"`
 }
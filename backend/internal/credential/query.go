@@ -0,0 +1,60 @@
+package credential
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"time"
+)
+
+// QueryCredential composable query of admin article and draft list
+type QueryCredential struct {
+	Page int `json:"page"`
+	// PageSize -1 means all matched
+	PageSize int          `json:"pageSize"`
+	Filter   *QueryFilter `json:"filter"`
+	// Sort keys applied in order, id desc is appended as tie breaker
+	Sort []*QuerySort `json:"sort"`
+	// Facets to count of matched documents, 'tags' or 'category'
+	Facets     []string `json:"facets"`
+	ToListView bool     `json:"toListView"`
+}
+
+// QueryFilter conditions of filter are combined by and, then combined with nested And, Or and Not. like
+// {"or": [{"tags": ["go"]}, {"allTags": ["rust", "wasm"]}], "hidden": false, "wordCount": {"gte": 1000}}
+type QueryFilter struct {
+	And []*QueryFilter `json:"and,omitempty"`
+	Or  []*QueryFilter `json:"or,omitempty"`
+	Not *QueryFilter   `json:"not,omitempty"`
+
+	// Title contains text, case-insensitive
+	Title    string   `json:"title,omitempty"`
+	Category []string `json:"category,omitempty"`
+	// Tags has any of tags
+	Tags []string `json:"tags,omitempty"`
+	// AllTags has all of tags
+	AllTags []string `json:"allTags,omitempty"`
+	Author  string   `json:"author,omitempty"`
+	// Hidden, Private and Top are supported by article only
+	Hidden    *bool           `json:"hidden,omitempty"`
+	Private   *bool           `json:"private,omitempty"`
+	Top       *bool           `json:"top,omitempty"`
+	WordCount *QueryIntRange  `json:"wordCount,omitempty"`
+	CreatedAt *QueryTimeRange `json:"createdAt,omitempty"`
+	UpdatedAt *QueryTimeRange `json:"updatedAt,omitempty"`
+}
+
+// QueryIntRange closed range, nil bound is unlimited
+type QueryIntRange struct {
+	Gte *int64 `json:"gte,omitempty"`
+	Lte *int64 `json:"lte,omitempty"`
+}
+
+// QueryTimeRange closed range of RFC 3339 time, nil bound is unlimited
+type QueryTimeRange struct {
+	Gte *time.Time `json:"gte,omitempty"`
+	Lte *time.Time `json:"lte,omitempty"`
+}
+
+type QuerySort struct {
+	Field string           `json:"field"`
+	Order domain.SortOrder `json:"order"`
+}
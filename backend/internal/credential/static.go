@@ -1,5 +1,7 @@
 package credential
 
+import "cc.allio/fusion/internal/domain"
+
 type StaticSearchOption struct {
 	Page       int    `json:"page"`
 	PageSize   int    `json:"pageSize"`
@@ -10,3 +12,30 @@ type FilePathCredential struct {
 	Src   string `json:"src"`
 	IsNew bool   `json:"isNew"`
 }
+
+type StorageMigrationCredential struct {
+	// To target storage setting
+	To *domain.StaticSetting `json:"to"`
+	// FromUrlPrefix and ToUrlPrefix prefix of static url inside article and draft content, like 'https://cdn.example.com'
+	FromUrlPrefix string `json:"fromUrlPrefix"`
+	ToUrlPrefix   string `json:"toUrlPrefix"`
+	// Switch change static setting to target when all files migrated, static records and urls are rewritten only after switched
+	Switch bool `json:"switch"`
+}
+
+type ChunkUploadCredential struct {
+	Filename string `json:"filename"`
+	// Size bytes of whole file
+	Size int64 `json:"size"`
+	// ChunkSize bytes of each part, default is used when zero
+	ChunkSize int64 `json:"chunkSize"`
+}
+
+type FileDirCredential struct {
+	Path string `json:"path"`
+}
+
+type FileMoveCredential struct {
+	Src  string `json:"src"`
+	Dest string `json:"dest"`
+}
@@ -20,9 +20,16 @@ type Article struct {
 	Viewer          int64     `json:"viewer" bson:"viewer"`
 	Visited         int64     `json:"visited" bson:"visited"`
 	Copyright       string    `json:"copyright" bson:"copyright"`
+	WordCount       int64     `json:"wordCount" bson:"wordCount"`
 	LastVisitedTime time.Time `json:"lastVisitedTime" bson:"lastVisitedTime"`
 	CreatedAt       time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt       time.Time `json:"updatedAt" bson:"updatedAt"`
+	// PublishAt article keeps hidden until then, it is cleared once published
+	PublishAt *time.Time `json:"publishAt" bson:"publishAt"`
+	// UnpublishAt article turns hidden at then, it is cleared once unpublished
+	UnpublishAt *time.Time `json:"unpublishAt" bson:"unpublishAt"`
+	// ScheduledHidden article is hidden only because of PublishAt, it is shown once published. set by server only
+	ScheduledHidden bool `json:"scheduledHidden" bson:"scheduledHidden"`
 }
 
 type ArticlePageResult struct {
@@ -30,3 +37,40 @@ type ArticlePageResult struct {
 	Total          int64      `json:"total"`
 	TotalWordCount int64      `json:"totalWordCount"`
 }
+
+type ArticleQueryResult struct {
+	Articles []*Article   `json:"articles"`
+	Total    int64        `json:"total"`
+	Facets   *QueryFacets `json:"facets,omitempty"`
+}
+
+// ArticleSearchHit article matched full-text search, content of article is omitted
+type ArticleSearchHit struct {
+	Article *Article `json:"article"`
+	Score   float64  `json:"score"`
+	// Highlights escaped text of matched fields with terms wrapped in <mark>, content is cut to snippet
+	Highlights map[string]string `json:"highlights"`
+}
+
+type ArticleSearchResult struct {
+	Hits     []*ArticleSearchHit `json:"hits"`
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"pageSize"`
+}
+
+type ScheduleAction = string
+
+const (
+	PublishScheduleAction      ScheduleAction = "publish"
+	UnpublishScheduleAction    ScheduleAction = "unpublish"
+	PublishDraftScheduleAction ScheduleAction = "publishDraft"
+)
+
+// ScheduledChange article changed by scheduled publishing
+type ScheduledChange struct {
+	Action  ScheduleAction `json:"action"`
+	Article *Article       `json:"article"`
+	// DraftId draft published, only for PublishDraftScheduleAction
+	DraftId uint64 `json:"draftId,omitempty"`
+}
@@ -0,0 +1,80 @@
+package domain
+
+import "time"
+
+type BackupImportMode = string
+
+// BackupSchemaVersion increase when backup archive layout changed
+const BackupSchemaVersion = 1
+
+const (
+	// MergeImportMode upsert backup documents, keep documents that not exist in backup
+	MergeImportMode BackupImportMode = "merge"
+	// ReplaceImportMode remove all documents that not exist in backup
+	ReplaceImportMode BackupImportMode = "replace"
+)
+
+type Backup struct {
+	Articles   []*Article  `json:"articles"`
+	Categories []*Category `json:"categories"`
+	Tags       []string    `json:"tags"`
+	Meta       *Meta       `json:"meta"`
+	Drafts     []*Draft    `json:"drafts"`
+	User       *User       `json:"user"`
+	Viewers    []*Viewer   `json:"viewers"`
+	Visits     []*Visit    `json:"visits"`
+	Static     []*Static   `json:"static"`
+	Setting    struct {
+		Static *StaticSetting `json:"static"`
+	} `json:"setting"`
+	// Users, CustomPages, Pipelines, Revisions and Settings only exist in backup archive, legacy backup.json holds admin User and
+	// static Setting only
+	Users       []*User            `json:"users"`
+	CustomPages []*CustomPage      `json:"customPages"`
+	Pipelines   []*Pipeline        `json:"pipelines"`
+	Revisions   []*ArticleRevision `json:"revisions"`
+	Settings    []*Setting         `json:"settings"`
+}
+
+// BackupImportReport describe what import backup changed (or would change when dry run)
+type BackupImportReport struct {
+	Mode        BackupImportMode          `json:"mode"`
+	DryRun      bool                      `json:"dryRun"`
+	Collections []*BackupCollectionReport `json:"collections"`
+	Files       int64                     `json:"files"`
+}
+
+type BackupCollectionReport struct {
+	Collection string `json:"collection"`
+	Insert     int64  `json:"insert"`
+	Update     int64  `json:"update"`
+	Delete     int64  `json:"delete"`
+}
+
+// BackupManifest the first entry of backup archive
+type BackupManifest struct {
+	SchemaVersion int                         `json:"schemaVersion"`
+	Version       string                      `json:"version"`
+	CreatedAt     time.Time                   `json:"createdAt"`
+	Collections   []*BackupManifestCollection `json:"collections"`
+	Files         []*BackupManifestFile       `json:"files"`
+}
+
+type BackupManifestCollection struct {
+	Name  string `json:"name"`
+	File  string `json:"file"`
+	Count int    `json:"count"`
+}
+
+type BackupManifestFile struct {
+	Sign     string `json:"sign"`
+	RealPath string `json:"realPath"`
+	File     string `json:"file"`
+}
+
+// BackupSnapshot backup archive that stored through storage driver
+type BackupSnapshot struct {
+	Name      string    `json:"name"`
+	Size      uint64    `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -12,11 +12,32 @@ type Draft struct {
 	Category  string    `json:"category" bson:"category"`
 	Author    string    `json:"author" bson:"author"`
 	Deleted   bool      `json:"deleted" bson:"deleted"`
+	WordCount int64     `json:"wordCount" bson:"wordCount"`
 	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	// PublishAt draft is published as article then, it is cleared once published
+	PublishAt *time.Time `json:"publishAt" bson:"publishAt"`
+	// PublishOption options of scheduled publishing
+	PublishOption *DraftPublishOption `json:"publishOption" bson:"publishOption"`
+}
+
+// DraftPublishOption options of article published from draft
+type DraftPublishOption struct {
+	Hidden      bool       `json:"hidden" bson:"hidden"`
+	Pathname    string     `json:"pathname" bson:"pathname"`
+	Private     bool       `json:"private" bson:"private"`
+	Password    string     `json:"password" bson:"password"`
+	Copyright   string     `json:"copyright" bson:"copyright"`
+	UnpublishAt *time.Time `json:"unpublishAt" bson:"unpublishAt"`
 }
 
 type DraftPageResult struct {
 	Drafts []*Draft `json:"articles"`
 	Total  int64    `json:"total"`
 }
+
+type DraftQueryResult struct {
+	Drafts []*Draft     `json:"articles"`
+	Total  int64        `json:"total"`
+	Facets *QueryFacets `json:"facets,omitempty"`
+}
@@ -20,6 +20,8 @@ const (
 	DeleteDraftEvent         EventKey = "deleteDraft"
 	UpdateSiteInfoEvent      EventKey = "updateSiteInfo"
 	ManualTriggerEvent       EventKey = "manualTriggerEvent"
+	PublishArticleEvent      EventKey = "publishArticle"
+	UnpublishArticleEvent    EventKey = "unpublishArticle"
 )
 
 var SystemEvents = []*EventItem{
@@ -83,4 +85,16 @@ var SystemEvents = []*EventItem{
 		EventDescription: "手动触发事件事件",
 		Passive:          true,
 	},
+	{
+		EventName:        PublishArticleEvent,
+		EventNameChinese: "定时发布文章",
+		EventDescription: "到达定时发布时间，文章变为可见或定时发布的草稿发布为文章",
+		Passive:          true,
+	},
+	{
+		EventName:        UnpublishArticleEvent,
+		EventNameChinese: "定时下线文章",
+		EventDescription: "到达定时下线时间，文章变为隐藏",
+		Passive:          true,
+	},
 }
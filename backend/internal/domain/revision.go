@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// RevisionSource what produced revision
+type RevisionSource = string
+
+const (
+	// InitialRevisionSource article saved before revision is recorded, taken before its first update
+	InitialRevisionSource RevisionSource = "initial"
+	UpdateRevisionSource  RevisionSource = "update"
+	PublishRevisionSource RevisionSource = "publish"
+	RestoreRevisionSource RevisionSource = "restore"
+)
+
+// ArticleRevision immutable snapshot of article
+type ArticleRevision struct {
+	Id        uint64         `json:"id" bson:"id"`
+	ArticleId uint64         `json:"articleId" bson:"articleId"`
+	Title     string         `json:"title" bson:"title"`
+	Content   string         `json:"content" bson:"content"`
+	Tags      []string       `json:"tags" bson:"tags"`
+	Category  string         `json:"category" bson:"category"`
+	Author    string         `json:"author" bson:"author"`
+	WordCount int64          `json:"wordCount" bson:"wordCount"`
+	Source    RevisionSource `json:"source" bson:"source"`
+	// RestoredFrom id of revision restored, only for RestoreRevisionSource
+	RestoredFrom uint64    `json:"restoredFrom,omitempty" bson:"restoredFrom,omitempty"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
@@ -7,3 +7,15 @@ const (
 	AscSort  SortOrder = "asc"
 	DescSort SortOrder = "desc"
 )
+
+// FacetCount number of documents of facet value
+type FacetCount struct {
+	Value string `json:"value" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// QueryFacets counts of tags and category among all documents matched query, ordered by count desc
+type QueryFacets struct {
+	Tags     []*FacetCount `json:"tags,omitempty" bson:"tags"`
+	Category []*FacetCount `json:"category,omitempty" bson:"category"`
+}
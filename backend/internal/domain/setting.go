@@ -15,9 +15,107 @@ type StaticSetting struct {
 	SecretAccessKey string `json:"secretAccessKey" bson:"secretAccessKey"`
 	Bucket          string `json:"bucket" bson:"bucket"`
 	BaseDir         string `json:"baseDir" bson:"baseDir"`
+	// Region signing region of s3 compatible storage, empty is derived from aws endpoint or us-east-1
+	Region          string `json:"region" bson:"region"`
 	WaterMarkText   string `json:"waterMarkText" bson:"waterMarkText"`
 	EnableWaterMark bool   `json:"enableWaterMark" bson:"enableWaterMark"`
-	EnableWebp      bool   `json:"enableWebp" bson:"enableWebp"`
+	// UploadPolicy enforced before file is stored
+	UploadPolicy *UploadPolicy `json:"uploadPolicy" bson:"uploadPolicy"`
+	// WaterMark options of watermark engine that used when upload image
+	WaterMark *WaterMarkSetting `json:"waterMark" bson:"waterMark"`
+	// EnableWebp convert uploaded jpeg and png to webp, original is kept as fallback
+	EnableWebp  bool  `json:"enableWebp" bson:"enableWebp"`
+	WebpQuality int64 `json:"webpQuality" bson:"webpQuality"`
+	// EnableAvif convert uploaded jpeg and png to avif, original is kept as fallback
+	EnableAvif  bool  `json:"enableAvif" bson:"enableAvif"`
+	AvifQuality int64 `json:"avifQuality" bson:"avifQuality"`
+	// KeepMetadata keep exif, xmp and iptc metadata of uploaded image, default is strip them after auto orientation
+	KeepMetadata bool `json:"keepMetadata" bson:"keepMetadata"`
+	// EnableThumb generate thumbnails when upload image
+	EnableThumb  bool         `json:"enableThumb" bson:"enableThumb"`
+	ThumbQuality int64        `json:"thumbQuality" bson:"thumbQuality"`
+	ThumbSizes   []*ThumbSize `json:"thumbSizes" bson:"thumbSizes"`
+	// ResizePresets sizes that on-the-fly resize endpoint accepts without signature
+	ResizePresets []*ResizePreset `json:"resizePresets" bson:"resizePresets"`
+}
+
+// UploadPolicy limits of uploaded file, zero means not limited
+type UploadPolicy struct {
+	// MaxSize max bytes of single file
+	MaxSize int64 `json:"maxSize" bson:"maxSize"`
+	// ChunkMaxSize max bytes of single file uploaded in chunks, MaxSize is not applied to chunked upload
+	ChunkMaxSize int64 `json:"chunkMaxSize" bson:"chunkMaxSize"`
+	// AllowedMimeTypes mime types detected by content, support wildcard like 'image/*', empty allows all
+	AllowedMimeTypes []string `json:"allowedMimeTypes" bson:"allowedMimeTypes"`
+	// CollaboratorQuota max total bytes that each collaborator can upload
+	CollaboratorQuota int64 `json:"collaboratorQuota" bson:"collaboratorQuota"`
+	// CollaboratorMaxFiles max files count that each collaborator can upload
+	CollaboratorMaxFiles int64 `json:"collaboratorMaxFiles" bson:"collaboratorMaxFiles"`
+}
+
+var DefaultUploadPolicy = UploadPolicy{
+	MaxSize: 20 << 20,
+}
+
+// WaterMarkSetting text or logo watermark, logo takes precedence of text when ImageSign is not empty
+type WaterMarkSetting struct {
+	// FontSign sign of uploaded TrueType or OpenType font static, empty uses builtin font that has no CJK glyph
+	FontSign string `json:"fontSign" bson:"fontSign"`
+	// FontSize text size in pixel, zero is relative to image width
+	FontSize float64 `json:"fontSize" bson:"fontSize"`
+	// Color hex color of text like '#ffffff'
+	Color string `json:"color" bson:"color"`
+	// ImageSign sign of uploaded png logo static
+	ImageSign string `json:"imageSign" bson:"imageSign"`
+	// ImageRatio logo width ratio of image width, zero keeps logo size
+	ImageRatio float64 `json:"imageRatio" bson:"imageRatio"`
+	Opacity    float64 `json:"opacity" bson:"opacity"`
+	Margin     int     `json:"margin" bson:"margin"`
+	// Rotation counter-clockwise angle in degrees
+	Rotation float64 `json:"rotation" bson:"rotation"`
+	// Location 'topLeft', 'topRight', 'bottomLeft', 'bottomRight' or 'center'
+	Location string `json:"location" bson:"location"`
+	Tiled    bool   `json:"tiled" bson:"tiled"`
+}
+
+var DefaultWaterMarkSetting = WaterMarkSetting{
+	Color:    "#ffffff",
+	Opacity:  0.6,
+	Margin:   16,
+	Location: "bottomRight",
+}
+
+// ThumbSize thumbnail size, first size is the default thumb of image
+type ThumbSize struct {
+	Name   string `json:"name" bson:"name"`
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	// Mode 'fit' or 'fill'
+	Mode string `json:"mode" bson:"mode"`
+}
+
+var DefaultThumbSizes = []*ThumbSize{
+	{Name: "small", Width: 320, Height: 320, Mode: "fill"},
+	{Name: "medium", Width: 960, Height: 960, Mode: "fit"},
+}
+
+// ResizePreset named size of on-the-fly resized image, zero width or height means not limited by it
+type ResizePreset struct {
+	Name   string `json:"name" bson:"name"`
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	// Fit 'fit' or 'fill'
+	Fit string `json:"fit" bson:"fit"`
+	// Format output format, empty keeps original format
+	Format  string `json:"format" bson:"format"`
+	Quality int    `json:"quality" bson:"quality"`
+}
+
+var DefaultResizePresets = []*ResizePreset{
+	{Name: "sm", Width: 480, Fit: "fit"},
+	{Name: "md", Width: 960, Fit: "fit"},
+	{Name: "lg", Width: 1600, Fit: "fit"},
+	{Name: "cover", Width: 1200, Height: 630, Fit: "fill"},
 }
 
 type LoginSetting struct {
@@ -54,3 +152,65 @@ type LayoutSetting struct {
 type IsrSetting struct {
 	Mode string `json:"mode"`
 }
+
+// BackupSetting scheduled backup and retention of stored snapshots, zero keep means not limited by that rule
+type BackupSetting struct {
+	Enable     bool   `json:"enable"`
+	Cron       string `json:"cron"`
+	KeepLast   int64  `json:"keepLast"`
+	KeepDaily  int64  `json:"keepDaily"`
+	KeepWeekly int64  `json:"keepWeekly"`
+}
+
+var DefaultBackupSetting = BackupSetting{
+	Enable:     false,
+	Cron:       "0 3 * * *",
+	KeepLast:   7,
+	KeepDaily:  7,
+	KeepWeekly: 4,
+}
+
+// StaticGCSetting scheduled garbage collection of orphaned static files
+type StaticGCSetting struct {
+	Enable bool   `json:"enable"`
+	Cron   string `json:"cron"`
+	// GraceDays orphans updated within grace days are never deleted
+	GraceDays int64 `json:"graceDays"`
+	// Delete remove orphans by schedule, otherwise only report them
+	Delete bool `json:"delete"`
+}
+
+var DefaultStaticGCSetting = StaticGCSetting{
+	Enable:    false,
+	Cron:      "0 4 * * 0",
+	GraceDays: 7,
+	Delete:    false,
+}
+
+// FeedSetting rss, atom and json feed generation
+type FeedSetting struct {
+	// Full include whole rendered content of article, otherwise only summary
+	Full bool `json:"full"`
+	// Limit recent articles in feed
+	Limit int64 `json:"limit"`
+	// SummaryLength max characters of summary when article has no '<!-- more -->' marker
+	SummaryLength int64 `json:"summaryLength"`
+}
+
+var DefaultFeedSetting = FeedSetting{
+	Full:          true,
+	Limit:         20,
+	SummaryLength: 200,
+}
+
+// RobotsSetting content of robots.txt
+type RobotsSetting struct {
+	Content string `json:"content"`
+	// Sitemap append sitemap url of site base url to content
+	Sitemap bool `json:"sitemap"`
+}
+
+var DefaultRobotsSetting = RobotsSetting{
+	Content: "User-agent: *\nAllow: /\nDisallow: /admin/\n",
+	Sitemap: true,
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"path"
 	"time"
 )
 
@@ -23,13 +24,180 @@ type Static struct {
 	StorageType StorageType `json:"storageType" bson:"storageType"`
 	FileType    string      `json:"fileType" bson:"fileType"`
 	RealPath    string      `json:"realPath" bson:"realPath"`
-	Meta        interface{} `json:"meta" bson:"meta"`
+	Meta        *StaticMeta `json:"meta" bson:"meta"`
 	Name        string      `json:"name" bson:"name"`
 	Sign        string      `json:"sign" bson:"sign"`
-	UpdatedAt   time.Time   `json:"updatedAt" bson:"updatedAt"`
+	// Size bytes of content
+	Size uint64 `json:"size" bson:"size"`
+	// MimeType detected by content sniffing
+	MimeType string `json:"mimeType" bson:"mimeType"`
+	// Owner id of uploaded user, it is used by collaborator quota
+	Owner uint64 `json:"owner" bson:"owner"`
+	// Hash hex sha256 of content, statics uploaded before deduplication has no hash
+	Hash      string    `json:"hash" bson:"hash,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// Files reality file path of static and its thumbnails
+func (s *Static) Files() []string {
+	files := []string{s.RealPath}
+	if s.Meta != nil {
+		for _, thumb := range s.Meta.Thumbs {
+			files = append(files, thumb.Path)
+		}
+		for _, variant := range s.Meta.Variants {
+			files = append(files, variant.Path)
+		}
+		for _, resized := range s.Meta.Resized {
+			files = append(files, resized.Path)
+		}
+	}
+	return files
+}
+
+// Filename original filename of static, legacy static without name uses base of reality path
+func (s *Static) Filename() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return path.Base(s.RealPath)
+}
+
+// StaticMeta extra information of static for front end
+type StaticMeta struct {
+	Width  int    `json:"width,omitempty" bson:"width,omitempty"`
+	Height int    `json:"height,omitempty" bson:"height,omitempty"`
+	Format string `json:"format,omitempty" bson:"format,omitempty"`
+	// DominantColor hex color like '#ff8800', front end can use it as placeholder before image loaded
+	DominantColor string           `json:"dominantColor,omitempty" bson:"dominantColor,omitempty"`
+	Thumbs        []*StaticThumb   `json:"thumbs,omitempty" bson:"thumbs,omitempty"`
+	Variants      []*StaticVariant `json:"variants,omitempty" bson:"variants,omitempty"`
+	// Resized cache of images that resized on the fly
+	Resized []*StaticResized `json:"resized,omitempty" bson:"resized,omitempty"`
+}
+
+// StaticResized cached image that resized on the fly by size and format
+type StaticResized struct {
+	// Key identify resize options, like '960x0_fit.webp'
+	Key      string `json:"key" bson:"key"`
+	MimeType string `json:"mimeType" bson:"mimeType"`
+	Size     uint64 `json:"size" bson:"size"`
+	// Path reality file path of resized image
+	Path string `json:"path" bson:"path"`
+}
+
+// StaticVariant converted format of image, like webp, served by content negotiation
+type StaticVariant struct {
+	Format   string `json:"format" bson:"format"`
+	MimeType string `json:"mimeType" bson:"mimeType"`
+	Size     uint64 `json:"size" bson:"size"`
+	// Path reality file path of variant
+	Path string `json:"path" bson:"path"`
+}
+
+// StaticThumb generated thumbnail of image
+type StaticThumb struct {
+	Name   string `json:"name" bson:"name"`
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	// Path reality file path of thumbnail
+	Path string `json:"path" bson:"path"`
 }
 
 type StaticPageResult struct {
 	Data  []*Static `json:"data"`
 	Total int64     `json:"total"`
 }
+
+// StorageMigrationProgress progress of moving statics between storage drivers
+type StorageMigrationProgress struct {
+	Running    bool                       `json:"running"`
+	From       string                     `json:"from"`
+	To         string                     `json:"to"`
+	Total      int                        `json:"total"`
+	Copied     int                        `json:"copied"`
+	Skipped    int                        `json:"skipped"`
+	Switched   bool                       `json:"switched"`
+	Rewritten  int                        `json:"rewritten"`
+	Failures   []*StorageMigrationFailure `json:"failures"`
+	StartedAt  time.Time                  `json:"startedAt"`
+	FinishedAt time.Time                  `json:"finishedAt"`
+}
+
+type StorageMigrationFailure struct {
+	Sign     string `json:"sign"`
+	RealPath string `json:"realPath"`
+	Error    string `json:"error"`
+}
+
+// StaticGCReport orphans found by static garbage collector
+type StaticGCReport struct {
+	// Unreferenced static records that no article, draft, custom page, meta or setting references
+	Unreferenced []*StaticOrphan `json:"unreferenced"`
+	// Untracked objects in storage that no static record owns
+	Untracked []*StaticOrphan `json:"untracked"`
+	// Missing static records whose file is not found in storage, they are only reported
+	Missing    []*StaticOrphan `json:"missing"`
+	Deleted    int             `json:"deleted"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+}
+
+type StaticOrphan struct {
+	// Sign of static record, empty for untracked object
+	Sign string `json:"sign"`
+	Path string `json:"path"`
+	Size uint64 `json:"size"`
+	// UpdatedAt update time of record or last modified time of object, orphan is deletable after grace period
+	UpdatedAt time.Time `json:"updatedAt"`
+	Deleted   bool      `json:"deleted"`
+}
+
+// ChunkUpload resumable upload session, every part except the last one must be ChunkSize bytes
+type ChunkUpload struct {
+	Id        string `json:"id" bson:"id"`
+	Filename  string `json:"filename" bson:"filename"`
+	Size      int64  `json:"size" bson:"size"`
+	ChunkSize int64  `json:"chunkSize" bson:"chunkSize"`
+	// Total count of parts
+	Total int `json:"total" bson:"total"`
+	// Parts numbers of uploaded parts that start from 1, client resumes upload by sending the rest
+	Parts     []int     `json:"parts" bson:"-"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// ChunkUploadSession persisted state of ChunkUpload, upload can be resumed after restart
+type ChunkUploadSession struct {
+	ChunkUpload `bson:",inline"`
+	Owner       uint64 `bson:"owner"`
+	// Mode storage mode that parts are staged in, upload can't be resumed after storage changed
+	Mode     string `bson:"mode"`
+	RealPath string `bson:"realPath"`
+	UploadId string `bson:"uploadId"`
+	MimeType string `bson:"mimeType"`
+	// Staged uploaded parts keyed by part number
+	Staged map[string]*ChunkUploadPart `bson:"staged"`
+	// Hashed count of parts hashed in order, -1 when parts are not uploaded in order and content must be read again
+	Hashed int `bson:"hashed"`
+	// HashState marshalled sha256 state of hashed parts
+	HashState []byte `bson:"hashState"`
+	// Completing parts are being assembled, session is kept until assembled so that temporary failure can be retried
+	Completing bool `bson:"completing"`
+}
+
+type ChunkUploadPart struct {
+	Number int    `bson:"number"`
+	ETag   string `bson:"etag"`
+	Size   int64  `bson:"size"`
+}
+
+// FileEntry file or directory listed by file manager
+type FileEntry struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	IsDir     bool      `json:"isDir"`
+	Size      uint64    `json:"size"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Static record of file, nil if file is not tracked
+	Static *Static `json:"static,omitempty"`
+}
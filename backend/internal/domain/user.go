@@ -13,6 +13,18 @@ const (
 
 type Permission string
 
+const (
+	AllPermission           Permission = "all"
+	ArticleCreatePermission Permission = "article:create"
+	ArticleDeletePermission Permission = "article:delete"
+	ArticleUpdatePermission Permission = "article:update"
+	DraftPublishPermission  Permission = "draft:publish"
+	DraftCreatePermission   Permission = "draft:create"
+	DraftDeletePermission   Permission = "draft:delete"
+	DraftUpdatePermission   Permission = "draft:update"
+	ImgDeletePermission     Permission = "img:delete"
+)
+
 type User struct {
 	Id          uint64       `json:"id" bson:"id"`
 	Name        string       `bson:"name" json:"name"`
@@ -103,6 +103,13 @@ func (isr *IsrEventBus) ActiveAll(args ...interface{}) {
 	isr.ActivePost(args...)
 	isr.ActivePage(args...)
 	isr.ActiveTag(args...)
+	isr.ActiveFeed()
+	isr.ActiveSitemap()
+}
+
+// ActiveFeed regenerate rss, atom and json feed
+func (isr *IsrEventBus) ActiveFeed() {
+	isr.Service.FeedService.Refresh()
 }
 
 // ActiveCategory active system all category make it rendering
@@ -142,6 +149,11 @@ func (isr *IsrEventBus) ActiveTag(args ...interface{}) {
 	}
 }
 
+// ActiveSitemap regenerate sitemap
+func (isr *IsrEventBus) ActiveSitemap() {
+	isr.Service.SitemapService.Refresh()
+}
+
 // ActiveAbout active about info
 func (isr *IsrEventBus) ActiveAbout(args ...interface{}) {
 	isr.ActiveRetry("/about", args...)
@@ -0,0 +1,69 @@
+package event
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/svr"
+	"context"
+	"github.com/google/wire"
+	"golang.org/x/exp/slog"
+	"time"
+)
+
+// maxScheduleInterval schedules are checked at least once per interval, so that schedules changed out of this
+// process, like by other instance or restored backup, are not missed
+const maxScheduleInterval = time.Minute
+
+// PublishScheduler apply scheduled publishing of svr.ScheduleService at due time, then trigger isr rendering and
+// dispatch pipeline events of changed articles
+type PublishScheduler struct {
+	Service *svr.Service
+	Isr     *IsrEventBus
+	Script  *ScriptEngine
+}
+
+var PublishSchedulerSet = wire.NewSet(wire.Struct(new(PublishScheduler), "*"))
+
+// Start schedule loop in background until ctx done, overdue schedules are applied immediately
+func (p *PublishScheduler) Start(ctx context.Context) {
+	go p.loop(ctx)
+	slog.Info("Publish scheduler started")
+}
+
+func (p *PublishScheduler) loop(ctx context.Context) {
+	schedule := p.Service.ScheduleService
+	for {
+		p.run()
+		wait := maxScheduleInterval
+		next, err := schedule.Next()
+		if err != nil {
+			slog.Error("Failed to find next publish schedule", "err", err)
+		} else if !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-schedule.Wake():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *PublishScheduler) run() {
+	changes := p.Service.ScheduleService.Run(time.Now())
+	if len(changes) == 0 {
+		return
+	}
+	p.Isr.ActiveAll("trigger incremental rendering by scheduled publishing", len(changes))
+	for _, change := range changes {
+		if change.Action == domain.UnpublishScheduleAction {
+			p.Script.DispatchUnpublishArticleEvent(change.Article)
+		} else {
+			p.Script.DispatchPublishArticleEvent(change.Article)
+		}
+		p.Script.DispatchAfterUpdateArticleEvent(change.Article, true)
+	}
+}
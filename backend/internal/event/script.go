@@ -204,3 +204,13 @@ func (s *ScriptEngine) DispatchUpdateSiteInfoEvent(args ...interface{}) <-chan *
 func (s *ScriptEngine) DispatchManualTriggerEvent(args ...interface{}) <-chan *CodeResult {
 	return s.DispatchByEventKey(domain.ManualTriggerEvent, args...)
 }
+
+// DispatchPublishArticleEvent dispatch scheduled publish article event
+func (s *ScriptEngine) DispatchPublishArticleEvent(args ...interface{}) <-chan *CodeResult {
+	return s.DispatchByEventKey(domain.PublishArticleEvent, args...)
+}
+
+// DispatchUnpublishArticleEvent dispatch scheduled unpublish article event
+func (s *ScriptEngine) DispatchUnpublishArticleEvent(args ...interface{}) <-chan *CodeResult {
+	return s.DispatchByEventKey(domain.UnpublishArticleEvent, args...)
+}
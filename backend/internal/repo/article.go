@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,23 @@ func (a *ArticleRepository) Count(filter mongodb.Logical, opts ...*options.Count
 	coll := a.Db.Collection(ArticleCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *ArticleRepository) Aggregate(pipeline mongo.Pipeline, result interface{}, opts ...*options.AggregateOptions) error {
+	coll := a.Db.Collection(ArticleCollection)
+	return handleAggregate(coll, pipeline, result, opts...)
+}
+
+func (a *ArticleRepository) Each(filter mongodb.Logical, f func(entity *domain.Article) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(ArticleCollection)
+	return handleEach[domain.Article](coll, filter, f, opts...)
+}
+
+func (a *ArticleRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(ArticleCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *ArticleRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(ArticleCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -0,0 +1,258 @@
+package repo
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slog"
+)
+
+// BackupDocuments one collection documents that will be restored from backup
+type BackupDocuments struct {
+	Collection string
+	// Key identity field of document, like 'id', 'sign'. empty means collection holds one document in scope, such as
+	// meta, it is replaced whatever its '_id' is
+	Key string
+	// Scope limit the documents that belong to backup, empty is whole collection
+	Scope     bson.D
+	Keys      []interface{}
+	Documents []interface{}
+}
+
+// NewBackupDocuments build BackupDocuments by domain entity and identity key func
+func NewBackupDocuments[T interface{}](collection string, key string, scope bson.D, entities []*T, keyOf func(entity *T) interface{}) *BackupDocuments {
+	documents := &BackupDocuments{
+		Collection: collection,
+		Key:        key,
+		Scope:      scope,
+		Keys:       make([]interface{}, 0, len(entities)),
+		Documents:  make([]interface{}, 0, len(entities)),
+	}
+	for _, entity := range entities {
+		if entity == nil {
+			continue
+		}
+		documents.Keys = append(documents.Keys, keyOf(entity))
+		documents.Documents = append(documents.Documents, entity)
+	}
+	return documents
+}
+
+// BackupRestorer repository that backup documents of its collection are diffed and restored through
+type BackupRestorer interface {
+	BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error)
+	BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error
+}
+
+type BackupRepository struct {
+	Cfg            *config.Config
+	Db             *mongo.Database
+	ArticleRepo    *ArticleRepository
+	CategoryRepo   *CategoryRepository
+	CustomPageRepo *CustomPageRepository
+	DraftRepo      *DraftRepository
+	MetaRepo       *MetaRepository
+	PipelineRepo   *PipelineRepository
+	RevisionRepo   *RevisionRepository
+	SettingsRepo   *SettingsRepository
+	StaticRepo     *StaticRepository
+	UserRepo       *UserRepository
+	ViewerRepo     *ViewerRepository
+	VisitRepo      *VisitRepository
+}
+
+var BackupRepositorySet = wire.NewSet(wire.Struct(new(BackupRepository), "*"))
+
+// Diff compare backup documents with current database, not write anything
+func (b *BackupRepository) Diff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	restorer, err := b.restorer(documents.Collection)
+	if err != nil {
+		return nil, err
+	}
+	return restorer.BackupDiff(documents, mode)
+}
+
+// Restore write all backup documents in one transaction, any error will be rollback. transaction requires replica set
+// or sharded cluster, standalone server restores collections one by one and reports which were restored on error
+func (b *BackupRepository) Restore(backupDocuments []*BackupDocuments, mode domain.BackupImportMode) error {
+	restorers := make([]BackupRestorer, 0, len(backupDocuments))
+	for _, documents := range backupDocuments {
+		restorer, err := b.restorer(documents.Collection)
+		if err != nil {
+			return err
+		}
+		restorers = append(restorers, restorer)
+	}
+	transactional, err := b.transactional(context.Background())
+	if err != nil {
+		return err
+	}
+	if !transactional {
+		slog.Warn("Mongodb is standalone, backup is restored without transaction")
+		restored := make([]string, 0, len(backupDocuments))
+		for i, documents := range backupDocuments {
+			if err := restorers[i].BackupRestore(context.Background(), documents, mode); err != nil {
+				return fmt.Errorf("mongodb is standalone and can't rollback, restored collections %v before %s failed: %w", restored, documents.Collection, err)
+			}
+			restored = append(restored, documents.Collection)
+		}
+		return nil
+	}
+	session, err := b.Db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+	txnOptions := options.Transaction().SetWriteConcern(b.Db.WriteConcern())
+	_, err = session.WithTransaction(context.Background(), func(ctx mongo.SessionContext) (interface{}, error) {
+		for i, documents := range backupDocuments {
+			if err := restorers[i].BackupRestore(ctx, documents, mode); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}, txnOptions)
+	return err
+}
+
+// transactional whether server supports transaction, only replica set member and mongos do
+func (b *BackupRepository) transactional(ctx context.Context) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := b.Db.RunCommand(ctx, bson.D{{"hello", 1}}).Decode(&hello); err != nil {
+		return false, err
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// restorer repository of collection
+func (b *BackupRepository) restorer(collection string) (BackupRestorer, error) {
+	switch collection {
+	case ArticleCollection:
+		return b.ArticleRepo, nil
+	case CategoryCollection:
+		return b.CategoryRepo, nil
+	case CustomPageCollection:
+		return b.CustomPageRepo, nil
+	case DraftCollection:
+		return b.DraftRepo, nil
+	case MetaCollection:
+		return b.MetaRepo, nil
+	case PipelineCollection:
+		return b.PipelineRepo, nil
+	case RevisionCollection:
+		return b.RevisionRepo, nil
+	case SettingsCollection:
+		return b.SettingsRepo, nil
+	case StaticCollection:
+		return b.StaticRepo, nil
+	case UserCollection:
+		return b.UserRepo, nil
+	case ViewerCollection:
+		return b.ViewerRepo, nil
+	case VisitCollection:
+		return b.VisitRepo, nil
+	default:
+		return nil, errors.New("collection can't be restored from backup: " + collection)
+	}
+}
+
+// handleBackupDiff count documents of backup that exist in collection, and that would be removed when replace
+func handleBackupDiff(coll *mongo.Collection, documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	ctx := context.Background()
+	report := &domain.BackupCollectionReport{Collection: documents.Collection}
+	exist := int64(0)
+	if len(documents.Documents) > 0 {
+		filter := backupScope(documents)
+		if documents.Key != "" {
+			filter = append(bson.D{{Key: documents.Key, Value: bson.D{{Key: "$in", Value: documents.Keys}}}}, filter...)
+		}
+		count, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		exist = count
+		// singleton replaces at most one document
+		if documents.Key == "" && exist > 1 {
+			exist = 1
+		}
+	}
+	report.Insert = int64(len(documents.Documents)) - exist
+	report.Update = exist
+	if mode == domain.ReplaceImportMode {
+		total, err := coll.CountDocuments(ctx, backupScope(documents))
+		if err != nil {
+			return nil, err
+		}
+		report.Delete = total - exist
+	}
+	return report, nil
+}
+
+// handleBackupRestore write backup documents into collection within ctx transaction
+func handleBackupRestore(ctx context.Context, coll *mongo.Collection, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	writes := documents.Documents
+	if documents.Key == "" {
+		// '_id' of singleton may differ in type from current one, such as ObjectID created by older version
+		stripped, err := withoutObjectId(documents.Documents)
+		if err != nil {
+			return err
+		}
+		writes = stripped
+	}
+	if mode == domain.ReplaceImportMode {
+		if _, err := coll.DeleteMany(ctx, backupScope(documents)); err != nil {
+			return err
+		}
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := coll.InsertMany(ctx, writes)
+		return err
+	}
+	opt := options.Replace().SetUpsert(true)
+	for i, document := range writes {
+		filter := backupScope(documents)
+		if documents.Key != "" {
+			filter = bson.D{{Key: documents.Key, Value: documents.Keys[i]}}
+		}
+		if _, err := coll.ReplaceOne(ctx, filter, document, opt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutObjectId marshal documents and remove their '_id', replaced document keeps its own '_id'
+func withoutObjectId(documents []interface{}) ([]interface{}, error) {
+	stripped := make([]interface{}, 0, len(documents))
+	for _, document := range documents {
+		raw, err := bson.Marshal(document)
+		if err != nil {
+			return nil, err
+		}
+		d := bson.D{}
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil, err
+		}
+		d = lo.Filter(d, func(e bson.E, _ int) bool { return e.Key != "_id" })
+		stripped = append(stripped, d)
+	}
+	return stripped, nil
+}
+
+func backupScope(documents *BackupDocuments) bson.D {
+	if documents.Scope == nil {
+		return bson.D{}
+	}
+	return documents.Scope
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,18 @@ func (a *CategoryRepository) Count(filter mongodb.Logical, opts ...*options.Coun
 	coll := a.Db.Collection(CategoryCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *CategoryRepository) Each(filter mongodb.Logical, f func(entity *domain.Category) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(CategoryCollection)
+	return handleEach[domain.Category](coll, filter, f, opts...)
+}
+
+func (a *CategoryRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(CategoryCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *CategoryRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(CategoryCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/mongodb"
+	"context"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ChunkUploadRepository struct {
+	Cfg *config.Config
+	Db  *mongo.Database
+}
+
+var ChunkUploadRepositorySet = wire.NewSet(wire.Struct(new(ChunkUploadRepository), "*"))
+
+// Save session whose id has been generated by service
+func (a *ChunkUploadRepository) Save(insert *domain.ChunkUploadSession, opts ...*options.InsertOneOptions) error {
+	coll := a.Db.Collection(ChunkUploadCollection)
+	_, err := coll.InsertOne(context.TODO(), insert, opts...)
+	return err
+}
+
+func (a *ChunkUploadRepository) Update(filter mongodb.Logical, update bson.D, opts ...*options.UpdateOptions) (bool, error) {
+	coll := a.Db.Collection(ChunkUploadCollection)
+	return handleUpdate(coll, filter, update, opts...)
+}
+
+func (a *ChunkUploadRepository) Remove(filter mongodb.Logical, opts ...*options.DeleteOptions) (bool, error) {
+	coll := a.Db.Collection(ChunkUploadCollection)
+	return handleRemove(coll, filter, opts...)
+}
+
+func (a *ChunkUploadRepository) FindOne(filter mongodb.Logical, opts ...*options.FindOneOptions) (*domain.ChunkUploadSession, error) {
+	coll := a.Db.Collection(ChunkUploadCollection)
+	return handleFindOne[domain.ChunkUploadSession](coll, func() *domain.ChunkUploadSession { return &domain.ChunkUploadSession{} }, filter, opts...)
+}
+
+func (a *ChunkUploadRepository) FindList(filter mongodb.Logical, opts ...*options.FindOptions) ([]*domain.ChunkUploadSession, error) {
+	coll := a.Db.Collection(ChunkUploadCollection)
+	return handleFindList[domain.ChunkUploadSession](coll, filter, opts...)
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,18 @@ func (a *CustomPageRepository) Count(filter mongodb.Logical, opts ...*options.Co
 	coll := a.Db.Collection(CustomPageCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *CustomPageRepository) Each(filter mongodb.Logical, f func(entity *domain.CustomPage) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(CustomPageCollection)
+	return handleEach[domain.CustomPage](coll, filter, f, opts...)
+}
+
+func (a *CustomPageRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(CustomPageCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *CustomPageRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(CustomPageCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,23 @@ func (a *DraftRepository) Count(filter mongodb.Logical, opts ...*options.CountOp
 	coll := a.Db.Collection(DraftCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *DraftRepository) Aggregate(pipeline mongo.Pipeline, result interface{}, opts ...*options.AggregateOptions) error {
+	coll := a.Db.Collection(DraftCollection)
+	return handleAggregate(coll, pipeline, result, opts...)
+}
+
+func (a *DraftRepository) Each(filter mongodb.Logical, f func(entity *domain.Draft) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(DraftCollection)
+	return handleEach[domain.Draft](coll, filter, f, opts...)
+}
+
+func (a *DraftRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(DraftCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *DraftRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(DraftCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,13 @@ func (a *MetaRepository) Count(filter mongodb.Logical, opts ...*options.CountOpt
 	coll := a.Db.Collection(MetaCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *MetaRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(MetaCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *MetaRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(MetaCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,18 @@ func (a *PipelineRepository) Count(filter mongodb.Logical, opts ...*options.Coun
 	coll := a.Db.Collection(PipelineCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *PipelineRepository) Each(filter mongodb.Logical, f func(entity *domain.Pipeline) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(PipelineCollection)
+	return handleEach[domain.Pipeline](coll, filter, f, opts...)
+}
+
+func (a *PipelineRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(PipelineCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *PipelineRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(PipelineCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -12,33 +12,38 @@ import (
 )
 
 const (
-	VisitCollection      = "visits"
-	ArticleCollection    = "articles"
-	MetaCollection       = "metas"
-	SettingsCollection   = "settings"
-	TokenCollection      = "tokens"
-	UserCollection       = "users"
-	ViewerCollection     = "viewers"
-	CategoryCollection   = "categories"
-	DraftCollection      = "drafts"
-	StaticCollection     = "statics"
-	CustomPageCollection = "custompages"
-	PipelineCollection   = "pipelines"
+	VisitCollection       = "visits"
+	ArticleCollection     = "articles"
+	MetaCollection        = "metas"
+	SettingsCollection    = "settings"
+	TokenCollection       = "tokens"
+	UserCollection        = "users"
+	ViewerCollection      = "viewers"
+	CategoryCollection    = "categories"
+	DraftCollection       = "drafts"
+	StaticCollection      = "statics"
+	CustomPageCollection  = "custompages"
+	PipelineCollection    = "pipelines"
+	RevisionCollection    = "revisions"
+	ChunkUploadCollection = "chunkuploads"
 )
 
 type Repository struct {
-	ArticleRepository    *ArticleRepository
-	CategoryRepository   *CategoryRepository
-	DraftRepository      *DraftRepository
-	MetaRepository       *MetaRepository
-	SettingsRepository   *SettingsRepository
-	StaticRepository     *StaticRepository
-	TokenRepository      *TokenRepository
-	UserRepository       *UserRepository
-	ViewerRepository     *ViewerRepository
-	VisitRepository      *VisitRepository
-	CustomPageRepository *CustomPageRepository
-	PipelineRepository   *PipelineRepository
+	ArticleRepository     *ArticleRepository
+	CategoryRepository    *CategoryRepository
+	DraftRepository       *DraftRepository
+	MetaRepository        *MetaRepository
+	SettingsRepository    *SettingsRepository
+	StaticRepository      *StaticRepository
+	TokenRepository       *TokenRepository
+	UserRepository        *UserRepository
+	ViewerRepository      *ViewerRepository
+	VisitRepository       *VisitRepository
+	CustomPageRepository  *CustomPageRepository
+	PipelineRepository    *PipelineRepository
+	BackupRepository      *BackupRepository
+	RevisionRepository    *RevisionRepository
+	ChunkUploadRepository *ChunkUploadRepository
 }
 
 var RepositorySet = wire.NewSet(
@@ -54,6 +59,9 @@ var RepositorySet = wire.NewSet(
 	VisitRepositorySet,
 	CustomPageRepositorySet,
 	PipelineRepositorySet,
+	BackupRepositorySet,
+	RevisionRepositorySet,
+	ChunkUploadRepositorySet,
 	wire.Struct(new(Repository), "*"),
 )
 
@@ -178,6 +186,37 @@ func handleFindList[T interface{}](coll *mongo.Collection, filter mongodb.Logica
 	return domains, nil
 }
 
+// handleEach decode documents one by one and call f, documents are not held in memory together. it stops at the
+// first error returned by f
+func handleEach[T interface{}](coll *mongo.Collection, filter mongodb.Logical, f func(entity *T) error, opts ...*options.FindOptions) error {
+	ctx := context.Background()
+	cursor, err := coll.Find(ctx, filter.ToBson(), opts...)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		entity := new(T)
+		if err := cursor.Decode(entity); err != nil {
+			return err
+		}
+		if err := f(entity); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// handleAggregate handle aggregate, all documents of result are decoded into result slice
+func handleAggregate(coll *mongo.Collection, pipeline mongo.Pipeline, result interface{}, opts ...*options.AggregateOptions) error {
+	ctx := context.Background()
+	cursor, err := coll.Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, result)
+}
+
 // writeTransaction wrap write concern writeTransaction open a write concern, and execute argument func f
 func writeTransaction[T interface{}](db *mongo.Database, f func(ctx mongo.SessionContext) (T, error)) (T, error) {
 	wc := db.WriteConcern()
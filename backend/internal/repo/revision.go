@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/mongodb"
+	"context"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RevisionRepository struct {
+	Cfg *config.Config
+	Db  *mongo.Database
+}
+
+var RevisionRepositorySet = wire.NewSet(wire.Struct(new(RevisionRepository), "*"))
+
+func (a *RevisionRepository) Save(insert *domain.ArticleRevision, opts ...*options.InsertOneOptions) (uint64, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleSave[domain.ArticleRevision](coll, insert, opts...)
+}
+
+func (a *RevisionRepository) SaveMany(inserts []interface{}, opts ...*options.InsertManyOptions) ([]uint64, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleSaveMany(coll, inserts, opts...)
+}
+
+func (a *RevisionRepository) Update(filter mongodb.Logical, update bson.D, opts ...*options.UpdateOptions) (bool, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleUpdate(coll, filter, update, opts...)
+}
+
+func (a *RevisionRepository) UpdateMany(filter mongodb.Logical, update bson.D, opts ...*options.UpdateOptions) (bool, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleUpdateMany(coll, filter, update, opts...)
+}
+
+func (a *RevisionRepository) Remove(filter mongodb.Logical, opts ...*options.DeleteOptions) (bool, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleRemove(coll, filter, opts...)
+}
+
+func (a *RevisionRepository) RemoveMany(filter mongodb.Logical, opts ...*options.DeleteOptions) (bool, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleRemoveMany(coll, filter, opts...)
+}
+
+func (a *RevisionRepository) FindOne(filter mongodb.Logical, opts ...*options.FindOneOptions) (*domain.ArticleRevision, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleFindOne[domain.ArticleRevision](coll, func() *domain.ArticleRevision { return &domain.ArticleRevision{} }, filter, opts...)
+}
+
+func (a *RevisionRepository) FindList(filter mongodb.Logical, opts ...*options.FindOptions) ([]*domain.ArticleRevision, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleFindList[domain.ArticleRevision](coll, filter, opts...)
+}
+
+func (a *RevisionRepository) Count(filter mongodb.Logical, opts ...*options.CountOptions) (int64, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleCount(coll, filter, opts...)
+}
+
+func (a *RevisionRepository) Each(filter mongodb.Logical, f func(entity *domain.ArticleRevision) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleEach[domain.ArticleRevision](coll, filter, f, opts...)
+}
+
+func (a *RevisionRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *RevisionRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(RevisionCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,18 @@ func (a *SettingsRepository) Count(filter mongodb.Logical, opts ...*options.Coun
 	coll := a.Db.Collection(SettingsCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *SettingsRepository) Each(filter mongodb.Logical, f func(entity *domain.Setting) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(SettingsCollection)
+	return handleEach[domain.Setting](coll, filter, f, opts...)
+}
+
+func (a *SettingsRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(SettingsCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *SettingsRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(SettingsCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
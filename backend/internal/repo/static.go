@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,6 +18,19 @@ type StaticRepository struct {
 
 var StaticRepositorySet = wire.NewSet(wire.Struct(new(StaticRepository), "*"))
 
+// EnsureIndexes create unique index of content hash, statics without hash are not indexed
+func (a *StaticRepository) EnsureIndexes(ctx context.Context) error {
+	coll := a.Db.Collection(StaticCollection)
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().
+			SetName("hash_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.D{{Key: "hash", Value: bson.D{{Key: "$type", Value: "string"}}}}),
+	})
+	return err
+}
+
 func (a *StaticRepository) Save(insert *domain.Static, opts ...*options.InsertOneOptions) (uint64, error) {
 	coll := a.Db.Collection(StaticCollection)
 	return handleSave[domain.Static](coll, insert, opts...)
@@ -61,3 +75,18 @@ func (a *StaticRepository) Count(filter mongodb.Logical, opts ...*options.CountO
 	coll := a.Db.Collection(StaticCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *StaticRepository) Each(filter mongodb.Logical, f func(entity *domain.Static) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(StaticCollection)
+	return handleEach[domain.Static](coll, filter, f, opts...)
+}
+
+func (a *StaticRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(StaticCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *StaticRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(StaticCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
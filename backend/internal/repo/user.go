@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,18 @@ func (a *UserRepository) Count(filter mongodb.Logical, opts ...*options.CountOpt
 	coll := a.Db.Collection(UserCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *UserRepository) Each(filter mongodb.Logical, f func(entity *domain.User) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(UserCollection)
+	return handleEach[domain.User](coll, filter, f, opts...)
+}
+
+func (a *UserRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(UserCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *UserRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(UserCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,18 @@ func (a *ViewerRepository) Count(filter mongodb.Logical, opts ...*options.CountO
 	coll := a.Db.Collection(ViewerCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *ViewerRepository) Each(filter mongodb.Logical, f func(entity *domain.Viewer) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(ViewerCollection)
+	return handleEach[domain.Viewer](coll, filter, f, opts...)
+}
+
+func (a *ViewerRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(ViewerCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *ViewerRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(ViewerCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -4,6 +4,7 @@ import (
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/pkg/mongodb"
+	"context"
 	"github.com/google/wire"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -61,3 +62,18 @@ func (a *VisitRepository) Count(filter mongodb.Logical, opts ...*options.CountOp
 	coll := a.Db.Collection(VisitCollection)
 	return handleCount(coll, filter, opts...)
 }
+
+func (a *VisitRepository) Each(filter mongodb.Logical, f func(entity *domain.Visit) error, opts ...*options.FindOptions) error {
+	coll := a.Db.Collection(VisitCollection)
+	return handleEach[domain.Visit](coll, filter, f, opts...)
+}
+
+func (a *VisitRepository) BackupDiff(documents *BackupDocuments, mode domain.BackupImportMode) (*domain.BackupCollectionReport, error) {
+	coll := a.Db.Collection(VisitCollection)
+	return handleBackupDiff(coll, documents, mode)
+}
+
+func (a *VisitRepository) BackupRestore(ctx context.Context, documents *BackupDocuments, mode domain.BackupImportMode) error {
+	coll := a.Db.Collection(VisitCollection)
+	return handleBackupRestore(ctx, coll, documents, mode)
+}
@@ -5,6 +5,7 @@ import (
 	"cc.allio/fusion/internal/credential"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/markdown"
 	"cc.allio/fusion/pkg/mongodb"
 	"cc.allio/fusion/pkg/util"
 	"errors"
@@ -44,6 +45,7 @@ var (
 		{"author", 1},
 		{"copyright", 1},
 		{"pathname", 1},
+		{"wordCount", 1},
 	}
 
 	AdminView = bson.D{
@@ -64,6 +66,9 @@ var (
 		{"author", 1},
 		{"copyright", 1},
 		{"pathname", 1},
+		{"wordCount", 1},
+		{"publishAt", 1},
+		{"unpublishAt", 1},
 	}
 
 	ListView = bson.D{
@@ -84,6 +89,7 @@ var (
 		{"author", 1},
 		{"copyright", 1},
 		{"pathname", 1},
+		{"wordCount", 1},
 	}
 )
 
@@ -92,6 +98,8 @@ type ArticleService struct {
 	MetaService  *MetaService
 	ArticleRepo  *repo.ArticleRepository
 	CategoryRepo *repo.CategoryRepository
+	SearchSvr    *SearchService
+	RevisionSvr  *RevisionService
 }
 
 var ArticleServiceSet = wire.NewSet(wire.Struct(new(ArticleService), "*"))
@@ -115,29 +123,102 @@ func (a *ArticleService) DeleteById(id int64) bool {
 		slog.Error("Delete article has err", "err", err)
 		return false
 	}
+	a.SearchSvr.Remove(uint64(id))
 	return updated
 }
 
 func (a *ArticleService) Create(article *domain.Article) (*domain.Article, error) {
+	article.WordCount = util.WordCount(article.Content)
+	hideUntilPublish(article, false)
 	id, err := a.ArticleRepo.Save(article)
 	if err != nil {
 		return nil, err
 	}
 	article.Id = id
+	a.SearchSvr.Reindex(id)
 	return article, nil
 }
 
 func (a *ArticleService) UpdateById(id uint64, article *domain.Article) bool {
 	filter := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id})
-	articleBson := util.ToBsonElements(article, "id")
-	update := bson.D{{"$set", articleBson}}
+	article.WordCount = util.WordCount(article.Content)
+	scheduledHidden := false
+	if stored, err := a.ArticleRepo.FindOne(filter); err == nil {
+		scheduledHidden = stored.ScheduledHidden
+	}
+	hideUntilPublish(article, scheduledHidden)
+	update := bson.D{{"$set", articleUpdate(article)}}
+	a.RevisionSvr.Initial(id)
 	updated, err := a.ArticleRepo.Update(filter, update)
 	if err != nil {
 		return false
 	}
+	a.SearchSvr.Reindex(id)
+	if _, err := a.RevisionSvr.Record(id, domain.UpdateRevisionSource, 0); err != nil {
+		slog.Error("Failed to record revision of article", "err", err, "id", id)
+	}
 	return updated
 }
 
+// hideUntilPublish article scheduled to publish in the future is hidden until published by ScheduleService, scheduled
+// is whether article is hidden by schedule already. article hidden by author is not shown by ScheduleService
+func hideUntilPublish(article *domain.Article, scheduled bool) {
+	article.ScheduledHidden = scheduled
+	if article.PublishAt != nil && article.PublishAt.After(time.Now()) && !article.Hidden {
+		article.Hidden, article.ScheduledHidden = true, true
+	}
+}
+
+// CancelSchedule clear scheduled publishing and unpublishing of article, article hidden by schedule keeps hidden
+func (a *ArticleService) CancelSchedule(id uint64) (bool, error) {
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id})
+	filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
+	update := bson.D{{"$set", bson.D{{"publishAt", nil}, {"unpublishAt", nil}, {"scheduledHidden", false}}}}
+	return a.ArticleRepo.Update(filter, update)
+}
+
+// articleUpdate elements to set of article, publishAt and unpublishAt omitted by payload are left out,
+// so saving article won't clear its pending schedule
+func articleUpdate(article *domain.Article) bson.D {
+	exclude := []string{"id"}
+	if article.PublishAt == nil {
+		exclude = append(exclude, "publishAt")
+	}
+	if article.UnpublishAt == nil {
+		exclude = append(exclude, "unpublishAt")
+	}
+	return util.ToBsonElements(article, exclude...)
+}
+
+// RestoreRevision overwrite title, content, tags, category and author of article with revision, restoring is recorded
+// as a new revision
+func (a *ArticleService) RestoreRevision(id uint64, revisionId uint64) (*domain.Article, error) {
+	revision, err := a.RevisionSvr.Get(id, revisionId)
+	if err != nil {
+		return nil, err
+	}
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id})
+	filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
+	update := bson.D{{"$set", bson.D{
+		{"title", revision.Title},
+		{"content", revision.Content},
+		{"tags", revision.Tags},
+		{"category", revision.Category},
+		{"author", revision.Author},
+		{"wordCount", util.WordCount(revision.Content)},
+		{"updatedAt", time.Now()},
+	}}}
+	a.RevisionSvr.Initial(id)
+	if _, err := a.ArticleRepo.Update(filter, update); err != nil {
+		return nil, err
+	}
+	a.SearchSvr.Reindex(id)
+	if _, err := a.RevisionSvr.Record(id, domain.RestoreRevisionSource, revisionId); err != nil {
+		slog.Error("Failed to record revision of restored article", "err", err, "id", id, "revisionId", revisionId)
+	}
+	return a.ArticleRepo.FindOne(filter)
+}
+
 func (a *ArticleService) GetById(id uint64) *domain.Article {
 	filter := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id})
 	filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
@@ -308,6 +389,7 @@ func (a *ArticleService) GetByOption(option credential.ArticleSearchOptionCreden
 					Viewer:          article.Viewer,
 					Visited:         article.Visited,
 					Copyright:       article.Copyright,
+					WordCount:       article.WordCount,
 					LastVisitedTime: article.LastVisitedTime,
 					CreatedAt:       article.CreatedAt,
 					UpdatedAt:       article.UpdatedAt,
@@ -348,6 +430,7 @@ func (a *ArticleService) GetByOption(option credential.ArticleSearchOptionCreden
 				Viewer:          article.Viewer,
 				Visited:         article.Visited,
 				Copyright:       article.Copyright,
+				WordCount:       article.WordCount,
 				LastVisitedTime: article.LastVisitedTime,
 				CreatedAt:       article.CreatedAt,
 				UpdatedAt:       article.UpdatedAt,
@@ -359,6 +442,43 @@ func (a *ArticleService) GetByOption(option credential.ArticleSearchOptionCreden
 	return result
 }
 
+// Query articles by composable filter with multiple sort keys, facets of tags and category are counted among all
+// matched articles
+func (a *ArticleService) Query(query *credential.QueryCredential) (*domain.ArticleQueryResult, error) {
+	pipeline, err := articleQuerySchema.pipeline(query)
+	if err != nil {
+		return nil, err
+	}
+	a.fillWordCount()
+	results := make([]*queryResult[domain.Article], 0, 1)
+	if err := a.ArticleRepo.Aggregate(pipeline, &results); err != nil {
+		return nil, err
+	}
+	result := &domain.ArticleQueryResult{Articles: make([]*domain.Article, 0)}
+	if len(results) > 0 {
+		result.Articles = append(result.Articles, results[0].Items...)
+		result.Total = results[0].total()
+		result.Facets = results[0].facets(query)
+	}
+	return result, nil
+}
+
+// fillWordCount count words of articles saved before word count is recorded, such as restored from backup
+func (a *ArticleService) fillWordCount() {
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "wordCount", Value: bson.D{{"$exists", false}}})
+	articles, err := a.ArticleRepo.FindList(filter, options.Find().SetProjection(bson.D{{"id", 1}, {"content", 1}}))
+	if err != nil {
+		slog.Error("Failed to find articles without word count", "err", err)
+		return
+	}
+	for _, article := range articles {
+		update := bson.D{{"$set", bson.D{{"wordCount", util.WordCount(article.Content)}}}}
+		if _, err := a.ArticleRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: article.Id}), update); err != nil {
+			slog.Error("Failed to fill word count of article", "err", err, "id", article.Id)
+		}
+	}
+}
+
 func (a *ArticleService) GetAll(view ArticleView, includeHidden bool, includeDelete bool) []*domain.Article {
 	viewFilter := a.getView(view)
 	if viewFilter == nil {
@@ -467,7 +587,12 @@ func (a *ArticleService) GetTotalNum(includeHidden bool) int64 {
 
 // UpdateTags by article id set new tags
 func (a *ArticleService) UpdateTags(id uint64, newTags []string) (bool, error) {
-	return a.ArticleRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id}), bson.D{{"$set", bson.D{{"tags", newTags}}}})
+	updated, err := a.ArticleRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id}), bson.D{{"$set", bson.D{{"tags", newTags}}}})
+	if err != nil {
+		return false, err
+	}
+	a.SearchSvr.Reindex(id)
+	return updated, nil
 }
 
 func (a *ArticleService) getArticleByIdOrPathname(idOrPathname string) *domain.Article {
@@ -503,9 +628,16 @@ func (a *ArticleService) GetArticleByIdOrPathnameWithAlternate(idOrPathname stri
 			article.Content = ""
 		}
 	}
+	var rendered *markdown.Rendered
+	if lo.IsNotEmpty(article.Content) {
+		var err error
+		if rendered, err = markdown.Render(article.Content); err != nil {
+			slog.Error("Failed to render article content", "err", err, "id", article.Id)
+		}
+	}
 	preArticle := a.getPreArticle(article.CreatedAt, false)
 	nextArticle := a.getNextArticle(article.CreatedAt, false)
-	return &credential.AlternateArticle{Article: article, Pre: preArticle, Next: nextArticle}, nil
+	return &credential.AlternateArticle{Article: article, Pre: preArticle, Next: nextArticle, Rendered: rendered}, nil
 }
 
 func (a *ArticleService) getPreArticle(createTime time.Time, includeHidden bool) *domain.Article {
@@ -581,21 +713,6 @@ func (a *ArticleService) GetArticleByIdOrPathnameWithPassword(idOrPathname strin
 	return nil
 }
 
-func (a *ArticleService) SearchByText(text string, includeHidden bool) []*domain.Article {
-	filter := mongodb.NewLogical()
-	regexText := bson.D{{"$regex", text}, {"$options", "i"}}
-	filter.AppendLogical(mongodb.NewLogicalDefaultArray(bson.D{{"content", regexText}, {"title", regexText}, {"category", regexText}, {"tags", regexText}}))
-	filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
-	if includeHidden {
-		filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(HiddenFilter))
-	}
-	articles, err := a.ArticleRepo.FindList(filter)
-	if err != nil {
-		return make([]*domain.Article, 0)
-	}
-	return articles
-}
-
 func (a *ArticleService) UpdateViewerByPathname(pathname string, isNew bool) {
 	article := a.getArticleByIdOrPathname(pathname)
 	if article != nil {
@@ -0,0 +1,46 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestArticleUpdate_KeepOmittedSchedule(t *testing.T) {
+	update := articleUpdate(&domain.Article{Id: 1, Title: "title"}).Map()
+	assert.NotContains(t, update, "id")
+	assert.NotContains(t, update, "publishAt")
+	assert.NotContains(t, update, "unpublishAt")
+	assert.Equal(t, "title", update["title"])
+
+	publishAt := time.Now().Add(time.Hour)
+	update = articleUpdate(&domain.Article{Id: 1, PublishAt: &publishAt}).Map()
+	assert.Contains(t, update, "publishAt")
+	assert.NotContains(t, update, "unpublishAt")
+}
+
+func TestHideUntilPublish(t *testing.T) {
+	future, past := time.Now().Add(time.Hour), time.Now().Add(-time.Hour)
+	cases := []struct {
+		name            string
+		article         *domain.Article
+		scheduled       bool
+		hidden          bool
+		scheduledHidden bool
+	}{
+		{"visible scheduled article", &domain.Article{PublishAt: &future}, false, true, true},
+		{"hidden by author", &domain.Article{PublishAt: &future, Hidden: true}, false, true, false},
+		{"hidden by schedule already", &domain.Article{PublishAt: &future, Hidden: true}, true, true, true},
+		{"payload flag is ignored", &domain.Article{PublishAt: &future, Hidden: true, ScheduledHidden: true}, false, true, false},
+		{"past schedule", &domain.Article{PublishAt: &past}, false, false, false},
+		{"omitted schedule keeps flag", &domain.Article{Hidden: true}, true, true, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hideUntilPublish(c.article, c.scheduled)
+			assert.Equal(t, c.hidden, c.article.Hidden)
+			assert.Equal(t, c.scheduledHidden, c.article.ScheduledHidden)
+		})
+	}
+}
@@ -0,0 +1,600 @@
+package svr
+
+import (
+	"bufio"
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/archive"
+	"cc.allio/fusion/pkg/env"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/retention"
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/util"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/goccy/go-json"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slog"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	manifestEntry   = "manifest.json"
+	collectionsDir  = "collections"
+	staticsDir      = "statics"
+	tagsCollection  = "tags"
+	ndjsonExtension = ".ndjson"
+	// snapshotDir storage directory of scheduled backup archives
+	snapshotDir    = "/fusion-backup"
+	snapshotPrefix = "fusion-backup-"
+	snapshotExt    = ".tar.gz"
+	snapshotLayout = "20060102150405"
+)
+
+var snapshotNameRegexp = regexp.MustCompile(`^` + snapshotPrefix + `(\d{14})` + regexp.QuoteMeta(snapshotExt) + `$`)
+
+type BackupService struct {
+	Cfg            *config.Config
+	BackupRepo     *repo.BackupRepository
+	ArticleRepo    *repo.ArticleRepository
+	CategoryRepo   *repo.CategoryRepository
+	CustomPageRepo *repo.CustomPageRepository
+	DraftRepo      *repo.DraftRepository
+	PipelineRepo   *repo.PipelineRepository
+	RevisionRepo   *repo.RevisionRepository
+	SettingsRepo   *repo.SettingsRepository
+	StaticRepo     *repo.StaticRepository
+	UserRepo       *repo.UserRepository
+	ViewerRepo     *repo.ViewerRepository
+	VisitRepo      *repo.VisitRepository
+	MetaSvr        *MetaService
+	TagSvr         *TagService
+	FileSvr        *FileService
+	SearchSvr      *SearchService
+}
+
+var BackupServiceSet = wire.NewSet(wire.Struct(new(BackupService), "*"))
+
+// Export stream .tar.gz backup archive into w. the archive first entry is manifest,
+// then each collection as ndjson, at last binary static files. documents are spooled into temporary files one by one,
+// so that the whole collection is never held in memory
+func (b *BackupService) Export(ctx context.Context, w io.Writer) error {
+	aw := archive.NewWriter(w)
+	manifest := &domain.BackupManifest{
+		SchemaVersion: domain.BackupSchemaVersion,
+		Version:       env.Version,
+		CreatedAt:     time.Now(),
+		Collections:   make([]*domain.BackupManifestCollection, 0),
+	}
+	statics := make([]*domain.Static, 0)
+	collections := []struct {
+		name string
+		each func(encode func(v interface{}) error) error
+	}{
+		{repo.ArticleCollection, func(encode func(v interface{}) error) error {
+			return b.ArticleRepo.Each(mongodb.NewLogicalOrDefaultArray(DeleteFilter), func(article *domain.Article) error { return encode(article) })
+		}},
+		{repo.CategoryCollection, func(encode func(v interface{}) error) error {
+			return b.CategoryRepo.Each(mongodb.NewLogical(), func(category *domain.Category) error { return encode(category) })
+		}},
+		{tagsCollection, func(encode func(v interface{}) error) error { return encodeAll(encode, b.TagSvr.GetAllTags(true)) }},
+		{repo.MetaCollection, func(encode func(v interface{}) error) error { return encode(b.MetaSvr.GetMeta()) }},
+		{repo.DraftCollection, func(encode func(v interface{}) error) error {
+			return b.DraftRepo.Each(mongodb.NewLogical(), func(draft *domain.Draft) error { return encode(draft) })
+		}},
+		{repo.UserCollection, func(encode func(v interface{}) error) error {
+			return b.UserRepo.Each(mongodb.NewLogical(), func(user *domain.User) error { return encode(user) })
+		}},
+		{repo.CustomPageCollection, func(encode func(v interface{}) error) error {
+			return b.CustomPageRepo.Each(mongodb.NewLogical(), func(customPage *domain.CustomPage) error { return encode(customPage) })
+		}},
+		{repo.PipelineCollection, func(encode func(v interface{}) error) error {
+			return b.PipelineRepo.Each(mongodb.NewLogical(), func(pipeline *domain.Pipeline) error { return encode(pipeline) })
+		}},
+		{repo.RevisionCollection, func(encode func(v interface{}) error) error {
+			return b.RevisionRepo.Each(mongodb.NewLogical(), func(revision *domain.ArticleRevision) error { return encode(revision) })
+		}},
+		{repo.ViewerCollection, func(encode func(v interface{}) error) error {
+			return b.ViewerRepo.Each(mongodb.NewLogical(), func(viewer *domain.Viewer) error { return encode(viewer) })
+		}},
+		{repo.VisitCollection, func(encode func(v interface{}) error) error {
+			return b.VisitRepo.Each(mongodb.NewLogical(), func(visit *domain.Visit) error { return encode(visit) })
+		}},
+		{repo.StaticCollection, func(encode func(v interface{}) error) error {
+			return b.StaticRepo.Each(mongodb.NewLogical(), func(static *domain.Static) error {
+				statics = append(statics, static)
+				return encode(static)
+			})
+		}},
+		{repo.SettingsCollection, func(encode func(v interface{}) error) error {
+			return b.SettingsRepo.Each(mongodb.NewLogical(), func(setting *domain.Setting) error { return encode(setting) })
+		}},
+	}
+	spools := make([]*archive.NdjsonSpool, 0, len(collections))
+	defer func() {
+		for _, spool := range spools {
+			spool.Close()
+		}
+	}()
+	for _, collection := range collections {
+		spool, err := archive.NewNdjsonSpool()
+		if err != nil {
+			return err
+		}
+		spools = append(spools, spool)
+		if err := collection.each(spool.Encode); err != nil {
+			return err
+		}
+		manifest.Collections = append(manifest.Collections, &domain.BackupManifestCollection{Name: collection.name, File: collectionEntry(collection.name), Count: spool.Count()})
+	}
+	manifest.Files = lo.Map(statics, func(static *domain.Static, _ int) *domain.BackupManifestFile {
+		return &domain.BackupManifestFile{Sign: static.Sign, RealPath: static.RealPath, File: staticEntry(static.RealPath)}
+	})
+	if err := aw.WriteJson(manifestEntry, manifest); err != nil {
+		return err
+	}
+	for i, collection := range manifest.Collections {
+		if err := aw.WriteSpool(collection.File, spools[i]); err != nil {
+			return err
+		}
+	}
+	for _, file := range manifest.Files {
+		if err := b.exportFile(ctx, aw, file); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+func encodeAll[T interface{}](encode func(v interface{}) error, values []T) error {
+	for _, v := range values {
+		if err := encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportFile write static file into archive, its size is taken from the opened object since static record may be stale.
+// files that can't be downloaded are skipped rather than abort the whole archive
+func (b *BackupService) exportFile(ctx context.Context, aw *archive.Writer, file *domain.BackupManifestFile) error {
+	content, err := b.FileSvr.Download(ctx, file.RealPath)
+	if err != nil {
+		slog.Warn("Static file can't be downloaded, skip backup it", "err", err, "realPath", file.RealPath)
+		return nil
+	}
+	defer content.Close()
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return aw.WriteFile(file.File, size, time.Now(), content)
+}
+
+// ImportFrom restore backup from r, r either .tar.gz archive created by Export or legacy backup.json
+func (b *BackupService) ImportFrom(ctx context.Context, r io.Reader, mode domain.BackupImportMode, dryRun bool) (*domain.BackupImportReport, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if archive.IsGzip(magic) {
+		return b.ImportArchive(ctx, reader, mode, dryRun)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	backup := &domain.Backup{}
+	if err := json.Unmarshal(body, backup); err != nil {
+		return nil, err
+	}
+	return b.Import(backup, mode, dryRun)
+}
+
+// ImportArchive restore .tar.gz backup archive. static files are staged into temporary files and stored through
+// storage driver before documents are restored, they are rolled back when storing or restoring documents failed, so that
+// failed or dry run import leaves storage untouched
+func (b *BackupService) ImportArchive(ctx context.Context, r io.Reader, mode domain.BackupImportMode, dryRun bool) (*domain.BackupImportReport, error) {
+	ar, err := archive.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer ar.Close()
+
+	header, content, err := ar.Next()
+	if err != nil {
+		return nil, err
+	}
+	if header.Name != manifestEntry {
+		return nil, errors.New("backup archive first entry must be " + manifestEntry)
+	}
+	manifest := &domain.BackupManifest{}
+	if err := json.NewDecoder(content).Decode(manifest); err != nil {
+		return nil, err
+	}
+	if manifest.SchemaVersion > domain.BackupSchemaVersion {
+		return nil, fmt.Errorf("backup schema version %d is newer than supported %d", manifest.SchemaVersion, domain.BackupSchemaVersion)
+	}
+	realPaths := make(map[string]string, len(manifest.Files))
+	for _, file := range manifest.Files {
+		realPath, err := backupRealPath(file.RealPath)
+		if err != nil {
+			return nil, err
+		}
+		realPaths[file.File] = realPath
+	}
+
+	backup := &domain.Backup{}
+	staged := make([]*stagedFile, 0)
+	defer func() {
+		for _, file := range staged {
+			file.Close()
+		}
+	}()
+	files := int64(0)
+	for {
+		header, content, err := ar.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if realPath, ok := realPaths[header.Name]; ok {
+			files++
+			if dryRun {
+				continue
+			}
+			file, err := stageFile(realPath, content)
+			if err != nil {
+				return nil, err
+			}
+			staged = append(staged, file)
+			continue
+		}
+		if err := b.readCollection(backup, header.Name, content); err != nil {
+			return nil, err
+		}
+	}
+	stored := &storedFiles{created: make([]string, 0), previous: make([]*stagedFile, 0)}
+	defer stored.Close()
+	if err := b.storeFiles(ctx, staged, stored); err != nil {
+		b.rollbackFiles(ctx, stored)
+		return nil, err
+	}
+	report, err := b.Import(backup, mode, dryRun)
+	if err != nil {
+		b.rollbackFiles(ctx, stored)
+		return nil, err
+	}
+	report.Files = files
+	return report, nil
+}
+
+// storedFiles static files written by import, overwritten files keep their previous content to be rolled back
+type storedFiles struct {
+	created  []string
+	previous []*stagedFile
+}
+
+// Close remove temporary files of previous content
+func (s *storedFiles) Close() {
+	for _, file := range s.previous {
+		file.Close()
+	}
+}
+
+// storeFiles store staged files through storage driver, the previous content of existing file is staged before it is
+// overwritten
+func (b *BackupService) storeFiles(ctx context.Context, staged []*stagedFile, stored *storedFiles) error {
+	for _, file := range staged {
+		if exist, err := b.FileSvr.Download(ctx, file.realPath); err == nil {
+			previous, err := stageFile(file.realPath, exist)
+			exist.Close()
+			if err != nil {
+				return err
+			}
+			stored.previous = append(stored.previous, previous)
+		} else {
+			stored.created = append(stored.created, file.realPath)
+		}
+		if err := b.FileSvr.Store(ctx, file.realPath, file.size, io.NopCloser(file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackFiles remove created files and write back previous content of overwritten files
+func (b *BackupService) rollbackFiles(ctx context.Context, stored *storedFiles) {
+	if len(stored.created) > 0 {
+		if err := b.FileSvr.Delete(ctx, stored.created); err != nil {
+			slog.Error("Failed to remove restored static files", "err", err, "files", stored.created)
+		}
+	}
+	for _, file := range stored.previous {
+		if err := b.FileSvr.Store(ctx, file.realPath, file.size, io.NopCloser(file)); err != nil {
+			slog.Error("Failed to roll back overwritten static file", "err", err, "realPath", file.realPath)
+		}
+	}
+}
+
+// backupRealPath clean reality path of backup file, it must be under storage.OsPathPrefix so that crafted archive can't
+// write outside of storage
+func backupRealPath(realPath string) (string, error) {
+	cleaned := path.Clean("/" + realPath)
+	if !strings.HasPrefix(cleaned, storage.OsPathPrefix+"/") {
+		return "", errors.New("invalid backup file path: " + realPath)
+	}
+	return cleaned, nil
+}
+
+// stagedFile static file of backup archive waiting to be stored
+type stagedFile struct {
+	*os.File
+	realPath string
+	size     uint64
+}
+
+func stageFile(realPath string, content io.Reader) (*stagedFile, error) {
+	temp, err := os.CreateTemp("", "fusion-restore-*")
+	if err != nil {
+		return nil, err
+	}
+	file := &stagedFile{File: temp, realPath: realPath}
+	size, err := io.Copy(temp, content)
+	if err == nil {
+		_, err = temp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	file.size = uint64(size)
+	return file, nil
+}
+
+// Close close and remove temporary file
+func (f *stagedFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.Name()))
+}
+
+func (b *BackupService) readCollection(backup *domain.Backup, entry string, content io.Reader) error {
+	var err error
+	switch entry {
+	case collectionEntry(repo.ArticleCollection):
+		backup.Articles, err = archive.ReadNdjson(content, func() *domain.Article { return &domain.Article{} })
+	case collectionEntry(repo.CategoryCollection):
+		backup.Categories, err = archive.ReadNdjson(content, func() *domain.Category { return &domain.Category{} })
+	case collectionEntry(repo.DraftCollection):
+		backup.Drafts, err = archive.ReadNdjson(content, func() *domain.Draft { return &domain.Draft{} })
+	case collectionEntry(repo.ViewerCollection):
+		backup.Viewers, err = archive.ReadNdjson(content, func() *domain.Viewer { return &domain.Viewer{} })
+	case collectionEntry(repo.VisitCollection):
+		backup.Visits, err = archive.ReadNdjson(content, func() *domain.Visit { return &domain.Visit{} })
+	case collectionEntry(repo.StaticCollection):
+		backup.Static, err = archive.ReadNdjson(content, func() *domain.Static { return &domain.Static{} })
+	case collectionEntry(repo.MetaCollection):
+		metas, readErr := archive.ReadNdjson(content, func() *domain.Meta { return &domain.Meta{} })
+		if len(metas) > 0 {
+			backup.Meta = metas[0]
+		}
+		err = readErr
+	case collectionEntry(repo.UserCollection):
+		backup.Users, err = archive.ReadNdjson(content, func() *domain.User { return &domain.User{} })
+	case collectionEntry(repo.CustomPageCollection):
+		backup.CustomPages, err = archive.ReadNdjson(content, func() *domain.CustomPage { return &domain.CustomPage{} })
+	case collectionEntry(repo.PipelineCollection):
+		backup.Pipelines, err = archive.ReadNdjson(content, func() *domain.Pipeline { return &domain.Pipeline{} })
+	case collectionEntry(repo.RevisionCollection):
+		backup.Revisions, err = archive.ReadNdjson(content, func() *domain.ArticleRevision { return &domain.ArticleRevision{} })
+	case collectionEntry(repo.SettingsCollection):
+		backup.Settings, err = archive.ReadNdjson(content, func() *domain.Setting { return &domain.Setting{} })
+	default:
+		// tags derive from articles, unknown entries are ignored
+		slog.Debug("Skip backup archive entry", "entry", entry)
+	}
+	return err
+}
+
+// Import restore backup into system. when dryRun is true, only report what would change.
+func (b *BackupService) Import(backup *domain.Backup, mode domain.BackupImportMode, dryRun bool) (*domain.BackupImportReport, error) {
+	if mode != domain.MergeImportMode && mode != domain.ReplaceImportMode {
+		return nil, errors.New("unknown backup import mode: " + mode)
+	}
+	backupDocuments, err := b.toBackupDocuments(backup)
+	if err != nil {
+		return nil, err
+	}
+	report := &domain.BackupImportReport{Mode: mode, DryRun: dryRun, Collections: make([]*domain.BackupCollectionReport, 0, len(backupDocuments))}
+	for _, documents := range backupDocuments {
+		collectionReport, err := b.BackupRepo.Diff(documents, mode)
+		if err != nil {
+			return nil, err
+		}
+		report.Collections = append(report.Collections, collectionReport)
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := b.BackupRepo.Restore(backupDocuments, mode); err != nil {
+		slog.Error("Failed to restore backup, transaction has been aborted", "err", err, "mode", mode)
+		return nil, err
+	}
+	b.SearchSvr.Invalidate()
+	return report, nil
+}
+
+// toBackupDocuments for each collection build documents with identity key
+func (b *BackupService) toBackupDocuments(backup *domain.Backup) ([]*repo.BackupDocuments, error) {
+	backupDocuments := []*repo.BackupDocuments{
+		repo.NewBackupDocuments(repo.ArticleCollection, "id", nil, backup.Articles, func(article *domain.Article) interface{} { return article.Id }),
+		repo.NewBackupDocuments(repo.CategoryCollection, "id", nil, backup.Categories, func(category *domain.Category) interface{} { return category.Id }),
+		repo.NewBackupDocuments(repo.DraftCollection, "id", nil, backup.Drafts, func(draft *domain.Draft) interface{} { return draft.Id }),
+		repo.NewBackupDocuments(repo.ViewerCollection, "id", nil, backup.Viewers, func(viewer *domain.Viewer) interface{} { return viewer.Id }),
+		repo.NewBackupDocuments(repo.VisitCollection, "id", nil, backup.Visits, func(visit *domain.Visit) interface{} { return visit.Id }),
+		repo.NewBackupDocuments(repo.StaticCollection, "sign", nil, backup.Static, func(static *domain.Static) interface{} { return static.Sign }),
+		repo.NewBackupDocuments(repo.CustomPageCollection, "id", nil, backup.CustomPages, func(customPage *domain.CustomPage) interface{} { return customPage.Id }),
+		repo.NewBackupDocuments(repo.PipelineCollection, "id", nil, backup.Pipelines, func(pipeline *domain.Pipeline) interface{} { return pipeline.Id }),
+		repo.NewBackupDocuments(repo.RevisionCollection, "id", nil, backup.Revisions, func(revision *domain.ArticleRevision) interface{} { return revision.Id }),
+	}
+	// meta only has one document, it is matched regardless of its '_id', which is ObjectID when created by older version
+	if backup.Meta != nil {
+		backupDocuments = append(backupDocuments, repo.NewBackupDocuments(repo.MetaCollection, "", nil, []*domain.Meta{backup.Meta}, func(meta *domain.Meta) interface{} { return nil }))
+	}
+	// backup archive holds all users and settings, legacy backup.json only holds admin and static setting, collaborators
+	// and other settings are kept
+	if len(backup.Users) > 0 {
+		backupDocuments = append(backupDocuments, repo.NewBackupDocuments(repo.UserCollection, "id", nil, backup.Users, func(user *domain.User) interface{} { return user.Id }))
+	} else if backup.User != nil {
+		scope := bson.D{{Key: "id", Value: AdminId}}
+		backupDocuments = append(backupDocuments, repo.NewBackupDocuments(repo.UserCollection, "id", scope, []*domain.User{backup.User}, func(user *domain.User) interface{} { return user.Id }))
+	}
+	if len(backup.Settings) > 0 {
+		backupDocuments = append(backupDocuments, repo.NewBackupDocuments(repo.SettingsCollection, "type", nil, backup.Settings, func(setting *domain.Setting) interface{} { return setting.Type }))
+	} else if backup.Setting.Static != nil {
+		id, err := mongodb.NextId()
+		if err != nil {
+			return nil, err
+		}
+		setting := &domain.Setting{Id: id, Type: StaticSettingType, Value: util.EntityToMap[*domain.StaticSetting](backup.Setting.Static)}
+		scope := bson.D{{Key: "type", Value: StaticSettingType}}
+		backupDocuments = append(backupDocuments, repo.NewBackupDocuments(repo.SettingsCollection, "type", scope, []*domain.Setting{setting}, func(setting *domain.Setting) interface{} { return setting.Type }))
+	}
+	return backupDocuments, nil
+}
+
+// SnapshotName backup archive name of time t
+func SnapshotName(t time.Time) string {
+	return snapshotPrefix + t.Format(snapshotLayout) + snapshotExt
+}
+
+// Snapshot export backup archive and store it through storage driver
+func (b *BackupService) Snapshot(ctx context.Context) (*domain.BackupSnapshot, error) {
+	temp, err := os.CreateTemp("", snapshotPrefix+"*"+snapshotExt)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		temp.Close()
+		os.Remove(temp.Name())
+	}()
+	if err := b.Export(ctx, temp); err != nil {
+		return nil, err
+	}
+	size, err := temp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	snapshot := &domain.BackupSnapshot{Name: SnapshotName(now), Size: uint64(size), CreatedAt: now}
+	if err := b.FileSvr.Store(ctx, snapshotPath(snapshot.Name), snapshot.Size, io.NopCloser(temp)); err != nil {
+		return nil, err
+	}
+	slog.Info("Backup snapshot stored", "name", snapshot.Name, "size", snapshot.Size)
+	return snapshot, nil
+}
+
+// ListSnapshots returns stored backup archives, newest first
+func (b *BackupService) ListSnapshots(ctx context.Context) ([]*domain.BackupSnapshot, error) {
+	objects, err := b.FileSvr.List(ctx, snapshotDir, false)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]*domain.BackupSnapshot, 0, len(objects))
+	for _, object := range objects {
+		if object.IsDir {
+			continue
+		}
+		createdAt, ok := snapshotTime(object.Name)
+		if !ok {
+			continue
+		}
+		snapshots = append(snapshots, &domain.BackupSnapshot{Name: object.Name, Size: object.Size, CreatedAt: createdAt})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
+
+// DownloadSnapshot obtain stored backup archive content by name
+func (b *BackupService) DownloadSnapshot(ctx context.Context, name string) (storage.RSCloser, error) {
+	if _, ok := snapshotTime(name); !ok {
+		return nil, errors.New("invalid backup snapshot name: " + name)
+	}
+	return b.FileSvr.Download(ctx, snapshotPath(name))
+}
+
+// DeleteSnapshot remove stored backup archive by name
+func (b *BackupService) DeleteSnapshot(ctx context.Context, name string) error {
+	if _, ok := snapshotTime(name); !ok {
+		return errors.New("invalid backup snapshot name: " + name)
+	}
+	return b.FileSvr.Delete(ctx, []string{snapshotPath(name)})
+}
+
+// Prune remove stored backup archives that not kept by retention policy of setting, returns removed names
+func (b *BackupService) Prune(ctx context.Context, setting *domain.BackupSetting) ([]string, error) {
+	policy := retention.Policy{KeepLast: int(setting.KeepLast), KeepDaily: int(setting.KeepDaily), KeepWeekly: int(setting.KeepWeekly)}
+	if policy.Unlimited() {
+		return nil, nil
+	}
+	snapshots, err := b.ListSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	times := lo.Map(snapshots, func(snapshot *domain.BackupSnapshot, _ int) time.Time { return snapshot.CreatedAt })
+	keep := policy.Keep(times)
+	removed := make([]string, 0)
+	for i, snapshot := range snapshots {
+		if keep[i] {
+			continue
+		}
+		if err := b.DeleteSnapshot(ctx, snapshot.Name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, snapshot.Name)
+	}
+	return removed, nil
+}
+
+func snapshotPath(name string) string {
+	return path.Join(snapshotDir, name)
+}
+
+// snapshotTime parse created time from snapshot name, reports false if name is not snapshot
+func snapshotTime(name string) (time.Time, bool) {
+	matches := snapshotNameRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(snapshotLayout, matches[1], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func collectionEntry(collection string) string {
+	return path.Join(collectionsDir, collection+ndjsonExtension)
+}
+
+func staticEntry(realPath string) string {
+	return path.Join(staticsDir, strings.TrimPrefix(realPath, "/"))
+}
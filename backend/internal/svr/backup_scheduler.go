@@ -0,0 +1,92 @@
+package svr
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"context"
+	"github.com/google/wire"
+	"github.com/robfig/cron/v3"
+	"golang.org/x/exp/slog"
+	"sync"
+)
+
+// BackupScheduler periodically store backup snapshot according to domain.BackupSetting
+type BackupScheduler struct {
+	Cfg        *config.Config
+	BackupSvr  *BackupService
+	SettingSvr *SettingService
+
+	mu   sync.Mutex
+	cron *cron.Cron
+}
+
+var BackupSchedulerSet = wire.NewSet(wire.Struct(new(BackupScheduler), "Cfg", "BackupSvr", "SettingSvr"))
+
+// Start schedule backup job by current setting, failure only be logged
+func (s *BackupScheduler) Start() {
+	if err := s.Reload(); err != nil {
+		slog.Error("Failed to start backup scheduler", "err", err)
+	}
+}
+
+// Reload stop running schedule, then re-schedule by latest setting. invoke it after backup setting changed
+func (s *BackupScheduler) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	setting := s.SettingSvr.FindBackupSetting()
+	if !setting.Enable {
+		slog.Info("Backup scheduler is disabled")
+		return nil
+	}
+	c := cron.New()
+	if _, err := c.AddFunc(setting.Cron, s.run); err != nil {
+		return err
+	}
+	c.Start()
+	s.cron = c
+	slog.Info("Backup scheduler started", "cron", setting.Cron)
+	return nil
+}
+
+// Stop running schedule, wait for running job complete
+func (s *BackupScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+func (s *BackupScheduler) stop() {
+	if s.cron == nil {
+		return
+	}
+	<-s.cron.Stop().Done()
+	s.cron = nil
+}
+
+// Run store one snapshot then prune outdated snapshots by retention setting
+func (s *BackupScheduler) Run(ctx context.Context) (*domain.BackupSnapshot, error) {
+	snapshot, err := s.BackupSvr.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := s.BackupSvr.Prune(ctx, s.SettingSvr.FindBackupSetting())
+	if err != nil {
+		slog.Error("Failed to prune backup snapshots", "err", err)
+	} else if len(removed) > 0 {
+		slog.Info("Pruned backup snapshots", "removed", removed)
+	}
+	return snapshot, nil
+}
+
+func (s *BackupScheduler) run() {
+	if _, err := s.Run(context.Background()); err != nil {
+		slog.Error("Failed to run scheduled backup", "err", err)
+	}
+}
+
+// ValidateCron reports whether expr is standard cron expression like '0 3 * * *'
+func ValidateCron(expr string) error {
+	_, err := cron.ParseStandard(expr)
+	return err
+}
@@ -0,0 +1,472 @@
+package svr
+
+import (
+	"bytes"
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/img"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/driver"
+	"cc.allio/fusion/pkg/storage/filesystem"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/exp/slog"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultChunkSize bytes of part when client not specifies
+	DefaultChunkSize = 8 << 20
+	// MinChunkSize object store rejects part less than 5MB except the last one
+	MinChunkSize = 5 << 20
+	MaxChunkSize = 64 << 20
+	// chunkUploadExpiry session that not completed in time will be aborted
+	chunkUploadExpiry = 24 * time.Hour
+)
+
+var (
+	ErrChunkUploadNotFound = errors.New("chunk upload not found")
+	ErrChunkUploadInvalid  = errors.New("invalid chunk upload")
+)
+
+// ChunkUploadService resumable upload of large file. parts are staged through multipart upload of storage driver,
+// then assembled and saved as domain.Static when completed. sessions are persisted, so that upload is resumed after
+// restart, expired ones are aborted on startup and whenever upload starts. metadata of images uploaded by chunk are
+// stripped as well, but they have no thumbnails and variants
+type ChunkUploadService struct {
+	FileSvr         *FileService
+	StaticSvr       *StaticService
+	SettingSvr      *SettingService
+	ChunkUploadRepo *repo.ChunkUploadRepository
+}
+
+var ChunkUploadServiceSet = wire.NewSet(wire.Struct(new(ChunkUploadService), "*"))
+
+// chunkSession persisted session along with storage that its parts are staged in
+type chunkSession struct {
+	*domain.ChunkUploadSession
+	fs      *filesystem.FileSystem
+	handler driver.MultipartHandler
+}
+
+// Init check upload policy and start multipart upload of file
+func (s *ChunkUploadService) Init(ctx context.Context, cred *credential.ChunkUploadCredential, owner *domain.User) (*domain.ChunkUpload, error) {
+	filename := filepath.Base(filepath.Clean("/" + cred.Filename))
+	if filename == "/" || cred.Size <= 0 {
+		return nil, fmt.Errorf("%w: filename and size are required", ErrChunkUploadInvalid)
+	}
+	chunkSize := cred.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("%w: chunk size must between %d and %d bytes", ErrChunkUploadInvalid, MinChunkSize, MaxChunkSize)
+	}
+	policy := s.SettingSvr.FindStaticSetting().UploadPolicy
+	if policy.ChunkMaxSize > 0 && cred.Size > policy.ChunkMaxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrUploadTooLarge, cred.Size, policy.ChunkMaxSize)
+	}
+	if err := s.StaticSvr.checkQuota(policy, owner, uint64(cred.Size)); err != nil {
+		return nil, err
+	}
+	s.Sweep(ctx)
+
+	fs, handler, err := s.FileSvr.Multipart()
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	upload := &domain.ChunkUpload{
+		Id:        hex.EncodeToString(id),
+		Filename:  filename,
+		Size:      cred.Size,
+		ChunkSize: chunkSize,
+		Total:     int((cred.Size + chunkSize - 1) / chunkSize),
+		Parts:     make([]int, 0),
+		ExpiresAt: time.Now().Add(chunkUploadExpiry),
+	}
+	// upload id takes place of content hash in path, content is not known yet
+	realPath := fs.Policy.GenerateOsPath(&storage.FileHeader{FilePath: "/" + filename, Hash: upload.Id})
+	uploadId, err := handler.InitMultipart(ctx, realPath)
+	if err != nil {
+		return nil, err
+	}
+	session := &domain.ChunkUploadSession{
+		ChunkUpload: *upload,
+		Mode:        fs.Policy.Mode,
+		RealPath:    realPath,
+		UploadId:    uploadId,
+		Staged:      make(map[string]*domain.ChunkUploadPart),
+	}
+	if owner != nil {
+		session.Owner = owner.Id
+	}
+	if err := s.ChunkUploadRepo.Save(session); err != nil {
+		if abortErr := handler.AbortMultipart(ctx, realPath, uploadId); abortErr != nil {
+			slog.Warn("Failed to abort chunk upload", "err", abortErr, "realPath", realPath)
+		}
+		return nil, err
+	}
+	return upload, nil
+}
+
+// UploadPart upload part by number, part uploaded again replaces the previous one. the first part decides mime type
+// that must be allowed by upload policy
+func (s *ChunkUploadService) UploadPart(ctx context.Context, id string, number int, part []byte, owner *domain.User) (*domain.ChunkUpload, error) {
+	session, err := s.session(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if number < 1 || number > session.Total {
+		return nil, fmt.Errorf("%w: part number must between 1 and %d", ErrChunkUploadInvalid, session.Total)
+	}
+	expected := session.ChunkSize
+	if number == session.Total {
+		expected = session.Size - session.ChunkSize*int64(session.Total-1)
+	}
+	if int64(len(part)) != expected {
+		return nil, fmt.Errorf("%w: part %d must be %d bytes, got %d", ErrChunkUploadInvalid, number, expected, len(part))
+	}
+	if session.Completing {
+		return nil, fmt.Errorf("%w: upload is being completed", ErrChunkUploadInvalid)
+	}
+	set := bson.D{}
+	if number == 1 {
+		policy := s.SettingSvr.FindStaticSetting().UploadPolicy
+		mimeType, err := checkMimeType(policy, session.Filename, mimetype.Detect(part))
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, bson.E{Key: "mimeType", Value: mimeType})
+	}
+
+	etag, err := session.handler.UploadPart(ctx, session.RealPath, session.UploadId, number, part)
+	if err != nil {
+		return nil, err
+	}
+	staged := &domain.ChunkUploadPart{Number: number, ETag: etag, Size: int64(len(part))}
+	set = append(set, bson.E{Key: "staged." + strconv.Itoa(number), Value: staged})
+	if _, err := s.ChunkUploadRepo.Update(chunkFilter(id), bson.D{{"$set", set}}); err != nil {
+		return nil, err
+	}
+	s.hashPart(session.ChunkUploadSession, number, part)
+	// session completed or aborted meanwhile is not found
+	session, err = s.session(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	return session.status(), nil
+}
+
+// hashPart content hash is computed along with parts uploaded in order, otherwise assembled file is read again when
+// completed. hash state advances only from the part right before, so that concurrent parts are never hashed twice
+func (s *ChunkUploadService) hashPart(session *domain.ChunkUploadSession, number int, part []byte) {
+	var update bson.D
+	switch {
+	case session.Hashed >= 0 && number == session.Hashed+1:
+		h := sha256.New()
+		if session.HashState != nil {
+			if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.HashState); err != nil {
+				slog.Warn("Failed to restore hash state of chunk upload", "err", err, "id", session.Id)
+				return
+			}
+		}
+		h.Write(part)
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			slog.Warn("Failed to save hash state of chunk upload", "err", err, "id", session.Id)
+			return
+		}
+		update = bson.D{{"$set", bson.D{{"hashed", number}, {"hashState", state}}}}
+	case number <= session.Hashed:
+		update = bson.D{{"$set", bson.D{{"hashed", -1}, {"hashState", nil}}}}
+	default:
+		return
+	}
+	filter := chunkFilter(session.Id).Append(bson.E{Key: "hashed", Value: session.Hashed})
+	if _, err := s.ChunkUploadRepo.Update(filter, update); err != nil {
+		slog.Warn("Failed to hash part of chunk upload", "err", err, "id", session.Id, "number", number)
+	}
+}
+
+// Status obtain uploaded parts of upload
+func (s *ChunkUploadService) Status(id string, owner *domain.User) (*domain.ChunkUpload, error) {
+	session, err := s.session(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	return session.status(), nil
+}
+
+// Complete assemble all parts then save as static, the existing static is returned when content has been uploaded
+func (s *ChunkUploadService) Complete(ctx context.Context, id string, owner *domain.User) (*credential.FilePathCredential, error) {
+	session, err := s.session(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if len(session.Staged) != session.Total {
+		return nil, fmt.Errorf("%w: %d of %d parts uploaded", ErrChunkUploadInvalid, len(session.Staged), session.Total)
+	}
+	parts := make([]storage.Part, 0, session.Total)
+	for number := 1; number <= session.Total; number++ {
+		staged, ok := session.Staged[strconv.Itoa(number)]
+		if !ok {
+			return nil, fmt.Errorf("%w: part %d is not uploaded", ErrChunkUploadInvalid, number)
+		}
+		parts = append(parts, storage.Part{Number: staged.Number, ETag: staged.ETag, Size: staged.Size})
+	}
+	if !s.mark(id, true) {
+		return nil, fmt.Errorf("%w: upload is being completed", ErrChunkUploadInvalid)
+	}
+
+	if err := session.handler.CompleteMultipart(ctx, session.RealPath, session.UploadId, parts); err != nil {
+		if !driver.IsPermanent(err) {
+			// parts are still staged, complete can be retried
+			s.mark(id, false)
+			return nil, err
+		}
+		if s.remove(chunkFilter(id)) {
+			s.abort(ctx, session)
+		}
+		return nil, err
+	}
+	// parts have been assembled, upload can't be resumed anymore
+	s.remove(chunkFilter(id))
+	if err := s.sanitize(ctx, session); err != nil {
+		slog.Warn("Failed to sanitize chunk uploaded image, save original", "err", err, "realPath", session.RealPath)
+	}
+	contentHash, err := s.contentHash(ctx, session)
+	if err != nil {
+		s.discard(ctx, session)
+		return nil, err
+	}
+	if existing, err := s.StaticSvr.GetByHash(contentHash); err == nil {
+		s.discard(ctx, session)
+		return &credential.FilePathCredential{Src: StaticUrl(existing.RealPath), IsNew: false}, nil
+	}
+
+	sign, err := session.fs.Handler.Sign(ctx, &storage.FileHeader{Filename: session.Filename, FilePath: "/" + session.Filename})
+	if err != nil {
+		s.discard(ctx, session)
+		return nil, err
+	}
+	storageType := domain.FileCustomType
+	if img.IsImage(session.Filename) {
+		storageType = domain.ImgStaticType
+	}
+	static := &domain.Static{
+		Name:        session.Filename,
+		StaticType:  session.fs.Policy.Mode,
+		StorageType: storageType,
+		FileType:    filepath.Ext(session.Filename),
+		RealPath:    session.RealPath,
+		Sign:        sign,
+		Size:        uint64(session.Size),
+		MimeType:    session.MimeType,
+		Owner:       session.Owner,
+		Hash:        contentHash,
+		UpdatedAt:   time.Now(),
+	}
+	return s.StaticSvr.save(ctx, static)
+}
+
+// Abort discard upload and its uploaded parts
+func (s *ChunkUploadService) Abort(ctx context.Context, id string, owner *domain.User) error {
+	session, err := s.session(id, owner)
+	if err != nil {
+		return err
+	}
+	if !s.claim(id) {
+		return ErrChunkUploadNotFound
+	}
+	return s.abort(ctx, session)
+}
+
+// Sweep abort expired uploads, invoke it at startup so that parts staged before restart are discarded
+func (s *ChunkUploadService) Sweep(ctx context.Context) {
+	expired, err := s.ChunkUploadRepo.FindList(mongodb.NewLogicalDefault(bson.E{Key: "expiresAt", Value: bson.D{{"$lt", time.Now()}}}))
+	if err != nil {
+		slog.Error("Failed to find expired chunk uploads", "err", err)
+		return
+	}
+	for _, session := range expired {
+		// session being completed over expiry has been interrupted
+		if !s.remove(chunkFilter(session.Id)) {
+			continue
+		}
+		fs, handler, err := s.FileSvr.Multipart()
+		if err != nil || fs.Policy.Mode != session.Mode {
+			slog.Warn("Storage of expired chunk upload is not available, staged parts are left", "err", err, "id", session.Id, "mode", session.Mode)
+			continue
+		}
+		s.abort(ctx, &chunkSession{ChunkUploadSession: session, fs: fs, handler: handler})
+	}
+}
+
+// contentHash hex sha256 of assembled file, svg is checked meanwhile since its script may span parts
+func (s *ChunkUploadService) contentHash(ctx context.Context, session *chunkSession) (string, error) {
+	if session.Hashed == session.Total && session.HashState != nil && session.MimeType != "image/svg+xml" {
+		h := sha256.New()
+		if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.HashState); err == nil {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+	}
+	content, err := session.fs.Handler.Download(ctx, session.RealPath)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	if session.MimeType == "image/svg+xml" {
+		if err := img.CheckSvg(content); err != nil {
+			return "", err
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// session find unexpired session of owner and storage that its parts are staged in
+func (s *ChunkUploadService) session(id string, owner *domain.User) (*chunkSession, error) {
+	session, err := s.ChunkUploadRepo.FindOne(chunkFilter(id))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrChunkUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrChunkUploadNotFound
+	}
+	// upload is only visible to its owner
+	var ownerId uint64
+	if owner != nil {
+		ownerId = owner.Id
+	}
+	if session.Owner != ownerId {
+		return nil, ErrChunkUploadNotFound
+	}
+	fs, handler, err := s.FileSvr.Multipart()
+	if err != nil {
+		return nil, err
+	}
+	if fs.Policy.Mode != session.Mode {
+		return nil, fmt.Errorf("%w: storage has been changed from %s to %s", ErrChunkUploadInvalid, session.Mode, fs.Policy.Mode)
+	}
+	return &chunkSession{ChunkUploadSession: session, fs: fs, handler: handler}, nil
+}
+
+// claim remove session before it is aborted, reports false if it has been removed or is being completed by others
+func (s *ChunkUploadService) claim(id string) bool {
+	return s.remove(chunkFilter(id).Append(bson.E{Key: "completing", Value: bson.D{{"$ne", true}}}))
+}
+
+// mark session being completed or not, reports false if it has been marked or removed by others
+func (s *ChunkUploadService) mark(id string, completing bool) bool {
+	filter := chunkFilter(id).Append(bson.E{Key: "completing", Value: bson.D{{"$ne", completing}}})
+	marked, err := s.ChunkUploadRepo.Update(filter, bson.D{{"$set", bson.D{{"completing", completing}}}})
+	if err != nil {
+		slog.Error("Failed to mark chunk upload", "err", err, "id", id, "completing", completing)
+		return false
+	}
+	return marked
+}
+
+// remove session by filter, reports false if it has been removed by others
+func (s *ChunkUploadService) remove(filter mongodb.Logical) bool {
+	removed, err := s.ChunkUploadRepo.Remove(filter)
+	if err != nil {
+		slog.Error("Failed to remove chunk upload", "err", err)
+		return false
+	}
+	return removed
+}
+
+func (s *ChunkUploadService) abort(ctx context.Context, session *chunkSession) error {
+	err := session.handler.AbortMultipart(ctx, session.RealPath, session.UploadId)
+	if err != nil {
+		slog.Warn("Failed to abort chunk upload", "err", err, "id", session.Id, "realPath", session.RealPath)
+	}
+	return err
+}
+
+// sanitize strip metadata of assembled image the same as image uploaded at once, it is re-encoded and uploaded in place
+func (s *ChunkUploadService) sanitize(ctx context.Context, session *chunkSession) error {
+	format, err := imaging.FormatFromFilename(session.Filename)
+	if err != nil || !img.Sanitizable(format) || s.SettingSvr.FindStaticSetting().KeepMetadata {
+		return nil
+	}
+	content, err := session.fs.Handler.Download(ctx, session.RealPath)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	// reject huge image before reading it into memory
+	if err := img.CheckDimension(content); err != nil {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	sanitized, _, err := img.Sanitize(data, format)
+	if err != nil {
+		return err
+	}
+	stream := &storage.FileStream{File: io.NopCloser(bytes.NewReader(sanitized)), Size: uint64(len(sanitized)), VirtualPath: session.RealPath, Name: session.Filename, SavePath: session.RealPath}
+	if err := session.fs.Handler.Upload(ctx, stream); err != nil {
+		return err
+	}
+	session.Size = int64(len(sanitized))
+	// hash state of parts is outdated
+	session.Hashed, session.HashState = -1, nil
+	return nil
+}
+
+// discard assembled file that will not be saved
+func (s *ChunkUploadService) discard(ctx context.Context, session *chunkSession) {
+	if _, err := session.fs.Handler.Remove(ctx, []string{session.RealPath}); err != nil {
+		slog.Warn("Failed to remove assembled chunk upload", "err", err, "realPath", session.RealPath)
+	}
+}
+
+func (c *chunkSession) status() *domain.ChunkUpload {
+	upload := c.ChunkUpload
+	upload.Parts = make([]int, 0, len(c.Staged))
+	for _, part := range c.Staged {
+		upload.Parts = append(upload.Parts, part.Number)
+	}
+	sort.Ints(upload.Parts)
+	return &upload
+}
+
+func chunkFilter(id string) mongodb.Logical {
+	return mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id})
+}
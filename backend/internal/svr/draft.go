@@ -18,9 +18,10 @@ import (
 )
 
 type DraftService struct {
-	Cfg        *config.Config
-	DraftRepo  *repo.DraftRepository
-	ArticleSvr *ArticleService
+	Cfg         *config.Config
+	DraftRepo   *repo.DraftRepository
+	ArticleSvr  *ArticleService
+	RevisionSvr *RevisionService
 }
 
 var DraftServiceSet = wire.NewSet(wire.Struct(new(DraftService), "*"))
@@ -99,6 +100,43 @@ func (d *DraftService) GetByOption(option *credential.DraftSearchOptionCredentia
 	return &domain.DraftPageResult{Drafts: drafts, Total: count}
 }
 
+// Query drafts by composable filter with multiple sort keys, facets of tags and category are counted among all
+// matched drafts
+func (d *DraftService) Query(query *credential.QueryCredential) (*domain.DraftQueryResult, error) {
+	pipeline, err := draftQuerySchema.pipeline(query)
+	if err != nil {
+		return nil, err
+	}
+	d.fillWordCount()
+	results := make([]*queryResult[domain.Draft], 0, 1)
+	if err := d.DraftRepo.Aggregate(pipeline, &results); err != nil {
+		return nil, err
+	}
+	result := &domain.DraftQueryResult{Drafts: make([]*domain.Draft, 0)}
+	if len(results) > 0 {
+		result.Drafts = append(result.Drafts, results[0].Items...)
+		result.Total = results[0].total()
+		result.Facets = results[0].facets(query)
+	}
+	return result, nil
+}
+
+// fillWordCount count words of drafts saved before word count is recorded
+func (d *DraftService) fillWordCount() {
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "wordCount", Value: bson.D{{"$exists", false}}})
+	drafts, err := d.DraftRepo.FindList(filter, options.Find().SetProjection(bson.D{{"id", 1}, {"content", 1}}))
+	if err != nil {
+		slog.Error("Failed to find drafts without word count", "err", err)
+		return
+	}
+	for _, draft := range drafts {
+		update := bson.D{{"$set", bson.D{{"wordCount", util.WordCount(draft.Content)}}}}
+		if _, err := d.DraftRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: draft.Id}), update); err != nil {
+			slog.Error("Failed to fill word count of draft", "err", err, "id", draft.Id)
+		}
+	}
+}
+
 func (d *DraftService) GetById(id int64) (*domain.Draft, error) {
 	filter := mongodb.NewLogical()
 	// delete
@@ -112,11 +150,13 @@ func (d *DraftService) DeleteById(id int64) (bool, error) {
 }
 
 func (d *DraftService) UpdateById(id int64, draft *domain.Draft) (bool, error) {
+	draft.WordCount = util.WordCount(draft.Content)
 	update := util.ToBsonElements(draft)
 	return d.DraftRepo.Update(mongodb.NewLogicalOrDefault(bson.E{Key: "id", Value: id}), update)
 }
 
 func (d *DraftService) Create(draft *domain.Draft) (*domain.Draft, error) {
+	draft.WordCount = util.WordCount(draft.Content)
 	saved, err := d.DraftRepo.Save(draft)
 	if err != nil {
 		return nil, err
@@ -143,6 +183,43 @@ func (d *DraftService) Publish(id int64, option *credential.DraftPublishCredenti
 		Private:   option.Private,
 		Password:  option.Password,
 		Copyright: option.Copyright,
+		// published article is unpublished by ScheduleService
+		UnpublishAt: option.UnpublishAt,
 	}
-	return d.ArticleSvr.Create(article)
+	article, err = d.ArticleSvr.Create(article)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.RevisionSvr.Record(article.Id, domain.PublishRevisionSource, 0); err != nil {
+		slog.Error("Failed to record revision of published article", "err", err, "id", article.Id)
+	}
+	return article, nil
+}
+
+// SchedulePublish record publish time and options on draft, it is published by ScheduleService at then
+func (d *DraftService) SchedulePublish(id int64, option *credential.DraftPublishCredential) (*domain.Draft, error) {
+	if option.PublishAt == nil {
+		return nil, errors.New("publish time of draft is required")
+	}
+	publishOption := &domain.DraftPublishOption{
+		Hidden:      option.Hidden,
+		Pathname:    option.Pathname,
+		Private:     option.Private,
+		Password:    option.Password,
+		Copyright:   option.Copyright,
+		UnpublishAt: option.UnpublishAt,
+	}
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id})
+	filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
+	update := bson.D{{"$set", bson.D{{"publishAt", option.PublishAt}, {"publishOption", publishOption}}}}
+	if _, err := d.DraftRepo.Update(filter, update); err != nil {
+		return nil, err
+	}
+	return d.GetById(id)
+}
+
+// CancelSchedule clear scheduled publishing of draft
+func (d *DraftService) CancelSchedule(id int64) (bool, error) {
+	update := bson.D{{"$set", bson.D{{"publishAt", nil}, {"publishOption", nil}}}}
+	return d.DraftRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id}), update)
 }
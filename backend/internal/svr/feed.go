@@ -0,0 +1,143 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/markdown"
+	"errors"
+	"github.com/google/wire"
+	"github.com/gorilla/feeds"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FeedFormat = string
+
+const (
+	RssFeedFormat  FeedFormat = "rss"
+	AtomFeedFormat FeedFormat = "atom"
+	JsonFeedFormat FeedFormat = "json"
+)
+
+// ErrFeedDisabled site info turns rss off
+var ErrFeedDisabled = errors.New("feed is disabled")
+
+// FeedService generate rss, atom and json feed of recent public articles. hidden, deleted, private articles and
+// articles of private category are excluded. generated feeds are cached until Refresh
+type FeedService struct {
+	ArticleSvr  *ArticleService
+	CategorySvr *CategoryService
+	MetaSvr     *MetaService
+	SettingSvr  *SettingService
+
+	feeds regenerated[map[FeedFormat]string]
+}
+
+var FeedServiceSet = wire.NewSet(wire.Struct(new(FeedService), "ArticleSvr", "CategorySvr", "MetaSvr", "SettingSvr"))
+
+// Feed obtain generated feed of format, it is generated when first requested
+func (s *FeedService) Feed(format FeedFormat) (string, error) {
+	if siteInfo := s.MetaSvr.GetSiteInfo(); siteInfo != nil && siteInfo.ShowRSS == "false" {
+		return "", ErrFeedDisabled
+	}
+	generated, err := s.feeds.get(s.generate)
+	if err != nil {
+		return "", err
+	}
+	return generated[format], nil
+}
+
+// Refresh regenerate feeds in background, outdated feeds are served until regenerated
+func (s *FeedService) Refresh() {
+	s.feeds.refresh("feed", s.generate)
+}
+
+func (s *FeedService) generate() (map[FeedFormat]string, error) {
+	siteInfo := s.MetaSvr.GetSiteInfo()
+	if siteInfo == nil {
+		siteInfo = &domain.SiteInfo{}
+	}
+	setting := s.SettingSvr.FindFeedSetting()
+	baseUrl := strings.TrimSuffix(siteInfo.BaseUrl, "/")
+
+	privateCategories := privateCategoryNames(s.CategorySvr.GetAllCategories())
+	articles := lo.Filter(s.ArticleSvr.GetAll(PublicArticleView, false, false), func(article *domain.Article, index int) bool {
+		return !article.Hidden && !article.Deleted && !article.Private && lo.IsEmpty(article.Password) && !privateCategories[article.Category]
+	})
+	if setting.Limit > 0 && int64(len(articles)) > setting.Limit {
+		articles = articles[:setting.Limit]
+	}
+
+	feed := &feeds.Feed{
+		Title:       siteInfo.SiteName,
+		Link:        &feeds.Link{Href: baseUrl},
+		Description: siteInfo.SiteDesc,
+		Author:      &feeds.Author{Name: siteInfo.Author},
+		Id:          baseUrl + "/",
+		Created:     siteInfo.Since,
+		Updated:     time.Now(),
+	}
+	if lo.IsNotEmpty(siteInfo.SiteLogo) {
+		feed.Image = &feeds.Image{Url: siteInfo.SiteLogo, Title: siteInfo.SiteName, Link: baseUrl}
+	}
+	if len(articles) > 0 {
+		feed.Updated = articles[0].UpdatedAt
+	}
+	for _, article := range articles {
+		link := baseUrl + "/post/" + strconv.FormatUint(article.Id, 10)
+		if lo.IsNotEmpty(article.Pathname) {
+			link = baseUrl + "/post/" + article.Pathname
+		}
+		if article.UpdatedAt.After(feed.Updated) {
+			feed.Updated = article.UpdatedAt
+		}
+		author := article.Author
+		if lo.IsEmpty(author) {
+			author = siteInfo.Author
+		}
+		item := &feeds.Item{
+			Title:   article.Title,
+			Link:    &feeds.Link{Href: link},
+			Id:      link,
+			Author:  &feeds.Author{Name: author},
+			Created: article.CreatedAt,
+			Updated: article.UpdatedAt,
+		}
+		rendered, err := markdown.Render(article.Content)
+		if err != nil {
+			slog.Error("Failed to render article content of feed", "err", err, "id", article.Id)
+		} else {
+			item.Description = markdown.Summary(rendered, int(setting.SummaryLength))
+			if setting.Full {
+				item.Content = rendered.Html
+			}
+		}
+		feed.Add(item)
+	}
+
+	generated := make(map[FeedFormat]string, 3)
+	var err error
+	if generated[RssFeedFormat], err = feed.ToRss(); err != nil {
+		return nil, err
+	}
+	if generated[AtomFeedFormat], err = feed.ToAtom(); err != nil {
+		return nil, err
+	}
+	if generated[JsonFeedFormat], err = feed.ToJSON(); err != nil {
+		return nil, err
+	}
+	return generated, nil
+}
+
+// privateCategoryNames names of private categories, articles of them are not public
+func privateCategoryNames(categories []*domain.Category) map[string]bool {
+	names := make(map[string]bool)
+	for _, category := range categories {
+		if category.Private {
+			names[category.Name] = true
+		}
+	}
+	return names
+}
@@ -1,15 +1,24 @@
 package svr
 
 import (
+	"bytes"
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/internal/repo"
 	"cc.allio/fusion/pkg/img"
 	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/driver"
 	"cc.allio/fusion/pkg/storage/filesystem"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/disintegration/imaging"
 	"github.com/google/wire"
 	"golang.org/x/exp/slog"
+	"image"
+	"io"
+	"os"
 	"time"
 )
 
@@ -21,6 +30,32 @@ type FileService struct {
 
 var FileServiceSet = wire.NewSet(wire.Struct(new(FileService), "*"))
 
+// Hash compute sha256 of header content while spooling it to temporary file, header content is replaced by the
+// temporary file that will be removed when closed
+func (f *FileService) Hash(header *storage.FileHeader) error {
+	tmp, err := os.CreateTemp("", "fusion-upload-")
+	if err != nil {
+		return err
+	}
+	spooled := &tempFile{File: tmp}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), header.File)
+	header.File.Close()
+	if err != nil {
+		spooled.Close()
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return err
+	}
+	header.File = spooled
+	header.Size = uint64(size)
+	header.Hash = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Upload file to current storage, reality path is header SavePath or addressed by header Hash when present
 func (f *FileService) Upload(ctx context.Context, header *storage.FileHeader) (*domain.Static, error) {
 	policy := f.createPolicyBySetting()
 	fs, err := f.chooseFs(policy)
@@ -28,7 +63,10 @@ func (f *FileService) Upload(ctx context.Context, header *storage.FileHeader) (*
 		return nil, err
 	}
 
-	osPath := policy.GenerateOsPath(header)
+	osPath := header.SavePath
+	if osPath == "" {
+		osPath = policy.GenerateOsPath(header)
+	}
 	fileStream := &storage.FileStream{
 		File:        header.File,
 		Size:        header.Size,
@@ -62,11 +100,118 @@ func (f *FileService) Upload(ctx context.Context, header *storage.FileHeader) (*
 		FileType:    header.Ext,
 		RealPath:    osPath,
 		Sign:        sign,
+		Size:        header.Size,
+		Hash:        header.Hash,
 		UpdatedAt:   time.Now(),
 	}
 	return static, err
 }
 
+// Download obtain file content by reality file path
+func (f *FileService) Download(ctx context.Context, path string) (storage.RSCloser, error) {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Handler.Download(ctx, path)
+}
+
+// Content obtain file content response by reality file path, object store response redirect url
+func (f *FileService) Content(ctx context.Context, path string) (*storage.ContentResponse, error) {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Handler.Content(ctx, path)
+}
+
+// UploadThumbs generate thumbnails of image by sizes, store them next to the original reality file path.
+// the first size is default thumb that can be obtained by storage.FileHeader ThumbFile
+func (f *FileService) UploadThumbs(ctx context.Context, realPath string, src image.Image, format imaging.Format, sizes []*domain.ThumbSize, quality int) ([]*domain.StaticThumb, error) {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return nil, err
+	}
+	thumbs := make([]*domain.StaticThumb, 0, len(sizes))
+	for i, size := range sizes {
+		thumb := img.Thumbnail(src, img.ThumbSize{Width: size.Width, Height: size.Height, Mode: size.Mode})
+		buf := &bytes.Buffer{}
+		if err := img.Encode(buf, thumb, format, quality); err != nil {
+			return thumbs, err
+		}
+		header := &storage.FileHeader{FilePath: realPath}
+		if i > 0 {
+			header.Thumb = size.Name
+		}
+		thumbFile := header.ThumbFile()
+		fileStream := &storage.FileStream{
+			File:        io.NopCloser(buf),
+			Size:        uint64(buf.Len()),
+			VirtualPath: thumbFile,
+			Name:        thumbFile,
+			SavePath:    thumbFile,
+		}
+		if err := fs.Handler.Upload(ctx, fileStream); err != nil {
+			return thumbs, err
+		}
+		thumbs = append(thumbs, &domain.StaticThumb{Name: size.Name, Width: thumb.Bounds().Dx(), Height: thumb.Bounds().Dy(), Path: thumbFile})
+	}
+	return thumbs, nil
+}
+
+// tempFile remove itself when closed
+type tempFile struct {
+	*os.File
+}
+
+func (t *tempFile) Close() error {
+	err := t.File.Close()
+	if removeErr := os.Remove(t.File.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Thumb obtain thumbnail content response of reality file path, empty name is default thumbnail
+func (f *FileService) Thumb(ctx context.Context, realPath string, name string) (*storage.ContentResponse, error) {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Handler.Thumb(ctx, &storage.FileHeader{FilePath: realPath, Thumb: name})
+}
+
+// Store save file content into specifies reality file path
+func (f *FileService) Store(ctx context.Context, path string, size uint64, file io.ReadCloser) error {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return err
+	}
+	fileStream := &storage.FileStream{
+		File:        file,
+		Size:        size,
+		VirtualPath: path,
+		Name:        path,
+		SavePath:    path,
+	}
+	return fs.Handler.Upload(ctx, fileStream)
+}
+
+// List objects under reality directory path
+func (f *FileService) List(ctx context.Context, path string, recursive bool) ([]storage.Object, error) {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Handler.List(ctx, path, recursive)
+}
+
 // Delete by reality file path
 func (f *FileService) Delete(ctx context.Context, filepath []string) error {
 	policy := f.createPolicyBySetting()
@@ -83,8 +228,44 @@ func (f *FileService) Delete(ctx context.Context, filepath []string) error {
 	return nil
 }
 
+// Multipart load current filesystem whose driver supports multipart upload
+func (f *FileService) Multipart() (*filesystem.FileSystem, driver.MultipartHandler, error) {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	handler, ok := fs.Handler.(driver.MultipartHandler)
+	if !ok {
+		return nil, nil, fmt.Errorf("storage %s does not support multipart upload", policy.Mode)
+	}
+	return fs, handler, nil
+}
+
+// Dir load current filesystem whose driver supports directory management
+func (f *FileService) Dir() (*filesystem.FileSystem, driver.DirHandler, error) {
+	policy := f.createPolicyBySetting()
+	fs, err := f.chooseFs(policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	handler, ok := fs.Handler.(driver.DirHandler)
+	if !ok {
+		return nil, nil, fmt.Errorf("storage %s does not support directory management", policy.Mode)
+	}
+	return fs, handler, nil
+}
+
+// FileSystemOf load filesystem by specifies static setting rather than current setting
+func (f *FileService) FileSystemOf(staticSetting *domain.StaticSetting) (*filesystem.FileSystem, error) {
+	return f.chooseFs(policyOf(staticSetting))
+}
+
 func (f *FileService) createPolicyBySetting() *storage.Policy {
-	staticSetting := f.SettingService.FindStaticSetting()
+	return policyOf(f.SettingService.FindStaticSetting())
+}
+
+func policyOf(staticSetting *domain.StaticSetting) *storage.Policy {
 	return &storage.Policy{
 		Mode:            staticSetting.Mode,
 		Endpoint:        staticSetting.Endpoint,
@@ -92,6 +273,7 @@ func (f *FileService) createPolicyBySetting() *storage.Policy {
 		SecretAccessKey: staticSetting.SecretAccessKey,
 		Bucket:          staticSetting.Bucket,
 		BaseDir:         staticSetting.BaseDir,
+		Region:          staticSetting.Region,
 	}
 }
 
@@ -0,0 +1,288 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/img"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/filesystem"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrFileNotFound    = errors.New("file not found")
+	ErrFileExists      = errors.New("file already exists")
+	ErrInvalidFilePath = errors.New("invalid file path")
+)
+
+// FileManagerService browse and manage files of current storage under storage.OsPathPrefix. static records
+// follow their files when moved or deleted, thumbnails, variants and resized images move along with original
+type FileManagerService struct {
+	FileSvr       *FileService
+	StaticSvr     *StaticService
+	UrlRewriteSvr *UrlRewriteService
+	StaticRepo    *repo.StaticRepository
+}
+
+var FileManagerServiceSet = wire.NewSet(wire.Struct(new(FileManagerService), "*"))
+
+// List files and directories directly under dir, derived files of statics are hidden
+func (s *FileManagerService) List(ctx context.Context, dir string) ([]*domain.FileEntry, error) {
+	dir, err := managedPath(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	fs, _, err := s.FileSvr.Dir()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := fs.Handler.List(ctx, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	statics, err := s.StaticRepo.FindList(underDir(dir))
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]*domain.Static, len(statics))
+	derived := make(map[string]bool)
+	for _, static := range statics {
+		byPath[static.RealPath] = static
+		for _, file := range static.Files()[1:] {
+			derived[file] = true
+		}
+	}
+	entries := make([]*domain.FileEntry, 0, len(objects))
+	for _, object := range objects {
+		entryPath := path.Join(dir, object.RelativePath)
+		if derived[entryPath] {
+			continue
+		}
+		entries = append(entries, &domain.FileEntry{
+			Name:      object.Name,
+			Path:      entryPath,
+			IsDir:     object.IsDir,
+			Size:      object.Size,
+			UpdatedAt: object.LastModify,
+			Static:    byPath[entryPath],
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Mkdir create directory and its parents
+func (s *FileManagerService) Mkdir(ctx context.Context, dir string) error {
+	dir, err := managedPath(dir, false)
+	if err != nil {
+		return err
+	}
+	_, handler, err := s.FileSvr.Dir()
+	if err != nil {
+		return err
+	}
+	return handler.Mkdir(ctx, dir)
+}
+
+// Move rename or move file or directory to dest path, dest must not exist. url of moved statics inside articles,
+// drafts, custom pages, metas and article revisions are rewritten, returns count of rewritten documents
+func (s *FileManagerService) Move(ctx context.Context, src string, dest string) (int, error) {
+	src, err := managedPath(src, false)
+	if err != nil {
+		return 0, err
+	}
+	dest, err = managedPath(dest, false)
+	if err != nil {
+		return 0, err
+	}
+	if dest == src || strings.HasPrefix(dest, src+"/") {
+		return 0, fmt.Errorf("%w: can not move %s into itself", ErrInvalidFilePath, src)
+	}
+	fs, handler, err := s.FileSvr.Dir()
+	if err != nil {
+		return 0, err
+	}
+	entry, err := stat(ctx, fs, src)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := stat(ctx, fs, dest); err == nil {
+		return 0, fmt.Errorf("%w: %s", ErrFileExists, dest)
+	}
+
+	urls := make(map[string]string)
+	if !entry.IsDir {
+		static, err := s.StaticSvr.GetByRealPath(src)
+		if err != nil {
+			return 0, handler.Move(ctx, src, dest)
+		}
+		for _, file := range static.Files() {
+			if err := handler.Move(ctx, file, rebase(file, src, dest)); err != nil && file == src {
+				return 0, err
+			}
+		}
+		if err := s.rebase(static, src, dest, urls); err != nil {
+			return 0, err
+		}
+		return s.UrlRewriteSvr.Rewrite(urls), nil
+	}
+
+	objects, err := fs.Handler.List(ctx, src, true)
+	if err != nil {
+		return 0, err
+	}
+	if err := handler.Mkdir(ctx, dest); err != nil {
+		return 0, err
+	}
+	for _, object := range objects {
+		from, to := path.Join(src, object.RelativePath), path.Join(dest, object.RelativePath)
+		if object.IsDir {
+			err = handler.Mkdir(ctx, to)
+		} else {
+			err = handler.Move(ctx, from, to)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	statics, err := s.StaticRepo.FindList(underDir(src))
+	if err != nil {
+		return 0, err
+	}
+	for _, static := range statics {
+		if err := s.rebase(static, src, dest, urls); err != nil {
+			return 0, err
+		}
+	}
+	rewritten := s.UrlRewriteSvr.Rewrite(urls)
+	return rewritten, handler.RemoveDir(ctx, src)
+}
+
+// Delete file or directory, static records of deleted files are removed
+func (s *FileManagerService) Delete(ctx context.Context, p string) error {
+	p, err := managedPath(p, false)
+	if err != nil {
+		return err
+	}
+	fs, handler, err := s.FileSvr.Dir()
+	if err != nil {
+		return err
+	}
+	entry, err := stat(ctx, fs, p)
+	if err != nil {
+		return err
+	}
+	if !entry.IsDir {
+		if static, err := s.StaticSvr.GetByRealPath(p); err == nil {
+			_, err = s.StaticSvr.DeleteBySign(ctx, static.Sign)
+			return err
+		}
+		return s.FileSvr.Delete(ctx, []string{p})
+	}
+	if err := handler.RemoveDir(ctx, p); err != nil {
+		return err
+	}
+	_, err = s.StaticRepo.RemoveMany(underDir(p))
+	return err
+}
+
+// Upload file into dir as static. the existing static is returned when content has been uploaded elsewhere
+func (s *FileManagerService) Upload(ctx context.Context, dir string, header *storage.FileHeader, owner *domain.User) (*credential.FilePathCredential, error) {
+	dir, err := managedPath(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	fs, _, err := s.FileSvr.Dir()
+	if err != nil {
+		return nil, err
+	}
+	header.SavePath = path.Join(dir, path.Base(path.Clean("/"+header.Filename)))
+	if _, err := stat(ctx, fs, header.SavePath); err == nil {
+		header.File.Close()
+		return nil, fmt.Errorf("%w: %s", ErrFileExists, header.SavePath)
+	}
+	if img.IsImage(header.Filename) {
+		return s.StaticSvr.CreateImage(ctx, header, owner)
+	}
+	return s.StaticSvr.CreateStatic(ctx, header, owner)
+}
+
+// rebase reality path of static and its derived files from src to dest, url of static before and after moved is
+// recorded into urls
+func (s *FileManagerService) rebase(static *domain.Static, src string, dest string, urls map[string]string) error {
+	from := StaticUrl(static.RealPath)
+	rebaseStatic(static, src, dest)
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "sign", Value: static.Sign})
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "realPath", Value: static.RealPath}, {Key: "meta", Value: static.Meta}}}}
+	if _, err := s.StaticRepo.Update(filter, update); err != nil {
+		return err
+	}
+	urls[from] = StaticUrl(static.RealPath)
+	return nil
+}
+
+// rebaseStatic move reality path of static and its derived files from src to dest
+func rebaseStatic(static *domain.Static, src string, dest string) {
+	static.RealPath = rebase(static.RealPath, src, dest)
+	if static.Meta != nil {
+		for _, thumb := range static.Meta.Thumbs {
+			thumb.Path = rebase(thumb.Path, src, dest)
+		}
+		for _, variant := range static.Meta.Variants {
+			variant.Path = rebase(variant.Path, src, dest)
+		}
+		for _, resized := range static.Meta.Resized {
+			resized.Path = rebase(resized.Path, src, dest)
+		}
+	}
+}
+
+// managedPath clean path, file manager only manages files under storage.OsPathPrefix
+func managedPath(p string, allowRoot bool) (string, error) {
+	cleaned := path.Clean("/" + p)
+	if cleaned == storage.OsPathPrefix && allowRoot {
+		return cleaned, nil
+	}
+	if !strings.HasPrefix(cleaned, storage.OsPathPrefix+"/") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidFilePath, p)
+	}
+	return cleaned, nil
+}
+
+// stat find file or directory by listing its parent directory
+func stat(ctx context.Context, fs *filesystem.FileSystem, p string) (*storage.Object, error) {
+	objects, err := fs.Handler.List(ctx, path.Dir(p), false)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		if object.Name == path.Base(p) {
+			return &object, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrFileNotFound, p)
+}
+
+func rebase(p string, src string, dest string) string {
+	return dest + strings.TrimPrefix(p, src)
+}
+
+// underDir filter statics whose reality path is under dir
+func underDir(dir string) mongodb.Logical {
+	return mongodb.NewLogicalDefault(bson.E{Key: "realPath", Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(dir) + "/"}}})
+}
@@ -0,0 +1,48 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestMove_RewriteReferencedStatic(t *testing.T) {
+	static := &domain.Static{
+		RealPath: "/fusion/2024-01-01/a.png",
+		Meta: &domain.StaticMeta{
+			Thumbs:   []*domain.StaticThumb{{Name: "small", Path: "/fusion/2024-01-01/a.png.thumb.small.png"}},
+			Variants: []*domain.StaticVariant{{Format: "webp", Path: "/fusion/2024-01-01/a.png.webp"}},
+		},
+	}
+	article := &domain.Article{Content: "![a](/static/fusion/2024-01-01/a.png)\n<img src=\"/static/fusion/2024-01-01/a.png\">\n![b](/static/fusion/2024-01-01/b.png)"}
+
+	from := StaticUrl(static.RealPath)
+	rebaseStatic(static, "/fusion/2024-01-01", "/fusion/images")
+	assert.Equal(t, "/fusion/images/a.png", static.RealPath)
+	assert.Equal(t, "/fusion/images/a.png.thumb.small.png", static.Meta.Thumbs[0].Path)
+	assert.Equal(t, "/fusion/images/a.png.webp", static.Meta.Variants[0].Path)
+
+	replace := newUrlReplacer(map[string]string{from: StaticUrl(static.RealPath)})
+	content, ok := replace(article.Content)
+	assert.True(t, ok)
+	assert.Equal(t, "![a](/static/fusion/images/a.png)\n<img src=\"/static/fusion/images/a.png\">\n![b](/static/fusion/2024-01-01/b.png)", content)
+
+	meta := &domain.Meta{
+		Id:       "meta",
+		SiteInfo: &domain.SiteInfo{SiteLogo: "/static/fusion/2024-01-01/a.png"},
+		Links:    []*domain.LinkItem{{Logo: "/static/fusion/2024-01-01/a.png"}, {Logo: "/static/fusion/other.png"}},
+	}
+	set, ok, err := replaceDocument(meta, replace)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	for _, e := range set {
+		assert.NotEqual(t, "_id", e.Key)
+	}
+	siteInfo := set.Map()["siteInfo"].(bson.D)
+	assert.Equal(t, "/static/fusion/images/a.png", siteInfo.Map()["siteLogo"])
+
+	_, ok, err = replaceDocument(&domain.Meta{Id: "meta", SiteInfo: &domain.SiteInfo{SiteLogo: "/static/fusion/other.png"}}, replace)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,205 @@
+package svr
+
+import (
+	"bytes"
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/img"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/storage"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slog"
+	"golang.org/x/sync/singleflight"
+	"io"
+	"net/url"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// ResizeUrlPrefix public url prefix of on-the-fly resized image
+const ResizeUrlPrefix = StaticUrlPrefix + "/img/"
+
+// ErrResizeNotAllowed resize options neither match preset nor carry valid signature
+var ErrResizeNotAllowed = errors.New("resize options are not allowed")
+
+// resizeTimeout resizing is shared by concurrent requests, so it is detached from request that starts it
+const resizeTimeout = time.Minute
+
+// resizeSlots limit images that decoded at the same time, decoding is memory and cpu bound
+var resizeSlots = make(chan struct{}, runtime.NumCPU())
+
+// ImageResizeService resize image on the fly. only sizes of domain.ResizePreset or options signed by admin are accepted
+// to prevent resize DoS, resized image is stored next to original and recorded in domain.StaticMeta Resized as cache
+type ImageResizeService struct {
+	Cfg        *config.Config
+	FileSvr    *FileService
+	SettingSvr *SettingService
+	StaticRepo *repo.StaticRepository
+
+	group singleflight.Group
+}
+
+var ImageResizeServiceSet = wire.NewSet(wire.Struct(new(ImageResizeService), "Cfg", "FileSvr", "SettingSvr", "StaticRepo"))
+
+// Options resolve resize options of static. preset takes precedence of width, height and fit, format can be
+// specified along with preset. options without preset must match size of any preset or carry valid signature
+func (s *ImageResizeService) Options(static *domain.Static, preset string, opts *img.ResizeOptions, signature string) (*img.ResizeOptions, error) {
+	source, err := imaging.FormatFromFilename(static.Filename())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not an image", img.ErrInvalidResize, static.Filename())
+	}
+	setting := s.SettingSvr.FindStaticSetting()
+	resolved := &img.ResizeOptions{Width: opts.Width, Height: opts.Height, Fit: opts.Fit, Format: opts.Format, Quality: int(setting.ThumbQuality)}
+	var matched *domain.ResizePreset
+	if preset != "" {
+		for _, p := range setting.ResizePresets {
+			if p.Name == preset {
+				matched = p
+				break
+			}
+		}
+		if matched == nil {
+			return nil, fmt.Errorf("%w: unknown preset %s", ErrResizeNotAllowed, preset)
+		}
+		resolved.Width, resolved.Height, resolved.Fit = matched.Width, matched.Height, matched.Fit
+		if resolved.Format == "" {
+			resolved.Format = matched.Format
+		}
+	}
+	if err := resolved.Normalize(source); err != nil {
+		return nil, err
+	}
+	if matched == nil {
+		for _, p := range setting.ResizePresets {
+			presetFit := p.Fit
+			if presetFit == "" {
+				presetFit = img.FitThumbMode
+			}
+			if p.Width == resolved.Width && p.Height == resolved.Height && presetFit == resolved.Fit {
+				matched = p
+				break
+			}
+		}
+	}
+	if matched == nil && !hmac.Equal([]byte(signature), []byte(s.Signature(static.Sign, resolved))) {
+		return nil, ErrResizeNotAllowed
+	}
+	if matched != nil && matched.Quality > 0 {
+		resolved.Quality = matched.Quality
+	}
+	return resolved, nil
+}
+
+// Signature of normalized resize options for static sign, signed url allows sizes beyond presets
+func (s *ImageResizeService) Signature(sign string, opts *img.ResizeOptions) string {
+	mac := hmac.New(sha256.New, []byte(s.Cfg.Token.SignedKey))
+	mac.Write([]byte(sign + "/" + opts.Key()))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// SignedUrl public url of resized static with signature
+func (s *ImageResizeService) SignedUrl(static *domain.Static, opts *img.ResizeOptions) (string, error) {
+	source, err := imaging.FormatFromFilename(static.Filename())
+	if err != nil {
+		return "", fmt.Errorf("%w: %s is not an image", img.ErrInvalidResize, static.Filename())
+	}
+	if err := opts.Normalize(source); err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("w", strconv.Itoa(opts.Width))
+	query.Set("h", strconv.Itoa(opts.Height))
+	query.Set("fit", opts.Fit)
+	query.Set("format", opts.Format)
+	query.Set("s", s.Signature(static.Sign, opts))
+	return ResizeUrlPrefix + static.Sign + "?" + query.Encode(), nil
+}
+
+// Content obtain resized image by resolved options, image is resized and cached when first requested
+func (s *ImageResizeService) Content(ctx context.Context, static *domain.Static, opts *img.ResizeOptions) (*storage.ContentResponse, error) {
+	key := opts.Key()
+	if static.Meta != nil {
+		for _, resized := range static.Meta.Resized {
+			if resized.Key == key {
+				return s.FileSvr.Content(ctx, resized.Path)
+			}
+		}
+	}
+	// concurrent requests of the same resized image share one resizing, the first request cancelled must not fail others
+	result := s.group.DoChan(static.Sign+"/"+key, func() (any, error) {
+		resizeCtx, cancel := context.WithTimeout(context.Background(), resizeTimeout)
+		defer cancel()
+		return s.resize(resizeCtx, static, opts)
+	})
+	select {
+	case r := <-result:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return &storage.ContentResponse{Content: nopCloser{bytes.NewReader(r.Val.([]byte))}, LastModified: time.Now()}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *ImageResizeService) resize(ctx context.Context, static *domain.Static, opts *img.ResizeOptions) ([]byte, error) {
+	select {
+	case resizeSlots <- struct{}{}:
+		defer func() { <-resizeSlots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	content, err := s.FileSvr.Download(ctx, static.RealPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return nil, err
+	}
+	resizedData, err := img.Resize(ctx, data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resized := &domain.StaticResized{
+		Key:      opts.Key(),
+		MimeType: img.ResizeMimeType(opts.Format),
+		Size:     uint64(len(resizedData)),
+		Path:     static.RealPath + "._resize_" + opts.Key(),
+	}
+	// cache failure only costs resizing again
+	if err := s.FileSvr.Store(ctx, resized.Path, resized.Size, io.NopCloser(bytes.NewReader(resizedData))); err != nil {
+		slog.Error("Failed to store resized image", "err", err, "path", resized.Path)
+		return resizedData, nil
+	}
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "sign", Value: static.Sign})
+	update := bson.D{{Key: "$push", Value: bson.D{{Key: "meta.resized", Value: resized}}}}
+	if static.Meta == nil {
+		update = bson.D{{Key: "$set", Value: bson.D{{Key: "meta", Value: &domain.StaticMeta{Resized: []*domain.StaticResized{resized}}}}}}
+	}
+	if _, err := s.StaticRepo.Update(filter, update); err != nil {
+		slog.Error("Failed to record resized image", "err", err, "sign", static.Sign, "path", resized.Path)
+	}
+	return resizedData, nil
+}
+
+// nopCloser in-memory content that can be served as storage.RSCloser
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,281 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/mongodb"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"regexp"
+)
+
+// ErrInvalidQuery query filter, sort or facet is not supported
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	TagsFacet     = "tags"
+	CategoryFacet = "category"
+)
+
+// maxQueryDepth max nesting of query filters
+const maxQueryDepth = 8
+
+// querySchema fields of collection that query supports
+type querySchema struct {
+	// flags hidden, private and top
+	flags    bool
+	sortable map[string]bool
+}
+
+var (
+	articleQuerySchema = &querySchema{
+		flags:    true,
+		sortable: map[string]bool{"id": true, "title": true, "top": true, "viewer": true, "visited": true, "wordCount": true, "createdAt": true, "updatedAt": true},
+	}
+	draftQuerySchema = &querySchema{
+		sortable: map[string]bool{"id": true, "title": true, "wordCount": true, "createdAt": true, "updatedAt": true},
+	}
+)
+
+// queryResult result of query pipeline
+type queryResult[T any] struct {
+	Items []*T `bson:"items"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+	Tags     []*domain.FacetCount `bson:"tags"`
+	Category []*domain.FacetCount `bson:"category"`
+}
+
+func (r *queryResult[T]) total() int64 {
+	if len(r.Total) == 0 {
+		return 0
+	}
+	return r.Total[0].Count
+}
+
+func (r *queryResult[T]) facets(query *credential.QueryCredential) *domain.QueryFacets {
+	if len(query.Facets) == 0 {
+		return nil
+	}
+	return &domain.QueryFacets{Tags: r.Tags, Category: r.Category}
+}
+
+// pipeline match documents by query filter, then sort, page and count facets of matched documents in one $facet stage
+func (q *querySchema) pipeline(query *credential.QueryCredential) (mongo.Pipeline, error) {
+	filter := mongodb.NewLogicalDefaultLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
+	if query.Filter != nil {
+		conditions, err := q.compile(query.Filter, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(conditions) > 0 {
+			filter.Append(bson.E{Key: mongodb.And, Value: conditions})
+		}
+	}
+	sort, err := q.sort(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	items := bson.A{bson.D{{Key: "$sort", Value: sort}}}
+	if query.PageSize != -1 {
+		page, pageSize := query.Page, query.PageSize
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 {
+			pageSize = 5
+		}
+		items = append(items, bson.D{{Key: "$skip", Value: int64((page - 1) * pageSize)}}, bson.D{{Key: "$limit", Value: int64(pageSize)}})
+	}
+	if query.ToListView {
+		items = append(items, bson.D{{Key: "$project", Value: bson.D{{Key: "content", Value: 0}}}})
+	}
+	facets := bson.D{
+		{Key: "items", Value: items},
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+	}
+	for _, facet := range query.Facets {
+		switch facet {
+		case TagsFacet:
+			facets = append(facets, bson.E{Key: TagsFacet, Value: bson.A{
+				bson.D{{Key: "$unwind", Value: "$tags"}},
+				facetCount("$tags"),
+				facetSort,
+			}})
+		case CategoryFacet:
+			facets = append(facets, bson.E{Key: CategoryFacet, Value: bson.A{facetCount("$category"), facetSort}})
+		default:
+			return nil, fmt.Errorf("%w: unknown facet %s", ErrInvalidQuery, facet)
+		}
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter.ToBson()}},
+		{{Key: "$facet", Value: facets}},
+	}, nil
+}
+
+// compile conditions of filter and its nested filters, each condition is a separate document so that conditions
+// with the same key are all applied
+func (q *querySchema) compile(filter *credential.QueryFilter, depth int) (bson.A, error) {
+	if depth > maxQueryDepth {
+		return nil, fmt.Errorf("%w: filter nested deeper than %d", ErrInvalidQuery, maxQueryDepth)
+	}
+	if !q.flags && (filter.Hidden != nil || filter.Private != nil || filter.Top != nil) {
+		return nil, fmt.Errorf("%w: hidden, private and top are not supported", ErrInvalidQuery)
+	}
+	conditions := bson.A{}
+	condition := func(key string, value interface{}) {
+		conditions = append(conditions, bson.D{{Key: key, Value: value}})
+	}
+	if filter.Title != "" {
+		condition("title", bson.D{{"$regex", regexp.QuoteMeta(filter.Title)}, {"$options", "i"}})
+	}
+	if len(filter.Category) > 0 {
+		condition("category", bson.D{{"$in", filter.Category}})
+	}
+	if len(filter.Tags) > 0 {
+		condition("tags", bson.D{{"$in", filter.Tags}})
+	}
+	if len(filter.AllTags) > 0 {
+		condition("tags", bson.D{{"$all", filter.AllTags}})
+	}
+	if filter.Author != "" {
+		condition("author", filter.Author)
+	}
+	if filter.Hidden != nil {
+		condition("hidden", *filter.Hidden)
+	}
+	if filter.Private != nil {
+		condition("private", *filter.Private)
+	}
+	if filter.Top != nil {
+		top := bson.D{{"$gt", 0}}
+		if !*filter.Top {
+			top = bson.D{{"$not", top}}
+		}
+		condition("top", top)
+	}
+	if filter.WordCount != nil {
+		if r := queryRange(filter.WordCount.Gte, filter.WordCount.Lte); r != nil {
+			condition("wordCount", r)
+		}
+	}
+	if filter.CreatedAt != nil {
+		if r := queryRange(filter.CreatedAt.Gte, filter.CreatedAt.Lte); r != nil {
+			condition("createdAt", r)
+		}
+	}
+	if filter.UpdatedAt != nil {
+		if r := queryRange(filter.UpdatedAt.Gte, filter.UpdatedAt.Lte); r != nil {
+			condition("updatedAt", r)
+		}
+	}
+
+	for _, and := range filter.And {
+		if and == nil {
+			continue
+		}
+		nested, err := q.compile(and, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, nested...)
+	}
+	if len(filter.Or) > 0 {
+		alternatives := bson.A{}
+		matchAll := false
+		for _, alternative := range filter.Or {
+			if alternative == nil {
+				continue
+			}
+			nested, err := q.compile(alternative, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			// alternative without conditions matches every document
+			matchAll = matchAll || len(nested) == 0
+			alternatives = append(alternatives, queryOperands(mongodb.And, nested))
+		}
+		if !matchAll && len(alternatives) > 0 {
+			conditions = append(conditions, queryOperands(mongodb.Or, alternatives))
+		}
+	}
+	if filter.Not != nil {
+		nested, err := q.compile(filter.Not, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(nested) == 0 {
+			return nil, fmt.Errorf("%w: not of empty filter matches nothing", ErrInvalidQuery)
+		}
+		conditions = append(conditions, queryOperands(mongodb.Nor, bson.A{queryOperands(mongodb.And, nested)}))
+	}
+	return conditions, nil
+}
+
+// queryOperands applies predicate to operands, each operand is a separate document. single operand of $and is the
+// document itself
+func queryOperands(predicate mongodb.Predicate, operands bson.A) bson.D {
+	if predicate == mongodb.And && len(operands) == 1 {
+		return operands[0].(bson.D)
+	}
+	return bson.D{{Key: predicate, Value: operands}}
+}
+
+// sort keys in order, default is createdAt desc. id desc is appended so that pages are stable
+func (q *querySchema) sort(sorts []*credential.QuerySort) (bson.D, error) {
+	sort := bson.D{}
+	seen := make(map[string]bool)
+	for _, s := range sorts {
+		if s == nil {
+			continue
+		}
+		if !q.sortable[s.Field] {
+			return nil, fmt.Errorf("%w: can not sort by %s", ErrInvalidQuery, s.Field)
+		}
+		if seen[s.Field] {
+			continue
+		}
+		seen[s.Field] = true
+		switch s.Order {
+		case domain.AscSort:
+			sort = append(sort, bson.E{Key: s.Field, Value: mongodb.Ascending})
+		case domain.DescSort, "":
+			sort = append(sort, bson.E{Key: s.Field, Value: mongodb.Descending})
+		default:
+			return nil, fmt.Errorf("%w: unknown sort order %s", ErrInvalidQuery, s.Order)
+		}
+	}
+	if len(sort) == 0 {
+		sort = append(sort, bson.E{Key: "createdAt", Value: mongodb.Descending})
+	}
+	if !seen["id"] {
+		sort = append(sort, bson.E{Key: "id", Value: mongodb.Descending})
+	}
+	return sort, nil
+}
+
+// queryRange closed range of bound, nil if both are unlimited
+func queryRange[T any](gte *T, lte *T) bson.D {
+	r := bson.D{}
+	if gte != nil {
+		r = append(r, bson.E{Key: "$gte", Value: *gte})
+	}
+	if lte != nil {
+		r = append(r, bson.E{Key: "$lte", Value: *lte})
+	}
+	if len(r) == 0 {
+		return nil
+	}
+	return r
+}
+
+var facetSort = bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}}
+
+func facetCount(field string) bson.D {
+	return bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: field}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}}
+}
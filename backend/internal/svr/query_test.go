@@ -0,0 +1,115 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/credential"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+func TestQueryCompile(t *testing.T) {
+	yes := true
+	gte, lte := int64(100), int64(500)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tag := func(name string) bson.D { return bson.D{{Key: "tags", Value: bson.D{{"$in", []string{name}}}}} }
+
+	cases := []struct {
+		name   string
+		schema *querySchema
+		filter *credential.QueryFilter
+		want   bson.A
+		err    bool
+	}{
+		{
+			name:   "same key conditions",
+			schema: articleQuerySchema,
+			filter: &credential.QueryFilter{Tags: []string{"go"}, AllTags: []string{"go", "db"}},
+			want:   bson.A{tag("go"), bson.D{{Key: "tags", Value: bson.D{{"$all", []string{"go", "db"}}}}}},
+		},
+		{
+			name:   "nested and is flattened",
+			schema: articleQuerySchema,
+			filter: &credential.QueryFilter{Author: "a", And: []*credential.QueryFilter{{Tags: []string{"go"}}, nil, {}}},
+			want:   bson.A{bson.D{{Key: "author", Value: "a"}}, tag("go")},
+		},
+		{
+			name:   "or of alternatives",
+			schema: articleQuerySchema,
+			filter: &credential.QueryFilter{Or: []*credential.QueryFilter{
+				{Tags: []string{"go"}},
+				{Tags: []string{"db"}, Hidden: &yes},
+			}},
+			want: bson.A{bson.D{{Key: "$or", Value: bson.A{
+				tag("go"),
+				bson.D{{Key: "$and", Value: bson.A{tag("db"), bson.D{{Key: "hidden", Value: true}}}}},
+			}}}},
+		},
+		{
+			name:   "or with empty alternative matches all",
+			schema: articleQuerySchema,
+			filter: &credential.QueryFilter{Or: []*credential.QueryFilter{{Tags: []string{"go"}}, {}}},
+			want:   bson.A{},
+		},
+		{
+			name:   "not of nested or",
+			schema: articleQuerySchema,
+			filter: &credential.QueryFilter{Not: &credential.QueryFilter{Or: []*credential.QueryFilter{{Tags: []string{"go"}}, {Tags: []string{"db"}}}}},
+			want: bson.A{bson.D{{Key: "$nor", Value: bson.A{
+				bson.D{{Key: "$or", Value: bson.A{tag("go"), tag("db")}}},
+			}}}},
+		},
+		{
+			name:   "ranges",
+			schema: draftQuerySchema,
+			filter: &credential.QueryFilter{
+				WordCount: &credential.QueryIntRange{Gte: &gte, Lte: &lte},
+				CreatedAt: &credential.QueryTimeRange{Gte: &since},
+				UpdatedAt: &credential.QueryTimeRange{},
+			},
+			want: bson.A{
+				bson.D{{Key: "wordCount", Value: bson.D{{"$gte", gte}, {"$lte", lte}}}},
+				bson.D{{Key: "createdAt", Value: bson.D{{"$gte", since}}}},
+			},
+		},
+		{
+			name:   "flags rejected for drafts",
+			schema: draftQuerySchema,
+			filter: &credential.QueryFilter{Top: &yes},
+			err:    true,
+		},
+		{
+			name:   "nested flags rejected for drafts",
+			schema: draftQuerySchema,
+			filter: &credential.QueryFilter{Or: []*credential.QueryFilter{{Tags: []string{"go"}}, {Hidden: &yes}}},
+			err:    true,
+		},
+		{
+			name:   "not of empty filter",
+			schema: articleQuerySchema,
+			filter: &credential.QueryFilter{Not: &credential.QueryFilter{}},
+			err:    true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conditions, err := c.schema.compile(c.filter, 0)
+			if c.err {
+				assert.True(t, errors.Is(err, ErrInvalidQuery), err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, conditions)
+		})
+	}
+}
+
+func TestQueryCompileDepth(t *testing.T) {
+	filter := &credential.QueryFilter{Author: "a"}
+	for i := 0; i <= maxQueryDepth; i++ {
+		filter = &credential.QueryFilter{And: []*credential.QueryFilter{filter}}
+	}
+	_, err := articleQuerySchema.compile(filter, 0)
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
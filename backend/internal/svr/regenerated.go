@@ -0,0 +1,68 @@
+package svr
+
+import (
+	"golang.org/x/exp/slog"
+	"golang.org/x/sync/singleflight"
+	"sync"
+)
+
+// regenerated value generated when first requested and regenerated in background when source changed, concurrent
+// generations are shared
+type regenerated[T any] struct {
+	mu        sync.RWMutex
+	value     T
+	generated bool
+	// revision increased by refresh, outdated generation will not replace newer one
+	revision uint64
+	stored   uint64
+	group    singleflight.Group
+}
+
+// get generated value, it is generated when not generated yet
+func (r *regenerated[T]) get(generate func() (T, error)) (T, error) {
+	r.mu.RLock()
+	value, generated := r.value, r.generated
+	r.mu.RUnlock()
+	if generated {
+		return value, nil
+	}
+	v, err, _ := r.group.Do("", func() (any, error) {
+		return r.generate(generate)
+	})
+	if err != nil {
+		return value, err
+	}
+	return v.(T), nil
+}
+
+// refresh regenerate value in background, outdated value is served until regenerated
+func (r *regenerated[T]) refresh(name string, generate func() (T, error)) {
+	// generation in flight may read source before change
+	r.mu.Lock()
+	r.revision++
+	r.mu.Unlock()
+	r.group.Forget("")
+	go func() {
+		if _, err, _ := r.group.Do("", func() (any, error) {
+			return r.generate(generate)
+		}); err != nil {
+			slog.Error("Failed to refresh "+name, "err", err)
+		}
+	}()
+}
+
+func (r *regenerated[T]) generate(generate func() (T, error)) (T, error) {
+	r.mu.RLock()
+	revision := r.revision
+	r.mu.RUnlock()
+	value, err := generate()
+	if err != nil {
+		return value, err
+	}
+	r.mu.Lock()
+	if !r.generated || revision >= r.stored {
+		r.value, r.generated, r.stored = value, true, revision
+	}
+	r.mu.Unlock()
+	return value, nil
+}
@@ -0,0 +1,32 @@
+package svr
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegenerated(t *testing.T) {
+	var r regenerated[int]
+	var calls atomic.Int32
+	failing := func() (int, error) { return 0, errors.New("source unavailable") }
+	generate := func() (int, error) { return int(calls.Add(1)), nil }
+
+	_, err := r.get(failing)
+	assert.Error(t, err)
+
+	value, err := r.get(generate)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+	value, _ = r.get(generate)
+	assert.Equal(t, 1, value, "generated value is cached")
+
+	r.refresh("test", generate)
+	assert.Eventually(t, func() bool {
+		value, _ := r.get(generate)
+		return value == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+}
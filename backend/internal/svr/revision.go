@@ -0,0 +1,122 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/diff"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/util"
+	"errors"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+	"time"
+)
+
+// ErrRevisionNotFound revision does not exist or belongs to other article
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// revisionOrder newest revision first, id of the same time breaks tie
+var revisionOrder = bson.D{{"createdAt", -1}, {"id", -1}}
+
+// RevisionService record immutable snapshot of article content, title, tags, category and author whenever article is
+// updated, published from draft or restored. revisions are kept after article is deleted
+type RevisionService struct {
+	ArticleRepo  *repo.ArticleRepository
+	RevisionRepo *repo.RevisionRepository
+}
+
+var RevisionServiceSet = wire.NewSet(wire.Struct(new(RevisionService), "*"))
+
+// List revisions of article newest first, content is omitted
+func (r *RevisionService) List(articleId uint64) ([]*domain.ArticleRevision, error) {
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "articleId", Value: articleId})
+	return r.RevisionRepo.FindList(filter, options.Find().SetSort(revisionOrder).SetProjection(bson.D{{"content", 0}}))
+}
+
+// Get revision of article
+func (r *RevisionService) Get(articleId uint64, id uint64) (*domain.ArticleRevision, error) {
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id}).Append(bson.E{Key: "articleId", Value: articleId})
+	revision, err := r.RevisionRepo.FindOne(filter)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, err
+}
+
+// Diff title and content from revision to another revision of article by lines or words
+func (r *RevisionService) Diff(articleId uint64, from uint64, to uint64, mode diff.Mode) (*credential.RevisionDiff, error) {
+	fromRevision, err := r.Get(articleId, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := r.Get(articleId, to)
+	if err != nil {
+		return nil, err
+	}
+	content, err := diff.Diff(fromRevision.Content, toRevision.Content, mode)
+	if err != nil {
+		return nil, err
+	}
+	title, err := diff.Diff(fromRevision.Title, toRevision.Title, diff.WordMode)
+	if err != nil {
+		return nil, err
+	}
+	fromRevision.Content, toRevision.Content = "", ""
+	return &credential.RevisionDiff{From: fromRevision, To: toRevision, Title: title, Content: content}, nil
+}
+
+// Initial record current state of article that has no revision, it must be called before article is overwritten so
+// that article saved before revisions are recorded can be restored
+func (r *RevisionService) Initial(articleId uint64) {
+	count, err := r.RevisionRepo.Count(mongodb.NewLogicalDefault(bson.E{Key: "articleId", Value: articleId}))
+	if err != nil {
+		slog.Error("Failed to count revisions of article", "err", err, "articleId", articleId)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	if _, err := r.Record(articleId, domain.InitialRevisionSource, 0); err != nil {
+		slog.Error("Failed to record initial revision of article", "err", err, "articleId", articleId)
+	}
+}
+
+// Record current state of article as revision. nothing is recorded when it equals to latest revision, such as only
+// visibility of article changed, the latest revision is returned instead
+func (r *RevisionService) Record(articleId uint64, source domain.RevisionSource, restoredFrom uint64) (*domain.ArticleRevision, error) {
+	article, err := r.ArticleRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: articleId}))
+	if err != nil {
+		return nil, err
+	}
+	latest, err := r.RevisionRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "articleId", Value: articleId}), options.FindOne().SetSort(revisionOrder))
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	if latest != nil && latest.Title == article.Title && latest.Content == article.Content && latest.Category == article.Category &&
+		latest.Author == article.Author && slices.Equal(latest.Tags, article.Tags) {
+		return latest, nil
+	}
+	revision := &domain.ArticleRevision{
+		ArticleId:    articleId,
+		Title:        article.Title,
+		Content:      article.Content,
+		Tags:         article.Tags,
+		Category:     article.Category,
+		Author:       article.Author,
+		WordCount:    util.WordCount(article.Content),
+		Source:       source,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now(),
+	}
+	id, err := r.RevisionRepo.Save(revision)
+	if err != nil {
+		return nil, err
+	}
+	revision.Id = id
+	return revision, nil
+}
@@ -0,0 +1,188 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/mongodb"
+	"errors"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slog"
+	"sync"
+	"time"
+)
+
+// ScheduleService publish and unpublish articles and publish drafts at scheduled time. schedules are persisted as
+// publishAt and unpublishAt of articles and drafts, so they survive restarts and overdue ones are applied on startup.
+// each schedule is claimed by conditional update before applied, it is applied once even if several instances run
+type ScheduleService struct {
+	ArticleRepo *repo.ArticleRepository
+	DraftRepo   *repo.DraftRepository
+	DraftSvr    *DraftService
+	SearchSvr   *SearchService
+
+	once sync.Once
+	wake chan struct{}
+}
+
+var ScheduleServiceSet = wire.NewSet(wire.Struct(new(ScheduleService), "ArticleRepo", "DraftRepo", "DraftSvr", "SearchSvr"))
+
+// Wake receive when schedules changed, scheduler should reconsider next due time
+func (s *ScheduleService) Wake() <-chan struct{} {
+	return s.channel()
+}
+
+// Notify scheduler that schedules changed, it never blocks
+func (s *ScheduleService) Notify() {
+	select {
+	case s.channel() <- struct{}{}:
+	default:
+	}
+}
+
+func (s *ScheduleService) channel() chan struct{} {
+	s.once.Do(func() { s.wake = make(chan struct{}, 1) })
+	return s.wake
+}
+
+// Next the earliest scheduled time, zero if nothing is scheduled
+func (s *ScheduleService) Next() (time.Time, error) {
+	var next time.Time
+	for _, field := range []string{"publishAt", "unpublishAt"} {
+		article, err := s.ArticleRepo.FindOne(scheduled(field), options.FindOne().SetSort(bson.D{{field, 1}}).SetProjection(bson.D{{field, 1}}))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		at := article.PublishAt
+		if field == "unpublishAt" {
+			at = article.UnpublishAt
+		}
+		next = earliest(next, at)
+	}
+	draft, err := s.DraftRepo.FindOne(scheduled("publishAt"), options.FindOne().SetSort(bson.D{{"publishAt", 1}}).SetProjection(bson.D{{"publishAt", 1}}))
+	if err == nil {
+		next = earliest(next, draft.PublishAt)
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, err
+	}
+	return next, nil
+}
+
+// Run apply schedules due at now, drafts are published first, then articles are published and unpublished.
+// failed schedule is logged and retried next run
+func (s *ScheduleService) Run(now time.Time) []*domain.ScheduledChange {
+	changes := make([]*domain.ScheduledChange, 0)
+	changes = append(changes, s.publishDrafts(now)...)
+	changes = append(changes, s.flip(now, "publishAt", domain.PublishScheduleAction)...)
+	changes = append(changes, s.flip(now, "unpublishAt", domain.UnpublishScheduleAction)...)
+	return changes
+}
+
+func (s *ScheduleService) publishDrafts(now time.Time) []*domain.ScheduledChange {
+	drafts, err := s.DraftRepo.FindList(due("publishAt", now))
+	if err != nil {
+		slog.Error("Failed to find drafts due to publish", "err", err)
+		return nil
+	}
+	changes := make([]*domain.ScheduledChange, 0, len(drafts))
+	for _, draft := range drafts {
+		claim := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: draft.Id}).Append(bson.E{Key: "publishAt", Value: draft.PublishAt})
+		claimed, err := s.DraftRepo.Update(claim, bson.D{{"$set", bson.D{{"publishAt", nil}, {"publishOption", nil}}}})
+		if err != nil || !claimed {
+			continue
+		}
+		option := &credential.DraftPublishCredential{}
+		if draft.PublishOption != nil {
+			option = &credential.DraftPublishCredential{
+				Hidden:      draft.PublishOption.Hidden,
+				Pathname:    draft.PublishOption.Pathname,
+				Private:     draft.PublishOption.Private,
+				Password:    draft.PublishOption.Password,
+				Copyright:   draft.PublishOption.Copyright,
+				UnpublishAt: draft.PublishOption.UnpublishAt,
+			}
+		}
+		article, err := s.DraftSvr.Publish(int64(draft.Id), option)
+		if err != nil {
+			slog.Error("Failed to publish scheduled draft", "err", err, "id", draft.Id)
+			// give back schedule for retry
+			restore := bson.D{{"$set", bson.D{{"publishAt", draft.PublishAt}, {"publishOption", draft.PublishOption}}}}
+			if _, err := s.DraftRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: draft.Id}), restore); err != nil {
+				slog.Error("Failed to restore schedule of draft", "err", err, "id", draft.Id)
+			}
+			continue
+		}
+		slog.Info("Published scheduled draft", "id", draft.Id, "articleId", article.Id)
+		changes = append(changes, &domain.ScheduledChange{Action: domain.PublishDraftScheduleAction, Article: article, DraftId: draft.Id})
+	}
+	return changes
+}
+
+// flip visibility of articles whose field is due, field is cleared once applied
+func (s *ScheduleService) flip(now time.Time, field string, action domain.ScheduleAction) []*domain.ScheduledChange {
+	articles, err := s.ArticleRepo.FindList(due(field, now), options.Find().SetProjection(bson.D{{"id", 1}, {field, 1}, {"scheduledHidden", 1}}))
+	if err != nil {
+		slog.Error("Failed to find articles due to "+action, "err", err)
+		return nil
+	}
+	changes := make([]*domain.ScheduledChange, 0, len(articles))
+	for _, article := range articles {
+		at, set := scheduledSet(article, field, action)
+		filter := mongodb.NewLogicalDefault(bson.E{Key: "id", Value: article.Id}).Append(bson.E{Key: field, Value: at})
+		claimed, err := s.ArticleRepo.Update(filter, bson.D{{"$set", set}})
+		if err != nil {
+			slog.Error("Failed to "+action+" scheduled article", "err", err, "id", article.Id)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.SearchSvr.Reindex(article.Id)
+		changed, err := s.ArticleRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: article.Id}))
+		if err != nil {
+			slog.Error("Failed to find scheduled article", "err", err, "id", article.Id)
+			continue
+		}
+		slog.Info("Applied article schedule", "action", action, "id", article.Id)
+		changes = append(changes, &domain.ScheduledChange{Action: action, Article: changed})
+	}
+	return changes
+}
+
+// scheduledSet scheduled time of field and elements to set when it is applied. publishing only shows article hidden by
+// schedule, article hidden by author keeps hidden
+func scheduledSet(article *domain.Article, field string, action domain.ScheduleAction) (*time.Time, bson.D) {
+	if action == domain.UnpublishScheduleAction {
+		return article.UnpublishAt, bson.D{{"hidden", true}, {field, nil}, {"scheduledHidden", false}}
+	}
+	set := bson.D{{field, nil}, {"createdAt", article.PublishAt}, {"scheduledHidden", false}}
+	if article.ScheduledHidden {
+		set = append(set, bson.E{Key: "hidden", Value: false})
+	}
+	return article.PublishAt, set
+}
+
+// scheduled documents having time of field, deleted ones are excluded
+func scheduled(field string) mongodb.Logical {
+	filter := mongodb.NewLogicalDefault(bson.E{Key: field, Value: bson.D{{"$type", "date"}}})
+	return filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
+}
+
+// due documents whose time of field is not after now
+func due(field string, now time.Time) mongodb.Logical {
+	filter := mongodb.NewLogicalDefault(bson.E{Key: field, Value: bson.D{{"$lte", now}}})
+	return filter.AppendLogical(mongodb.NewLogicalOrDefaultArray(DeleteFilter))
+}
+
+func earliest(next time.Time, at *time.Time) time.Time {
+	if at != nil && (next.IsZero() || at.Before(next)) {
+		return *at
+	}
+	return next
+}
@@ -0,0 +1,26 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestScheduledSet(t *testing.T) {
+	at := time.Now()
+
+	_, set := scheduledSet(&domain.Article{PublishAt: &at, ScheduledHidden: true}, "publishAt", domain.PublishScheduleAction)
+	assert.Equal(t, false, set.Map()["hidden"], "article hidden by schedule is shown")
+	assert.Equal(t, false, set.Map()["scheduledHidden"])
+
+	scheduled, set := scheduledSet(&domain.Article{PublishAt: &at}, "publishAt", domain.PublishScheduleAction)
+	assert.Equal(t, &at, scheduled)
+	assert.NotContains(t, set.Map(), "hidden", "article hidden by author keeps hidden")
+	assert.Equal(t, &at, set.Map()["createdAt"])
+
+	scheduled, set = scheduledSet(&domain.Article{UnpublishAt: &at, ScheduledHidden: true}, "unpublishAt", domain.UnpublishScheduleAction)
+	assert.Equal(t, &at, scheduled)
+	assert.Equal(t, true, set.Map()["hidden"])
+	assert.Equal(t, false, set.Map()["scheduledHidden"])
+}
@@ -0,0 +1,215 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/markdown"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/search"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slog"
+	"strings"
+	"sync"
+)
+
+// searchable fields of article
+const (
+	TitleSearchField    = "title"
+	ContentSearchField  = "content"
+	TagsSearchField     = "tags"
+	CategorySearchField = "category"
+)
+
+// DefaultSearchBoosts title matters most, then tags and category
+var DefaultSearchBoosts = map[string]float64{
+	TitleSearchField:    3,
+	TagsSearchField:     2,
+	CategorySearchField: 2,
+	ContentSearchField:  1,
+}
+
+const (
+	searchSnippetLength = 120
+	MaxSearchPageSize   = 50
+)
+
+// SearchService full-text search of visible articles by in-memory inverted index. index is built from database on
+// first search and updated when article is created, updated or deleted. content of private article or article of
+// private category is not indexed
+type SearchService struct {
+	ArticleRepo  *repo.ArticleRepository
+	CategoryRepo *repo.CategoryRepository
+
+	mu       sync.RWMutex
+	index    *search.Index
+	articles map[uint64]*domain.Article
+}
+
+var SearchServiceSet = wire.NewSet(wire.Struct(new(SearchService), "ArticleRepo", "CategoryRepo"))
+
+// Search articles ranked by BM25, page starts from 1. boosts override DefaultSearchBoosts of fields
+func (s *SearchService) Search(query string, boosts map[string]float64, page int, pageSize int) (*domain.ArticleSearchResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > MaxSearchPageSize {
+		pageSize = 10
+	}
+	index, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	privateCategories := s.privateCategories()
+	s.mu.RLock()
+	articles := s.articles
+	s.mu.RUnlock()
+
+	result := index.Search(query, boosts, nil, (page-1)*pageSize, pageSize)
+	searchResult := &domain.ArticleSearchResult{Hits: make([]*domain.ArticleSearchHit, 0, len(result.Hits)), Total: int64(result.Total), Page: page, PageSize: pageSize}
+	for _, hit := range result.Hits {
+		article, ok := articles[hit.Id]
+		if !ok {
+			continue
+		}
+		// category may turn private after indexed
+		if privateCategories[article.Category] {
+			delete(hit.Highlights, ContentSearchField)
+		}
+		searchResult.Hits = append(searchResult.Hits, &domain.ArticleSearchHit{Article: article, Score: hit.Score, Highlights: hit.Highlights})
+	}
+	return searchResult, nil
+}
+
+// Reindex article of id from database, it is removed from index if invisible
+func (s *SearchService) Reindex(id uint64) {
+	s.mu.RLock()
+	built := s.index != nil
+	s.mu.RUnlock()
+	if !built {
+		return
+	}
+	article, err := s.ArticleRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id}))
+	if err != nil {
+		slog.Error("Failed to find article for search index", "err", err, "id", id)
+		s.Remove(id)
+		return
+	}
+	s.put(article, s.privateCategories())
+}
+
+// Remove article of id from index
+func (s *SearchService) Remove(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index == nil {
+		return
+	}
+	s.index.Remove(id)
+	s.articles = copyWithout(s.articles, id)
+}
+
+// Invalidate drop index, it is rebuilt on next search. used when articles are changed in bulk
+func (s *SearchService) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index = nil
+	s.articles = nil
+}
+
+func (s *SearchService) build() (*search.Index, error) {
+	s.mu.RLock()
+	index := s.index
+	s.mu.RUnlock()
+	if index != nil {
+		return index, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil {
+		return s.index, nil
+	}
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "deleted", Value: false})
+	filter.Append(bson.E{Key: "hidden", Value: false})
+	articles, err := s.ArticleRepo.FindList(filter)
+	if err != nil {
+		return nil, err
+	}
+	s.index = search.NewIndex(DefaultSearchBoosts, searchSnippetLength)
+	s.articles = make(map[uint64]*domain.Article, len(articles))
+	privateCategories := s.privateCategories()
+	for _, article := range articles {
+		id, fields, summary, ok := searchDocument(article, privateCategories)
+		if ok {
+			s.index.Put(id, fields)
+			s.articles[id] = summary
+		}
+	}
+	slog.Info("Search index built", "articles", s.index.Len())
+	return s.index, nil
+}
+
+func (s *SearchService) put(article *domain.Article, privateCategories map[string]bool) {
+	id, fields, summary, ok := searchDocument(article, privateCategories)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index == nil {
+		return
+	}
+	if !ok {
+		s.index.Remove(article.Id)
+		s.articles = copyWithout(s.articles, article.Id)
+		return
+	}
+	s.index.Put(id, fields)
+	// searches in flight hold the previous map
+	articles := copyWithout(s.articles, id)
+	articles[id] = summary
+	s.articles = articles
+}
+
+func (s *SearchService) privateCategories() map[string]bool {
+	categories, err := s.CategoryRepo.FindList(mongodb.NewLogicalDefault(bson.E{Key: "private", Value: true}))
+	if err != nil {
+		slog.Error("Failed to find private categories for search", "err", err)
+	}
+	private := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		private[category.Name] = true
+	}
+	return private
+}
+
+// searchDocument fields of visible article to index, and article without content and password as search result
+func searchDocument(article *domain.Article, privateCategories map[string]bool) (uint64, map[string]string, *domain.Article, bool) {
+	if article.Hidden || article.Deleted {
+		return 0, nil, nil, false
+	}
+	fields := map[string]string{
+		TitleSearchField:    article.Title,
+		TagsSearchField:     strings.Join(article.Tags, " "),
+		CategorySearchField: article.Category,
+	}
+	if !article.Private && !privateCategories[article.Category] {
+		if rendered, err := markdown.Render(article.Content); err == nil {
+			fields[ContentSearchField] = markdown.PlainText(rendered.Html)
+		} else {
+			fields[ContentSearchField] = article.Content
+		}
+	}
+	summary := *article
+	summary.Content = ""
+	summary.Password = ""
+	return article.Id, fields, &summary, true
+}
+
+func copyWithout(articles map[uint64]*domain.Article, id uint64) map[uint64]*domain.Article {
+	copied := make(map[uint64]*domain.Article, len(articles))
+	for k, v := range articles {
+		if k != id {
+			copied[k] = v
+		}
+	}
+	return copied
+}
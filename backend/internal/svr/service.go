@@ -29,6 +29,19 @@ type Service struct {
 	PipelineService   *PipelineService
 	FileService       *FileService
 	LogService        *LogService
+	BackupService     *BackupService
+	BackupScheduler   *BackupScheduler
+	StorageMigration  *StorageMigrationService
+	WatermarkService  *WatermarkService
+	StaticGCService   *StaticGCService
+	ChunkUpload       *ChunkUploadService
+	ImageResize       *ImageResizeService
+	FileManager       *FileManagerService
+	FeedService       *FeedService
+	SitemapService    *SitemapService
+	SearchService     *SearchService
+	RevisionService   *RevisionService
+	ScheduleService   *ScheduleService
 }
 
 var ServiceSet = wire.NewSet(
@@ -50,5 +63,19 @@ var ServiceSet = wire.NewSet(
 	PipelineServiceSet,
 	FileServiceSet,
 	LogServiceSet,
+	BackupServiceSet,
+	BackupSchedulerSet,
+	UrlRewriteServiceSet,
+	StorageMigrationServiceSet,
+	WatermarkServiceSet,
+	StaticGCServiceSet,
+	ChunkUploadServiceSet,
+	ImageResizeServiceSet,
+	FileManagerServiceSet,
+	FeedServiceSet,
+	SitemapServiceSet,
+	SearchServiceSet,
+	RevisionServiceSet,
+	ScheduleServiceSet,
 	wire.Struct(new(Service), "*"),
 )
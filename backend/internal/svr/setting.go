@@ -5,6 +5,7 @@ import (
 	"cc.allio/fusion/internal/credential"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/img"
 	"cc.allio/fusion/pkg/mongodb"
 	"cc.allio/fusion/pkg/storage"
 	"cc.allio/fusion/pkg/util"
@@ -17,14 +18,18 @@ import (
 )
 
 const (
-	JwtSettingType    = "jwt"
-	MenuSettingType   = "menu"
-	StaticSettingType = "static"
-	IsrSettingType    = "isr"
-	LoginSettingType  = "login"
-	HttpsSettingType  = "https"
-	WalineSettingType = "waline"
-	LayoutSettingType = "layout"
+	JwtSettingType      = "jwt"
+	MenuSettingType     = "menu"
+	StaticSettingType   = "static"
+	IsrSettingType      = "isr"
+	LoginSettingType    = "login"
+	HttpsSettingType    = "https"
+	WalineSettingType   = "waline"
+	LayoutSettingType   = "layout"
+	BackupSettingType   = "backup"
+	StaticGCSettingType = "staticGC"
+	FeedSettingType     = "feed"
+	RobotsSettingType   = "robots"
 )
 
 type SettingService struct {
@@ -37,12 +42,14 @@ var SettingServiceSet = wire.NewSet(wire.Struct(new(SettingService), "*"))
 // ---------------------- static ----------------------
 
 func (s *SettingService) FindStaticSetting() *domain.StaticSetting {
+	// setting not saved yet or failed to find still has defaults, so that upload policy and others are never nil
+	value := map[string]any{}
 	setting, err := s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: StaticSettingType}))
 	if err != nil {
 		slog.Error("Find static setting has error", "err", err)
-		return &domain.StaticSetting{}
+	} else {
+		value = setting.Value
 	}
-	value := setting.Value
 	staticSetting := &domain.StaticSetting{
 		Mode:            util.GetValue[string](value, "mode", storage.LocalMode),
 		Endpoint:        util.GetValue[string](value, "endpoint", ""),
@@ -52,6 +59,37 @@ func (s *SettingService) FindStaticSetting() *domain.StaticSetting {
 		BaseDir:         util.GetValue[string](value, "baseDir", ""),
 		WaterMarkText:   util.GetValue[string](value, "waterMarkText", ""),
 		EnableWaterMark: util.GetValue[bool](value, "enableWaterMark", false),
+		EnableWebp:      util.GetValue[bool](value, "enableWebp", false),
+		WebpQuality:     int64(util.GetValue[float64](value, "webpQuality", img.DefaultQuality)),
+		EnableAvif:      util.GetValue[bool](value, "enableAvif", false),
+		AvifQuality:     int64(util.GetValue[float64](value, "avifQuality", img.DefaultQuality)),
+		KeepMetadata:    util.GetValue[bool](value, "keepMetadata", false),
+		EnableThumb:     util.GetValue[bool](value, "enableThumb", true),
+		ThumbQuality:    int64(util.GetValue[float64](value, "thumbQuality", img.DefaultQuality)),
+	}
+	nested := util.MapToEntity(value, &struct {
+		ThumbSizes    []*domain.ThumbSize      `json:"thumbSizes"`
+		WaterMark     *domain.WaterMarkSetting `json:"waterMark"`
+		UploadPolicy  *domain.UploadPolicy     `json:"uploadPolicy"`
+		ResizePresets []*domain.ResizePreset   `json:"resizePresets"`
+	}{})
+	staticSetting.ThumbSizes = nested.ThumbSizes
+	staticSetting.ResizePresets = nested.ResizePresets
+	staticSetting.WaterMark = nested.WaterMark
+	staticSetting.UploadPolicy = nested.UploadPolicy
+	if staticSetting.UploadPolicy == nil {
+		uploadPolicy := domain.DefaultUploadPolicy
+		staticSetting.UploadPolicy = &uploadPolicy
+	}
+	if staticSetting.WaterMark == nil {
+		waterMark := domain.DefaultWaterMarkSetting
+		staticSetting.WaterMark = &waterMark
+	}
+	if len(staticSetting.ThumbSizes) == 0 {
+		staticSetting.ThumbSizes = domain.DefaultThumbSizes
+	}
+	if staticSetting.ResizePresets == nil {
+		staticSetting.ResizePresets = domain.DefaultResizePresets
 	}
 	return staticSetting
 }
@@ -288,3 +326,212 @@ func (s *SettingService) SaveOrUpdateIsrSetting(isr *domain.IsrSetting) (bool, e
 		return s.SettingRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: IsrSettingType}), bson.D{{"$set", bson.D{{"value", value}}}})
 	}
 }
+
+// ---------------------- backup ----------------------
+
+// FindBackupSetting if backup setting is null, will be saved domain.DefaultBackupSetting
+func (s *SettingService) FindBackupSetting() *domain.BackupSetting {
+	setting, err := util.TryThen[domain.Setting](
+		func() (*domain.Setting, error) {
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: BackupSettingType}))
+		},
+		func() (*domain.Setting, error) {
+			value := util.EntityToMap[*domain.BackupSetting](&domain.DefaultBackupSetting)
+			_, err := s.SettingRepo.Save(&domain.Setting{Type: BackupSettingType, Value: value})
+			if err != nil {
+				slog.Error("Failed to save default backup setting.", "err", err)
+				return nil, err
+			}
+			// re find
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: BackupSettingType}))
+		},
+		func(err error) bool {
+			return errors.Is(err, mongo.ErrNoDocuments)
+		},
+	)
+	if err != nil {
+		slog.Error("Find backup setting has error", "err", err)
+		return &domain.BackupSetting{}
+	}
+	value := setting.Value
+	return &domain.BackupSetting{
+		Enable:     util.GetValue[bool](value, "enable", domain.DefaultBackupSetting.Enable),
+		Cron:       util.GetValue[string](value, "cron", domain.DefaultBackupSetting.Cron),
+		KeepLast:   int64(util.GetValue[float64](value, "keepLast", float64(domain.DefaultBackupSetting.KeepLast))),
+		KeepDaily:  int64(util.GetValue[float64](value, "keepDaily", float64(domain.DefaultBackupSetting.KeepDaily))),
+		KeepWeekly: int64(util.GetValue[float64](value, "keepWeekly", float64(domain.DefaultBackupSetting.KeepWeekly))),
+	}
+}
+
+// SaveOrUpdateBackupSetting replace whole backup setting, not composite with outdated
+// because disable schedule and zero keep are meaningful values
+func (s *SettingService) SaveOrUpdateBackupSetting(backup *domain.BackupSetting) (bool, error) {
+	value := util.EntityToMap[*domain.BackupSetting](backup)
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "type", Value: BackupSettingType})
+	if _, err := s.SettingRepo.FindOne(filter); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return false, err
+		}
+		saved, err := s.SettingRepo.Save(&domain.Setting{Type: BackupSettingType, Value: value})
+		if err != nil {
+			return false, err
+		}
+		return saved > 0, nil
+	}
+	return s.SettingRepo.Update(filter, bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: value}}}})
+}
+
+// ---------------------- static gc ----------------------
+
+// FindStaticGCSetting if static gc setting is null, will be saved domain.DefaultStaticGCSetting
+func (s *SettingService) FindStaticGCSetting() *domain.StaticGCSetting {
+	setting, err := util.TryThen[domain.Setting](
+		func() (*domain.Setting, error) {
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: StaticGCSettingType}))
+		},
+		func() (*domain.Setting, error) {
+			value := util.EntityToMap[*domain.StaticGCSetting](&domain.DefaultStaticGCSetting)
+			_, err := s.SettingRepo.Save(&domain.Setting{Type: StaticGCSettingType, Value: value})
+			if err != nil {
+				slog.Error("Failed to save default static gc setting.", "err", err)
+				return nil, err
+			}
+			// re find
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: StaticGCSettingType}))
+		},
+		func(err error) bool {
+			return errors.Is(err, mongo.ErrNoDocuments)
+		},
+	)
+	if err != nil {
+		slog.Error("Find static gc setting has error", "err", err)
+		return &domain.StaticGCSetting{}
+	}
+	value := setting.Value
+	return &domain.StaticGCSetting{
+		Enable:    util.GetValue[bool](value, "enable", domain.DefaultStaticGCSetting.Enable),
+		Cron:      util.GetValue[string](value, "cron", domain.DefaultStaticGCSetting.Cron),
+		GraceDays: int64(util.GetValue[float64](value, "graceDays", float64(domain.DefaultStaticGCSetting.GraceDays))),
+		Delete:    util.GetValue[bool](value, "delete", domain.DefaultStaticGCSetting.Delete),
+	}
+}
+
+// SaveOrUpdateStaticGCSetting replace whole static gc setting, disable schedule and deletion are meaningful values
+func (s *SettingService) SaveOrUpdateStaticGCSetting(gc *domain.StaticGCSetting) (bool, error) {
+	value := util.EntityToMap[*domain.StaticGCSetting](gc)
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "type", Value: StaticGCSettingType})
+	if _, err := s.SettingRepo.FindOne(filter); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return false, err
+		}
+		saved, err := s.SettingRepo.Save(&domain.Setting{Type: StaticGCSettingType, Value: value})
+		if err != nil {
+			return false, err
+		}
+		return saved > 0, nil
+	}
+	return s.SettingRepo.Update(filter, bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: value}}}})
+}
+
+// ---------------------- feed ----------------------
+
+// FindFeedSetting if feed setting is null, will be saved domain.DefaultFeedSetting
+func (s *SettingService) FindFeedSetting() *domain.FeedSetting {
+	setting, err := util.TryThen[domain.Setting](
+		func() (*domain.Setting, error) {
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: FeedSettingType}))
+		},
+		func() (*domain.Setting, error) {
+			value := util.EntityToMap[*domain.FeedSetting](&domain.DefaultFeedSetting)
+			_, err := s.SettingRepo.Save(&domain.Setting{Type: FeedSettingType, Value: value})
+			if err != nil {
+				slog.Error("Failed to save default feed setting.", "err", err)
+				return nil, err
+			}
+			// re find
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: FeedSettingType}))
+		},
+		func(err error) bool {
+			return errors.Is(err, mongo.ErrNoDocuments)
+		},
+	)
+	if err != nil {
+		slog.Error("Find feed setting has error", "err", err)
+		feed := domain.DefaultFeedSetting
+		return &feed
+	}
+	value := setting.Value
+	return &domain.FeedSetting{
+		Full:          util.GetValue[bool](value, "full", domain.DefaultFeedSetting.Full),
+		Limit:         int64(util.GetValue[float64](value, "limit", float64(domain.DefaultFeedSetting.Limit))),
+		SummaryLength: int64(util.GetValue[float64](value, "summaryLength", float64(domain.DefaultFeedSetting.SummaryLength))),
+	}
+}
+
+// SaveOrUpdateFeedSetting replace whole feed setting, summary only is a meaningful value
+func (s *SettingService) SaveOrUpdateFeedSetting(feed *domain.FeedSetting) (bool, error) {
+	value := util.EntityToMap[*domain.FeedSetting](feed)
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "type", Value: FeedSettingType})
+	if _, err := s.SettingRepo.FindOne(filter); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return false, err
+		}
+		saved, err := s.SettingRepo.Save(&domain.Setting{Type: FeedSettingType, Value: value})
+		if err != nil {
+			return false, err
+		}
+		return saved > 0, nil
+	}
+	return s.SettingRepo.Update(filter, bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: value}}}})
+}
+
+// ---------------------- robots ----------------------
+
+// FindRobotsSetting if robots setting is null, will be saved domain.DefaultRobotsSetting
+func (s *SettingService) FindRobotsSetting() *domain.RobotsSetting {
+	setting, err := util.TryThen[domain.Setting](
+		func() (*domain.Setting, error) {
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: RobotsSettingType}))
+		},
+		func() (*domain.Setting, error) {
+			value := util.EntityToMap[*domain.RobotsSetting](&domain.DefaultRobotsSetting)
+			_, err := s.SettingRepo.Save(&domain.Setting{Type: RobotsSettingType, Value: value})
+			if err != nil {
+				slog.Error("Failed to save default robots setting.", "err", err)
+				return nil, err
+			}
+			// re find
+			return s.SettingRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "type", Value: RobotsSettingType}))
+		},
+		func(err error) bool {
+			return errors.Is(err, mongo.ErrNoDocuments)
+		},
+	)
+	if err != nil {
+		slog.Error("Find robots setting has error", "err", err)
+		robots := domain.DefaultRobotsSetting
+		return &robots
+	}
+	value := setting.Value
+	return &domain.RobotsSetting{
+		Content: util.GetValue[string](value, "content", domain.DefaultRobotsSetting.Content),
+		Sitemap: util.GetValue[bool](value, "sitemap", domain.DefaultRobotsSetting.Sitemap),
+	}
+}
+
+// SaveOrUpdateRobotsSetting replace whole robots setting, empty content is a meaningful value
+func (s *SettingService) SaveOrUpdateRobotsSetting(robots *domain.RobotsSetting) (bool, error) {
+	value := util.EntityToMap[*domain.RobotsSetting](robots)
+	filter := mongodb.NewLogicalDefault(bson.E{Key: "type", Value: RobotsSettingType})
+	if _, err := s.SettingRepo.FindOne(filter); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return false, err
+		}
+		saved, err := s.SettingRepo.Save(&domain.Setting{Type: RobotsSettingType, Value: value})
+		if err != nil {
+			return false, err
+		}
+		return saved > 0, nil
+	}
+	return s.SettingRepo.Update(filter, bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: value}}}})
+}
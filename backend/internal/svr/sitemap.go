@@ -0,0 +1,124 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/sitemap"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sitemapPageSize articles of each page of website
+const sitemapPageSize = 5
+
+// SitemapService generate sitemap of posts, tags, categories, pages and custom pages, hidden and deleted articles and
+// private categories along with their articles are excluded. sitemap is split into parts referenced by sitemap index when urls exceed sitemap.MaxURLs
+type SitemapService struct {
+	ArticleSvr    *ArticleService
+	CategorySvr   *CategoryService
+	CustomPageSvr *CustomPageService
+	MetaSvr       *MetaService
+	SettingSvr    *SettingService
+
+	sitemap regenerated[*sitemap.Sitemap]
+}
+
+var SitemapServiceSet = wire.NewSet(wire.Struct(new(SitemapService), "ArticleSvr", "CategorySvr", "CustomPageSvr", "MetaSvr", "SettingSvr"))
+
+// Sitemap obtain generated sitemap, it is generated when first requested
+func (s *SitemapService) Sitemap() (*sitemap.Sitemap, error) {
+	return s.sitemap.get(s.generate)
+}
+
+// Refresh regenerate sitemap in background, outdated sitemap is served until regenerated
+func (s *SitemapService) Refresh() {
+	s.sitemap.refresh("sitemap", s.generate)
+}
+
+// Robots content of robots.txt, sitemap url is appended if configured
+func (s *SitemapService) Robots() string {
+	setting := s.SettingSvr.FindRobotsSetting()
+	content := setting.Content
+	if !setting.Sitemap {
+		return content
+	}
+	if siteInfo := s.MetaSvr.GetSiteInfo(); siteInfo != nil && lo.IsNotEmpty(siteInfo.BaseUrl) {
+		if lo.IsNotEmpty(content) && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += "Sitemap: " + strings.TrimSuffix(siteInfo.BaseUrl, "/") + "/sitemap.xml\n"
+	}
+	return content
+}
+
+func (s *SitemapService) generate() (*sitemap.Sitemap, error) {
+	baseUrl := ""
+	if siteInfo := s.MetaSvr.GetSiteInfo(); siteInfo != nil {
+		baseUrl = strings.TrimSuffix(siteInfo.BaseUrl, "/")
+	}
+	allCategories := s.CategorySvr.GetAllCategories()
+	privateCategories := privateCategoryNames(allCategories)
+	articles := lo.Filter(s.ArticleSvr.GetAll(ListArticleView, false, false), func(article *domain.Article, index int) bool {
+		return !article.Hidden && !article.Deleted && !privateCategories[article.Category]
+	})
+
+	var latest time.Time
+	posts := make([]sitemap.URL, 0, len(articles))
+	tags := make(map[string]time.Time)
+	categories := make(map[string]time.Time)
+	for _, category := range allCategories {
+		if !category.Private {
+			categories[category.Name] = time.Time{}
+		}
+	}
+	for _, article := range articles {
+		pathname := strconv.FormatUint(article.Id, 10)
+		if lo.IsNotEmpty(article.Pathname) {
+			pathname = url.PathEscape(article.Pathname)
+		}
+		posts = append(posts, sitemap.URL{Loc: baseUrl + "/post/" + pathname, LastMod: article.UpdatedAt})
+		if article.UpdatedAt.After(latest) {
+			latest = article.UpdatedAt
+		}
+		for _, tag := range article.Tags {
+			if article.UpdatedAt.After(tags[tag]) {
+				tags[tag] = article.UpdatedAt
+			}
+		}
+		if lastMod, ok := categories[article.Category]; ok && article.UpdatedAt.After(lastMod) {
+			categories[article.Category] = article.UpdatedAt
+		}
+	}
+
+	urls := make([]sitemap.URL, 0, len(posts)+len(tags)+len(categories)+16)
+	urls = append(urls, sitemap.URL{Loc: baseUrl + "/", LastMod: latest})
+	for _, p := range []string{"/category", "/tag", "/timeline", "/about", "/link"} {
+		urls = append(urls, sitemap.URL{Loc: baseUrl + p})
+	}
+	urls = append(urls, posts...)
+	urls = append(urls, sortedURLs(baseUrl+"/tag/", tags)...)
+	urls = append(urls, sortedURLs(baseUrl+"/category/", categories)...)
+	for i := 1; i <= (len(articles)+sitemapPageSize-1)/sitemapPageSize; i++ {
+		urls = append(urls, sitemap.URL{Loc: baseUrl + "/page/" + strconv.Itoa(i)})
+	}
+	for _, customPage := range s.CustomPageSvr.GetAll() {
+		urls = append(urls, sitemap.URL{Loc: baseUrl + "/c" + customPage.Path, LastMod: customPage.UpdatedAt})
+	}
+
+	return sitemap.Generate(urls, sitemap.MaxURLs, sitemap.PartLoc(baseUrl))
+}
+
+// sortedURLs urls of names under prefix sorted by name, so that sitemap is stable
+func sortedURLs(prefix string, lastMods map[string]time.Time) []sitemap.URL {
+	names := lo.Keys(lastMods)
+	sort.Strings(names)
+	urls := make([]sitemap.URL, 0, len(names))
+	for _, name := range names {
+		urls = append(urls, sitemap.URL{Loc: prefix + url.PathEscape(name), LastMod: lastMods[name]})
+	}
+	return urls
+}
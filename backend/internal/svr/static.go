@@ -1,39 +1,297 @@
 package svr
 
 import (
+	"bytes"
 	"cc.allio/fusion/config"
 	"cc.allio/fusion/internal/credential"
 	"cc.allio/fusion/internal/domain"
 	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/img"
 	"cc.allio/fusion/pkg/mongodb"
 	"cc.allio/fusion/pkg/storage"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/disintegration/imaging"
 	"github.com/google/wire"
 	"github.com/samber/lo"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/exp/slog"
+	"image"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
 )
 
+// StaticUrlPrefix public url prefix of static files that served by backend
+const StaticUrlPrefix = "/static"
+
 type StaticService struct {
-	Cfg         *config.Config
-	FileService *FileService
-	StaticRepo  *repo.StaticRepository
+	Cfg            *config.Config
+	FileService    *FileService
+	SettingService *SettingService
+	StaticRepo     *repo.StaticRepository
 }
 
 var StaticServiceSet = wire.NewSet(wire.Struct(new(StaticService), "*"))
 
-func (s *StaticService) CreateStatic(ctx context.Context, header *storage.FileHeader) (*credential.FilePathCredential, error) {
+// CreateStatic upload file of owner after upload policy checked, the existing static is returned when content
+// has been uploaded
+func (s *StaticService) CreateStatic(ctx context.Context, header *storage.FileHeader, owner *domain.User) (*credential.FilePathCredential, error) {
+	policy := s.SettingService.FindStaticSetting().UploadPolicy
+	if policy.MaxSize > 0 && header.Size > uint64(policy.MaxSize) {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrUploadTooLarge, header.Size, policy.MaxSize)
+	}
+	if err := s.FileService.Hash(header); err != nil {
+		return nil, err
+	}
+	defer header.File.Close()
+	mimeType, err := checkContent(policy, header.Filename, header.Size, header.File.(io.ReadSeeker))
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := s.GetByHash(header.Hash); err == nil {
+		return &credential.FilePathCredential{Src: StaticUrl(existing.RealPath), IsNew: false}, nil
+	}
+	if err := s.checkQuota(policy, owner, header.Size); err != nil {
+		return nil, err
+	}
 	static, err := s.FileService.Upload(ctx, header)
 	if err != nil {
 		return nil, err
 	}
+	static.MimeType = mimeType
+	if owner != nil {
+		static.Owner = owner.Id
+	}
+	return s.save(ctx, static)
+}
+
+// ImageTransform transform image content that passed upload policy, like drawing watermark
+type ImageTransform func(ctx context.Context, src io.Reader, format imaging.Format) (io.Reader, error)
+
+// CreateImage upload image of owner after upload policy checked, transforms applied, exif orientation applied and
+// metadata stripped, then generate thumbnails and convert to webp or avif by static setting.
+// image processing failure will not fail upload
+func (s *StaticService) CreateImage(ctx context.Context, header *storage.FileHeader, owner *domain.User, transforms ...ImageTransform) (*credential.FilePathCredential, error) {
+	setting := s.SettingService.FindStaticSetting()
+	policy := setting.UploadPolicy
+	reader := io.Reader(header.File)
+	if policy.MaxSize > 0 {
+		// read one more byte to find out exceeded file
+		reader = io.LimitReader(header.File, policy.MaxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	header.File.Close()
+	if err != nil {
+		return nil, err
+	}
+	mimeType, err := checkContent(policy, header.Filename, uint64(len(data)), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	format, formatErr := imaging.FormatFromFilename(header.Filename)
+	for _, transform := range transforms {
+		if formatErr != nil {
+			break
+		}
+		transformed, err := transform(ctx, bytes.NewReader(data), format)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(transformed); err != nil {
+			return nil, err
+		}
+	}
+	var src image.Image
+	if formatErr == nil && !setting.KeepMetadata && img.Sanitizable(format) {
+		sanitized, decoded, err := img.Sanitize(data, format)
+		if err != nil {
+			slog.Warn("Failed to sanitize image, upload original", "err", err, "filename", header.Filename)
+		} else {
+			data, src = sanitized, decoded
+		}
+	}
+	if formatErr == nil && src == nil {
+		if src, err = img.Decode(data); err != nil {
+			slog.Warn("Failed to decode image", "err", err, "filename", header.Filename)
+		}
+	}
 
-	_, err = s.StaticRepo.Save(static)
+	hash := sha256.Sum256(data)
+	header.Hash = hex.EncodeToString(hash[:])
+	if existing, err := s.GetByHash(header.Hash); err == nil {
+		return &credential.FilePathCredential{Src: StaticUrl(existing.RealPath), IsNew: false}, nil
+	}
+	if err := s.checkQuota(policy, owner, uint64(len(data))); err != nil {
+		return nil, err
+	}
+	header.File = io.NopCloser(bytes.NewReader(data))
+	header.Size = uint64(len(data))
+	static, err := s.FileService.Upload(ctx, header)
 	if err != nil {
 		return nil, err
 	}
-	return &credential.FilePathCredential{Src: static.RealPath, IsNew: true}, nil
+	static.MimeType = mimeType
+	if owner != nil {
+		static.Owner = owner.Id
+	}
+	if src == nil {
+		return s.save(ctx, static)
+	}
+
+	meta := &domain.StaticMeta{
+		Width:         src.Bounds().Dx(),
+		Height:        src.Bounds().Dy(),
+		Format:        strings.ToLower(format.String()),
+		DominantColor: img.HexColor(img.DominantColor(src)),
+	}
+	if setting.EnableThumb {
+		thumbs, err := s.FileService.UploadThumbs(ctx, static.RealPath, src, format, setting.ThumbSizes, int(setting.ThumbQuality))
+		if err != nil {
+			slog.Error("Failed to generate thumbnails", "err", err, "realPath", static.RealPath)
+		}
+		meta.Thumbs = thumbs
+	}
+	if format == imaging.JPEG || format == imaging.PNG {
+		meta.Variants = s.convert(ctx, static.RealPath, path.Ext(header.Filename), data, setting)
+	}
+	static.Meta = meta
+	return s.save(ctx, static)
+}
+
+// save uploaded static, when concurrent upload of the same content has been saved, files of this one
+// are removed and the existing static is returned
+func (s *StaticService) save(ctx context.Context, static *domain.Static) (*credential.FilePathCredential, error) {
+	_, err := s.StaticRepo.Save(static)
+	if err == nil {
+		return &credential.FilePathCredential{Src: StaticUrl(static.RealPath), IsNew: true}, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+	existing, findErr := s.GetByHash(static.Hash)
+	if findErr != nil {
+		return nil, err
+	}
+	files := lo.Without(static.Files(), existing.Files()...)
+	if len(files) > 0 {
+		if err := s.FileService.Delete(ctx, files); err != nil {
+			slog.Warn("Failed to remove duplicated static files", "err", err, "files", files)
+		}
+	}
+	return &credential.FilePathCredential{Src: StaticUrl(existing.RealPath), IsNew: false}, nil
+}
+
+// convert image data to enabled formats, variant stored next to reality file path with format extension
+func (s *StaticService) convert(ctx context.Context, realPath string, ext string, data []byte, setting *domain.StaticSetting) []*domain.StaticVariant {
+	formats := make(map[img.ConvertFormat]int64)
+	if setting.EnableAvif {
+		formats[img.AvifFormat] = setting.AvifQuality
+	}
+	if setting.EnableWebp {
+		formats[img.WebpFormat] = setting.WebpQuality
+	}
+	variants := make([]*domain.StaticVariant, 0, len(formats))
+	// avif is smaller than webp, prefer it when both accepted
+	for _, format := range []img.ConvertFormat{img.AvifFormat, img.WebpFormat} {
+		quality, ok := formats[format]
+		if !ok {
+			continue
+		}
+		converted, err := img.Convert(ctx, data, ext, format, int(quality))
+		if err != nil {
+			slog.Warn("Failed to convert image, original will be served", "err", err, "format", format, "realPath", realPath)
+			continue
+		}
+		variantPath := realPath + "." + format
+		if err := s.FileService.Store(ctx, variantPath, uint64(len(converted)), io.NopCloser(bytes.NewReader(converted))); err != nil {
+			slog.Error("Failed to store converted image", "err", err, "format", format, "realPath", realPath)
+			continue
+		}
+		variants = append(variants, &domain.StaticVariant{Format: format, MimeType: img.MimeType(format), Size: uint64(len(converted)), Path: variantPath})
+	}
+	return variants
+}
+
+// Variant choose converted variant of static that accepted by request Accept header, nil means serve original
+func (s *StaticService) Variant(static *domain.Static, accept string) *domain.StaticVariant {
+	if static.Meta == nil {
+		return nil
+	}
+	for _, variant := range static.Meta.Variants {
+		if accepts(accept, variant.MimeType) {
+			return variant
+		}
+	}
+	return nil
+}
+
+// Thumb obtain thumbnail content response of static by thumb name, empty name is default thumbnail
+func (s *StaticService) Thumb(ctx context.Context, static *domain.Static, name string) (*storage.ContentResponse, error) {
+	if static.Meta == nil || len(static.Meta.Thumbs) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	if name == static.Meta.Thumbs[0].Name {
+		name = ""
+	}
+	if name != "" && !lo.ContainsBy(static.Meta.Thumbs, func(thumb *domain.StaticThumb) bool { return thumb.Name == name }) {
+		return nil, fs.ErrNotExist
+	}
+	return s.FileService.Thumb(ctx, static.RealPath, name)
+}
+
+// accepts whether Accept header explicitly contains mime type with non-zero quality, wildcard is not counted
+// because browsers send '*/*' even if they can not decode the format
+func accepts(accept string, mimeType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), mimeType) {
+			continue
+		}
+		for _, param := range params[1:] {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// StaticUrl public url of static reality file path
+func StaticUrl(realPath string) string {
+	return StaticUrlPrefix + path.Join("/", realPath)
+}
+
+func (s *StaticService) GetByRealPath(realPath string) (*domain.Static, error) {
+	return s.StaticRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "realPath", Value: realPath}))
+}
+
+// GetByHash find static by content hash
+func (s *StaticService) GetByHash(hash string) (*domain.Static, error) {
+	return s.StaticRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "hash", Value: hash}))
+}
+
+func (s *StaticService) GetBySign(sign string) (*domain.Static, error) {
+	return s.StaticRepo.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "sign", Value: sign}))
+}
+
+// Content obtain static file content response through storage driver
+func (s *StaticService) Content(ctx context.Context, static *domain.Static) (*storage.ContentResponse, error) {
+	return s.FileService.Content(ctx, static.RealPath)
+}
+
+// VariantContent obtain content response of converted variant
+func (s *StaticService) VariantContent(ctx context.Context, variant *domain.StaticVariant) (*storage.ContentResponse, error) {
+	return s.FileService.Content(ctx, variant.Path)
 }
 
 func (s *StaticService) GetAll(storageType domain.StaticType) []*domain.Static {
@@ -82,7 +340,7 @@ func (s *StaticService) DeleteBySign(ctx context.Context, sign string) (bool, er
 		return false, err
 	}
 	// remove file in filesystem
-	err = s.FileService.Delete(ctx, []string{static.RealPath})
+	err = s.FileService.Delete(ctx, static.Files())
 	if err != nil {
 		return false, err
 	}
@@ -95,7 +353,7 @@ func (s *StaticService) DeleteAll(ctx context.Context, staticType domain.StaticT
 	statics := s.GetAll(staticType)
 
 	// remove file in filesystem
-	filepath := lo.Map[*domain.Static, string](statics, func(item *domain.Static, index int) string { return item.RealPath })
+	filepath := lo.FlatMap[*domain.Static, string](statics, func(item *domain.Static, index int) []string { return item.Files() })
 	err := s.FileService.Delete(ctx, filepath)
 	if err != nil {
 		return false, err
@@ -0,0 +1,306 @@
+package svr
+
+import (
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/storage"
+	"context"
+	"errors"
+	"github.com/goccy/go-json"
+	"github.com/google/wire"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slog"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// pathRegexp candidate path inside content, full url is matched from its first slash
+	pathRegexp = regexp.MustCompile(`/[^\s"'()<>\[\]{}\\|^` + "`" + `]+`)
+	// signRegexp static sign and content hash are hex sha256
+	signRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+)
+
+// StaticGCService find statics that nothing references, objects in storage that no static owns and statics
+// whose file is lost. orphans can be deleted after grace period, manually or by schedule of domain.StaticGCSetting
+type StaticGCService struct {
+	Cfg            *config.Config
+	StaticRepo     *repo.StaticRepository
+	ArticleRepo    *repo.ArticleRepository
+	DraftRepo      *repo.DraftRepository
+	CustomPageRepo *repo.CustomPageRepository
+	MetaRepo       *repo.MetaRepository
+	SettingRepo    *repo.SettingsRepository
+	RevisionRepo   *repo.RevisionRepository
+	FileSvr        *FileService
+	SettingSvr     *SettingService
+
+	mu      sync.Mutex
+	running bool
+	report  *domain.StaticGCReport
+	cron    *cron.Cron
+}
+
+var StaticGCServiceSet = wire.NewSet(wire.Struct(new(StaticGCService), "Cfg", "StaticRepo", "ArticleRepo", "DraftRepo", "CustomPageRepo", "MetaRepo", "SettingRepo", "RevisionRepo", "FileSvr", "SettingSvr"))
+
+// Start schedule gc job by current setting, failure only be logged
+func (s *StaticGCService) Start() {
+	if err := s.Reload(); err != nil {
+		slog.Error("Failed to start static gc scheduler", "err", err)
+	}
+}
+
+// Reload stop running schedule, then re-schedule by latest setting. invoke it after static gc setting changed
+func (s *StaticGCService) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cron != nil {
+		s.cron.Stop()
+		s.cron = nil
+	}
+	setting := s.SettingSvr.FindStaticGCSetting()
+	if !setting.Enable {
+		return nil
+	}
+	c := cron.New()
+	if _, err := c.AddFunc(setting.Cron, s.run); err != nil {
+		return err
+	}
+	c.Start()
+	s.cron = c
+	slog.Info("Static gc scheduler started", "cron", setting.Cron)
+	return nil
+}
+
+// Report returns latest gc report, nil if never run
+func (s *StaticGCService) Report() *domain.StaticGCReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report
+}
+
+// Run find orphans, orphans older than grace days are deleted when remove is true
+func (s *StaticGCService) Run(ctx context.Context, remove bool) (*domain.StaticGCReport, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, errors.New("static gc is running")
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	report := &domain.StaticGCReport{
+		Unreferenced: make([]*domain.StaticOrphan, 0),
+		Untracked:    make([]*domain.StaticOrphan, 0),
+		Missing:      make([]*domain.StaticOrphan, 0),
+		StartedAt:    time.Now(),
+	}
+	setting := s.SettingSvr.FindStaticGCSetting()
+	deadline := report.StartedAt.Add(-time.Duration(setting.GraceDays) * 24 * time.Hour)
+
+	statics, err := s.StaticRepo.FindList(mongodb.NewLogical())
+	if err != nil {
+		return nil, err
+	}
+	staticSetting := s.SettingSvr.FindStaticSetting()
+	fs, err := s.FileSvr.FileSystemOf(staticSetting)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := fs.Handler.List(ctx, storage.OsPathPrefix, true)
+	if err != nil {
+		return nil, err
+	}
+	corpus, err := s.corpus()
+	if err != nil {
+		return nil, err
+	}
+	referenced := references(corpus, statics)
+
+	owned := make(map[string]bool)
+	for _, static := range statics {
+		for _, file := range static.Files() {
+			owned[migratedPath(file)] = true
+		}
+	}
+	stored := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		if object.IsDir {
+			continue
+		}
+		objectPath := path.Join(storage.OsPathPrefix, object.RelativePath)
+		stored[objectPath] = true
+		if !owned[objectPath] {
+			report.Untracked = append(report.Untracked, &domain.StaticOrphan{Path: objectPath, Size: object.Size, UpdatedAt: object.LastModify})
+		}
+	}
+	for _, static := range statics {
+		orphan := &domain.StaticOrphan{Sign: static.Sign, Path: static.RealPath, UpdatedAt: static.UpdatedAt}
+		// statics of other storage and legacy statics stored outside of prefix can not be listed
+		listed := static.StaticType == staticSetting.Mode && strings.HasPrefix(migratedPath(static.RealPath), storage.OsPathPrefix+"/")
+		if listed && !stored[migratedPath(static.RealPath)] {
+			report.Missing = append(report.Missing, orphan)
+		}
+		if !referenced[static.Sign] {
+			report.Unreferenced = append(report.Unreferenced, orphan)
+		}
+	}
+
+	if remove {
+		report.Deleted = s.remove(ctx, report, statics, deadline)
+	}
+	report.FinishedAt = time.Now()
+	slog.Info("Static gc finished", "unreferenced", len(report.Unreferenced), "untracked", len(report.Untracked), "missing", len(report.Missing), "deleted", report.Deleted)
+
+	s.mu.Lock()
+	s.report = report
+	s.mu.Unlock()
+	return report, nil
+}
+
+// remove unreferenced statics and untracked objects that not updated after deadline, returns deleted count
+func (s *StaticGCService) remove(ctx context.Context, report *domain.StaticGCReport, statics []*domain.Static, deadline time.Time) int {
+	deleted := 0
+	bySign := make(map[string]*domain.Static, len(statics))
+	for _, static := range statics {
+		bySign[static.Sign] = static
+	}
+	for _, orphan := range report.Unreferenced {
+		if orphan.UpdatedAt.After(deadline) {
+			continue
+		}
+		if err := s.FileSvr.Delete(ctx, bySign[orphan.Sign].Files()); err != nil {
+			slog.Error("Failed to delete unreferenced static files", "err", err, "sign", orphan.Sign)
+			continue
+		}
+		if _, err := s.StaticRepo.Remove(mongodb.NewLogicalDefault(bson.E{Key: "sign", Value: orphan.Sign})); err != nil {
+			slog.Error("Failed to delete unreferenced static", "err", err, "sign", orphan.Sign)
+			continue
+		}
+		orphan.Deleted = true
+		deleted++
+	}
+	for _, orphan := range report.Untracked {
+		if orphan.UpdatedAt.After(deadline) {
+			continue
+		}
+		if err := s.FileSvr.Delete(ctx, []string{orphan.Path}); err != nil {
+			slog.Error("Failed to delete untracked static object", "err", err, "path", orphan.Path)
+			continue
+		}
+		orphan.Deleted = true
+		deleted++
+	}
+	return deleted
+}
+
+// corpus serialized articles, drafts, custom pages, metas, settings and article revisions that may reference statics.
+// revisions keep images referenced by older content, so that restored revision is not broken
+func (s *StaticGCService) corpus() ([]string, error) {
+	var corpus []string
+	collect := func(v any) {
+		if content, err := json.Marshal(v); err == nil {
+			corpus = append(corpus, string(content))
+		}
+	}
+	articles, err := s.ArticleRepo.FindList(mongodb.NewLogical())
+	if err != nil {
+		return nil, err
+	}
+	for _, article := range articles {
+		collect(article)
+	}
+	drafts, err := s.DraftRepo.FindList(mongodb.NewLogical())
+	if err != nil {
+		return nil, err
+	}
+	for _, draft := range drafts {
+		collect(draft)
+	}
+	pages, err := s.CustomPageRepo.FindList(mongodb.NewLogical())
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		collect(page)
+	}
+	metas, err := s.MetaRepo.FindList(mongodb.NewLogical())
+	if err != nil {
+		return nil, err
+	}
+	for _, meta := range metas {
+		collect(meta)
+	}
+	settings, err := s.SettingRepo.FindList(mongodb.NewLogical())
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range settings {
+		collect(setting)
+	}
+	if err := s.RevisionRepo.Each(mongodb.NewLogical(), func(revision *domain.ArticleRevision) error {
+		corpus = append(corpus, revision.Content)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+func (s *StaticGCService) run() {
+	setting := s.SettingSvr.FindStaticGCSetting()
+	if _, err := s.Run(context.Background(), setting.Delete); err != nil {
+		slog.Error("Failed to run scheduled static gc", "err", err)
+	}
+}
+
+// references returns signs of statics referenced by corpus, static is referenced by url that ends with reality
+// path of itself, thumbnail or variant, or by its sign like '/api/public/static/<sign>' and watermark setting
+func references(corpus []string, statics []*domain.Static) map[string]bool {
+	byPath := make(map[string]string)
+	bySign := make(map[string]bool, len(statics))
+	for _, static := range statics {
+		bySign[static.Sign] = true
+		for _, file := range static.Files() {
+			byPath[migratedPath(file)] = static.Sign
+		}
+	}
+	referenced := make(map[string]bool)
+	for _, content := range corpus {
+		for _, candidate := range pathRegexp.FindAllString(content, -1) {
+			candidate, _, _ = strings.Cut(candidate, "?")
+			candidate, _, _ = strings.Cut(candidate, "#")
+			if unescaped, err := url.PathUnescape(candidate); err == nil {
+				candidate = unescaped
+			}
+			// match every suffix that starts with slash, url may carry host or base dir
+			for i := 0; i < len(candidate); i++ {
+				if candidate[i] != '/' {
+					continue
+				}
+				if sign, ok := byPath[candidate[i:]]; ok {
+					referenced[sign] = true
+					break
+				}
+			}
+		}
+		for _, sign := range signRegexp.FindAllString(content, -1) {
+			if bySign[sign] {
+				referenced[sign] = true
+			}
+		}
+	}
+	return referenced
+}
@@ -0,0 +1,51 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	photo := &domain.Static{
+		Sign:     strings.Repeat("a", 64),
+		RealPath: "/fusion/2024-01-01/photo.png",
+		Meta: &domain.StaticMeta{
+			Thumbs:   []*domain.StaticThumb{{Name: "small", Path: "/fusion/2024-01-01/photo.png.thumb.small.png"}},
+			Variants: []*domain.StaticVariant{{Format: "webp", Path: "/fusion/2024-01-01/photo.png.webp"}},
+		},
+	}
+	spaced := &domain.Static{Sign: strings.Repeat("b", 64), RealPath: "/fusion/2024-01-01/my photo 图.png"}
+	legacy := &domain.Static{Sign: strings.Repeat("c", 64), RealPath: "fusion/2023-01-01/legacy.png"}
+	font := &domain.Static{Sign: strings.Repeat("d", 64), RealPath: "/fusion/2024-01-01/font.ttf"}
+	statics := []*domain.Static{photo, spaced, legacy, font}
+
+	cases := []struct {
+		name    string
+		content string
+		want    []*domain.Static
+	}{
+		{"relative url", "![a](/static/fusion/2024-01-01/photo.png)", []*domain.Static{photo}},
+		{"host prefixed url", `<img src="https://cdn.example.com/blog/fusion/2024-01-01/photo.png">`, []*domain.Static{photo}},
+		{"query and fragment", "![a](/static/fusion/2024-01-01/photo.png?w=200#top)", []*domain.Static{photo}},
+		{"thumbnail", "![a](/static/fusion/2024-01-01/photo.png.thumb.small.png)", []*domain.Static{photo}},
+		{"variant", `<source srcset="/static/fusion/2024-01-01/photo.png.webp">`, []*domain.Static{photo}},
+		{"percent encoded", "![a](/static/fusion/2024-01-01/my%20photo%20%E5%9B%BE.png)", []*domain.Static{spaced}},
+		{"legacy relative real path", "![a](/static/fusion/2023-01-01/legacy.png)", []*domain.Static{legacy}},
+		{"sign reference", `{"fontSign":"` + font.Sign + `"}`, []*domain.Static{font}},
+		{"public sign url", "/api/public/static/" + font.Sign, []*domain.Static{font}},
+		{"unknown sign", strings.Repeat("e", 64), nil},
+		{"other file", "![a](/static/fusion/2024-01-01/other.png)", nil},
+		{"prefix of real path", "![a](/static/fusion/2024-01-01/photo)", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			referenced := references([]string{c.content}, statics)
+			assert.Len(t, referenced, len(c.want))
+			for _, static := range c.want {
+				assert.True(t, referenced[static.Sign], static.RealPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package svr
+
+import (
+	"bytes"
+	"cc.allio/fusion/config"
+	"cc.allio/fusion/internal/credential"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/mongodb"
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/filesystem"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slog"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+// StorageMigrationService copy all statics from current storage to another storage,
+// static records and static urls are switched only after static setting switched.
+// run again after failure will skip files that already exist in target storage.
+type StorageMigrationService struct {
+	Cfg           *config.Config
+	StaticRepo    *repo.StaticRepository
+	FileSvr       *FileService
+	SettingSvr    *SettingService
+	UrlRewriteSvr *UrlRewriteService
+
+	mu       sync.Mutex
+	progress *domain.StorageMigrationProgress
+}
+
+var StorageMigrationServiceSet = wire.NewSet(wire.Struct(new(StorageMigrationService), "Cfg", "StaticRepo", "FileSvr", "SettingSvr", "UrlRewriteSvr"))
+
+// Start migration in background, returns error if another migration is running.
+// finished will be invoked with final progress when migration completed
+func (s *StorageMigrationService) Start(migration *credential.StorageMigrationCredential, finished func(progress *domain.StorageMigrationProgress)) error {
+	if migration.To == nil || migration.To.Mode == "" {
+		return errors.New("storage migration target must not be empty")
+	}
+	from := s.SettingSvr.FindStaticSetting()
+	source, err := s.FileSvr.FileSystemOf(from)
+	if err != nil {
+		return err
+	}
+	target, err := s.FileSvr.FileSystemOf(migration.To)
+	if err != nil {
+		return err
+	}
+	statics, err := s.StaticRepo.FindList(mongodb.NewLogical())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.progress != nil && s.progress.Running {
+		return errors.New("storage migration is running")
+	}
+	s.progress = &domain.StorageMigrationProgress{
+		Running:   true,
+		From:      from.Mode,
+		To:        migration.To.Mode,
+		Total:     len(statics),
+		Failures:  make([]*domain.StorageMigrationFailure, 0),
+		StartedAt: time.Now(),
+	}
+	go func() {
+		s.migrate(context.Background(), migration, source, target, statics)
+		if finished != nil {
+			finished(s.Progress())
+		}
+	}()
+	return nil
+}
+
+// Progress returns copy of latest migration progress, nil if never started
+func (s *StorageMigrationService) Progress() *domain.StorageMigrationProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.progress == nil {
+		return nil
+	}
+	progress := *s.progress
+	progress.Failures = append([]*domain.StorageMigrationFailure{}, s.progress.Failures...)
+	return &progress
+}
+
+func (s *StorageMigrationService) migrate(ctx context.Context, migration *credential.StorageMigrationCredential, source *filesystem.FileSystem, target *filesystem.FileSystem, statics []*domain.Static) {
+	migrated := make([]*domain.Static, 0, len(statics))
+	for _, static := range statics {
+		copied, err := s.copy(ctx, source, target, static.RealPath, migratedPath(static.RealPath))
+		if err == nil {
+			// thumbnails and variants, original is still available if them failed
+			for _, file := range static.Files()[1:] {
+				if _, fileErr := s.copy(ctx, source, target, file, migratedPath(file)); fileErr != nil {
+					slog.Warn("Failed to migrate static derived file", "err", fileErr, "sign", static.Sign, "path", file)
+				}
+			}
+			migrated = append(migrated, static)
+		}
+		s.mu.Lock()
+		switch {
+		case err != nil:
+			slog.Error("Failed to migrate static", "err", err, "sign", static.Sign, "realPath", static.RealPath)
+			s.progress.Failures = append(s.progress.Failures, &domain.StorageMigrationFailure{Sign: static.Sign, RealPath: static.RealPath, Error: err.Error()})
+		case copied:
+			s.progress.Copied++
+		default:
+			s.progress.Skipped++
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	failed := len(s.progress.Failures) > 0
+	s.mu.Unlock()
+	// records and urls keep pointing to source storage until static setting switched, so the site is never half migrated
+	if migration.Switch && !failed {
+		if _, err := s.SettingSvr.SaveOrUpdateStaticSetting(migration.To); err != nil {
+			slog.Error("Failed to switch static setting after storage migration", "err", err)
+		} else {
+			rewritten := s.switchRecords(migration, migrated)
+			s.mu.Lock()
+			s.progress.Switched = true
+			s.progress.Rewritten = rewritten
+			s.mu.Unlock()
+		}
+	}
+
+	s.mu.Lock()
+	s.progress.Running = false
+	s.progress.FinishedAt = time.Now()
+	slog.Info("Storage migration finished", "progress", s.progress)
+	s.mu.Unlock()
+}
+
+// switchRecords point static records to target storage and rewrite url of them, returns rewritten document count
+func (s *StorageMigrationService) switchRecords(migration *credential.StorageMigrationCredential, statics []*domain.Static) int {
+	urls := make(map[string]string, len(statics))
+	for _, static := range statics {
+		realPath := migratedPath(static.RealPath)
+		filter := mongodb.NewLogicalDefault(bson.E{Key: "sign", Value: static.Sign})
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "staticType", Value: migration.To.Mode}, {Key: "realPath", Value: realPath}}}}
+		if _, err := s.StaticRepo.Update(filter, update); err != nil {
+			slog.Error("Failed to switch static record", "err", err, "sign", static.Sign)
+		}
+		urls[migration.FromUrlPrefix+static.RealPath] = migration.ToUrlPrefix + realPath
+	}
+	return s.UrlRewriteSvr.Rewrite(urls)
+}
+
+// copy static file from source to target, returns false if target already has the same content
+func (s *StorageMigrationService) copy(ctx context.Context, source *filesystem.FileSystem, target *filesystem.FileSystem, from string, to string) (bool, error) {
+	content, err := source.Handler.Download(ctx, from)
+	if err != nil {
+		return false, err
+	}
+	defer content.Close()
+	size, err := sizeOf(content)
+	if err != nil {
+		return false, err
+	}
+	if exist, err := target.Handler.Download(ctx, to); err == nil {
+		same, err := sameContent(content, size, exist)
+		exist.Close()
+		if err == nil && same {
+			return false, nil
+		}
+	}
+	stream := &storage.FileStream{File: io.NopCloser(content), Size: uint64(size), VirtualPath: to, Name: path.Base(to), SavePath: to}
+	if err := target.Handler.Upload(ctx, stream); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// migratedPath normalize legacy relative real path, all drivers share same layout of key
+func migratedPath(realPath string) string {
+	return path.Join("/", realPath)
+}
+
+func sizeOf(content storage.RSCloser) (int64, error) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	_, err = content.Seek(0, io.SeekStart)
+	return size, err
+}
+
+// sameContent compare by size then sha256, content is rewound for later reading
+func sameContent(content storage.RSCloser, size int64, exist storage.RSCloser) (bool, error) {
+	existSize, err := sizeOf(exist)
+	if err != nil || existSize != size {
+		return false, err
+	}
+	expected, actual := sha256.New(), sha256.New()
+	if _, err := io.Copy(expected, content); err != nil {
+		return false, err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(actual, exist); err != nil {
+		return false, err
+	}
+	return bytes.Equal(expected.Sum(nil), actual.Sum(nil)), nil
+}
@@ -63,6 +63,11 @@ func (tokenSvr *TokenService) CreateToken(userId uint64, username string) (strin
 	return tokenSigned, nil
 }
 
+// FindAvailableToken find persistence token that not disabled
+func (tokenSvr *TokenService) FindAvailableToken(token string) (*domain.Token, error) {
+	return tokenSvr.TokenRepo.FindOne(mongodb.NewLogicalDefaultArray(bson.D{{Key: "token", Value: token}, {Key: "disabled", Value: false}}))
+}
+
 func (tokenSvr *TokenService) DisabledToken(token string) (bool, error) {
 	return tokenSvr.TokenRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "token", Value: token}), bson.D{{"disabled", true}})
 }
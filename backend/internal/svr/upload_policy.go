@@ -0,0 +1,99 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/img"
+	"cc.allio/fusion/pkg/mongodb"
+	"errors"
+	"fmt"
+	"github.com/gabriel-vasile/mimetype"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"strings"
+)
+
+var (
+	ErrUploadTooLarge = errors.New("file size exceeds upload limit")
+	ErrUploadMimeType = errors.New("file type is not allowed")
+	ErrUploadQuota    = errors.New("upload quota exceeded")
+)
+
+// checkContent enforce size limit and allowed mime types of upload policy, svg containing script is always rejected.
+// returns mime type detected by content, content is rewound after checked
+func checkContent(policy *domain.UploadPolicy, filename string, size uint64, content io.ReadSeeker) (string, error) {
+	if policy.MaxSize > 0 && size > uint64(policy.MaxSize) {
+		return "", fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrUploadTooLarge, size, policy.MaxSize)
+	}
+	detected, err := mimetype.DetectReader(content)
+	if err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	mimeType, err := checkMimeType(policy, filename, detected)
+	if err != nil {
+		return "", err
+	}
+	if detected.Is("image/svg+xml") {
+		if err := img.CheckSvg(content); err != nil {
+			return "", err
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+	return mimeType, nil
+}
+
+// checkMimeType detected mime type must match file extension and be allowed by upload policy
+func checkMimeType(policy *domain.UploadPolicy, filename string, detected *mimetype.MIME) (string, error) {
+	mimeType, _, _ := strings.Cut(detected.String(), ";")
+	if img.IsImage(filename) && !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("%w: content %s does not match extension of %s", ErrUploadMimeType, mimeType, filename)
+	}
+	if !mimeAllowed(policy.AllowedMimeTypes, mimeType) {
+		return "", fmt.Errorf("%w: %s", ErrUploadMimeType, mimeType)
+	}
+	return mimeType, nil
+}
+
+func mimeAllowed(allowed []string, mimeType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+		if strings.EqualFold(pattern, mimeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkQuota enforce collaborator quota of upload policy, admin is not limited
+func (s *StaticService) checkQuota(policy *domain.UploadPolicy, owner *domain.User, size uint64) error {
+	if owner == nil || owner.Id == AdminId || owner.Type != domain.CollaborateUserType {
+		return nil
+	}
+	if policy.CollaboratorQuota <= 0 && policy.CollaboratorMaxFiles <= 0 {
+		return nil
+	}
+	statics, err := s.StaticRepo.FindList(mongodb.NewLogicalDefault(bson.E{Key: "owner", Value: owner.Id}))
+	if err != nil {
+		return err
+	}
+	if policy.CollaboratorMaxFiles > 0 && int64(len(statics)) >= policy.CollaboratorMaxFiles {
+		return fmt.Errorf("%w: %d files reaches limit %d", ErrUploadQuota, len(statics), policy.CollaboratorMaxFiles)
+	}
+	used := size
+	for _, static := range statics {
+		used += static.Size
+	}
+	if policy.CollaboratorQuota > 0 && used > uint64(policy.CollaboratorQuota) {
+		return fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrUploadQuota, used, policy.CollaboratorQuota)
+	}
+	return nil
+}
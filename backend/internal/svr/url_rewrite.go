@@ -0,0 +1,175 @@
+package svr
+
+import (
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/internal/repo"
+	"cc.allio/fusion/pkg/mongodb"
+	"github.com/google/wire"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slog"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// UrlRewriteService replace static urls inside every document that may reference statics, used when statics are moved
+// or migrated to another storage
+type UrlRewriteService struct {
+	ArticleRepo    *repo.ArticleRepository
+	DraftRepo      *repo.DraftRepository
+	CustomPageRepo *repo.CustomPageRepository
+	MetaRepo       *repo.MetaRepository
+	RevisionRepo   *repo.RevisionRepository
+	SearchSvr      *SearchService
+}
+
+var UrlRewriteServiceSet = wire.NewSet(wire.Struct(new(UrlRewriteService), "*"))
+
+// Rewrite replace urls inside articles, drafts, custom pages, metas and article revisions, failure is logged since files
+// have been moved. returns count of rewritten documents, revisions are not counted
+func (s *UrlRewriteService) Rewrite(urls map[string]string) int {
+	for from, to := range urls {
+		if from == to {
+			delete(urls, from)
+		}
+	}
+	if len(urls) == 0 {
+		return 0
+	}
+	replace := newUrlReplacer(urls)
+	rewritten := 0
+	setContent := func(field string, value string) bson.D {
+		return bson.D{{Key: "$set", Value: bson.D{{Key: field, Value: value}}}}
+	}
+	if articles, err := s.ArticleRepo.FindList(mongodb.NewLogical()); err != nil {
+		slog.Error("Failed to find articles for rewriting static urls", "err", err)
+	} else {
+		for _, article := range articles {
+			if content, ok := replace(article.Content); ok {
+				if _, err := s.ArticleRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: article.Id}), setContent("content", content)); err != nil {
+					slog.Error("Failed to rewrite article content", "err", err, "id", article.Id)
+					continue
+				}
+				s.SearchSvr.Reindex(article.Id)
+				rewritten++
+			}
+		}
+	}
+	if drafts, err := s.DraftRepo.FindList(mongodb.NewLogical()); err != nil {
+		slog.Error("Failed to find drafts for rewriting static urls", "err", err)
+	} else {
+		for _, draft := range drafts {
+			if content, ok := replace(draft.Content); ok {
+				if _, err := s.DraftRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: draft.Id}), setContent("content", content)); err != nil {
+					slog.Error("Failed to rewrite draft content", "err", err, "id", draft.Id)
+					continue
+				}
+				rewritten++
+			}
+		}
+	}
+	if pages, err := s.CustomPageRepo.FindList(mongodb.NewLogical()); err != nil {
+		slog.Error("Failed to find custom pages for rewriting static urls", "err", err)
+	} else {
+		for _, page := range pages {
+			if html, ok := replace(page.Html); ok {
+				if _, err := s.CustomPageRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: page.Id}), setContent("html", html)); err != nil {
+					slog.Error("Failed to rewrite custom page", "err", err, "id", page.Id)
+					continue
+				}
+				rewritten++
+			}
+		}
+	}
+	if metas, err := s.MetaRepo.FindList(mongodb.NewLogical()); err != nil {
+		slog.Error("Failed to find meta for rewriting static urls", "err", err)
+	} else {
+		for _, meta := range metas {
+			set, ok, err := replaceDocument(meta, replace)
+			if err != nil || !ok {
+				continue
+			}
+			// meta is singleton, its '_id' may be ObjectID created by older version
+			if _, err := s.MetaRepo.Update(mongodb.NewLogical(), bson.D{{Key: "$set", Value: set}}); err != nil {
+				slog.Error("Failed to rewrite meta", "err", err)
+				continue
+			}
+			rewritten++
+		}
+	}
+	// revision is restorable snapshot, it must not reference old url either
+	if err := s.RevisionRepo.Each(mongodb.NewLogical(), func(revision *domain.ArticleRevision) error {
+		if content, ok := replace(revision.Content); ok {
+			if _, err := s.RevisionRepo.Update(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: revision.Id}), setContent("content", content)); err != nil {
+				slog.Error("Failed to rewrite article revision", "err", err, "id", revision.Id)
+			}
+		}
+		return nil
+	}); err != nil {
+		slog.Error("Failed to find article revisions for rewriting static urls", "err", err)
+	}
+	return rewritten
+}
+
+// newUrlReplacer only replace url that starts after markdown or html delimiter,
+// so url already carry new prefix won't be replaced again
+func newUrlReplacer(urls map[string]string) func(content string) (string, bool) {
+	froms := make([]string, 0, len(urls))
+	for from := range urls {
+		froms = append(froms, regexp.QuoteMeta(from))
+	}
+	// longer url first
+	sort.Slice(froms, func(i, j int) bool { return len(froms[i]) > len(froms[j]) })
+	pattern := regexp.MustCompile(`(^|[\s(\[<"'=])(` + strings.Join(froms, "|") + `)`)
+	return func(content string) (string, bool) {
+		replaced := pattern.ReplaceAllStringFunc(content, func(match string) string {
+			submatch := pattern.FindStringSubmatch(match)
+			return submatch[1] + urls[submatch[2]]
+		})
+		return replaced, replaced != content
+	}
+}
+
+// replaceDocument replace every string nested in entity, returns fields except '_id' when any string replaced
+func replaceDocument(entity interface{}, replace func(string) (string, bool)) (bson.D, bool, error) {
+	raw, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, false, err
+	}
+	document := bson.D{}
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, false, err
+	}
+	replaced, ok := replaceStrings(document, replace)
+	if !ok {
+		return nil, false, nil
+	}
+	fields := make(bson.D, 0, len(document))
+	for _, e := range replaced.(bson.D) {
+		if e.Key != "_id" {
+			fields = append(fields, e)
+		}
+	}
+	return fields, true, nil
+}
+
+func replaceStrings(v interface{}, replace func(string) (string, bool)) (interface{}, bool) {
+	changed := false
+	switch value := v.(type) {
+	case string:
+		return replace(value)
+	case bson.D:
+		for i, e := range value {
+			if replaced, ok := replaceStrings(e.Value, replace); ok {
+				value[i].Value, changed = replaced, true
+			}
+		}
+	case bson.A:
+		for i, e := range value {
+			if replaced, ok := replaceStrings(e, replace); ok {
+				value[i], changed = replaced, true
+			}
+		}
+	}
+	return v, changed
+}
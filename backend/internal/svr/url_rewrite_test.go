@@ -0,0 +1,36 @@
+package svr
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewUrlReplacer(t *testing.T) {
+	replace := newUrlReplacer(map[string]string{
+		"/static/a.png":      "https://cdn.example.com/a.png",
+		"/static/a.png.webp": "https://cdn.example.com/a.png.webp",
+	})
+	cases := []struct {
+		name    string
+		content string
+		want    string
+		ok      bool
+	}{
+		{"markdown", "![a](/static/a.png)", "![a](https://cdn.example.com/a.png)", true},
+		{"html attribute", `<img src="/static/a.png">`, `<img src="https://cdn.example.com/a.png">`, true},
+		{"single quote", `<img src='/static/a.png'>`, `<img src='https://cdn.example.com/a.png'>`, true},
+		{"line start", "/static/a.png", "https://cdn.example.com/a.png", true},
+		{"reference link", "[a]: /static/a.png", "[a]: https://cdn.example.com/a.png", true},
+		{"longer url first", "![a](/static/a.png.webp)", "![a](https://cdn.example.com/a.png.webp)", true},
+		{"already prefixed", "![a](https://cdn.example.com/static/a.png)", "![a](https://cdn.example.com/static/a.png)", false},
+		{"other static", "![b](/static/b.png)", "![b](/static/b.png)", false},
+		{"multiple", "![a](/static/a.png) ![a](/static/a.png)", "![a](https://cdn.example.com/a.png) ![a](https://cdn.example.com/a.png)", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			content, ok := replace(c.content)
+			assert.Equal(t, c.want, content)
+			assert.Equal(t, c.ok, ok)
+		})
+	}
+}
@@ -39,6 +39,11 @@ func (userSvr *UserService) getUser() (*domain.User, error) {
 	return user, nil
 }
 
+// GetUserById find admin or collaborator by user id
+func (userSvr *UserService) GetUserById(id uint64) (*domain.User, error) {
+	return userSvr.UserRepository.FindOne(mongodb.NewLogicalDefault(bson.E{Key: "id", Value: id}))
+}
+
 func (userSvr *UserService) GetUserList() []*domain.User {
 	userList, err := userSvr.UserRepository.FindList(mongodb.NewLogical())
 	if err != nil {
@@ -0,0 +1,160 @@
+package svr
+
+import (
+	"bytes"
+	"cc.allio/fusion/internal/domain"
+	"cc.allio/fusion/pkg/img"
+	"context"
+	"github.com/disintegration/imaging"
+	"github.com/google/wire"
+	"golang.org/x/exp/slog"
+	"golang.org/x/image/font/opentype"
+	"image"
+	"io"
+	"sync"
+)
+
+// WatermarkService draw watermark to uploaded image according to domain.WaterMarkSetting,
+// font and logo are uploaded statics that referenced by sign
+type WatermarkService struct {
+	FileService    *FileService
+	StaticService  *StaticService
+	SettingService *SettingService
+
+	mu sync.Mutex
+	// fonts parsed font cache by static sign
+	fonts map[string]*opentype.Font
+	// logos decoded logo cache by static sign
+	logos map[string]image.Image
+}
+
+var WatermarkServiceSet = wire.NewSet(wire.Struct(new(WatermarkService), "FileService", "StaticService", "SettingService"))
+
+// Apply draw watermark to image read from src, empty text uses text of static setting.
+// src is returned as it is when there is neither text nor logo
+func (w *WatermarkService) Apply(ctx context.Context, src io.Reader, format imaging.Format, text string) (io.Reader, error) {
+	setting := w.SettingService.FindStaticSetting()
+	if text == "" {
+		text = setting.WaterMarkText
+	}
+	opts, err := w.options(ctx, setting.WaterMark, text)
+	if err != nil {
+		return nil, err
+	}
+	// nothing to draw, watermark is treated as disabled
+	if opts.Text == "" && opts.Image == nil {
+		return src, nil
+	}
+	watermark, err := img.NewWatermark(src)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := watermark.EncodeWith(buf, opts, format); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// options convert setting to img.WatermarkOptions, font or logo failed to load will fall back to text of builtin font
+func (w *WatermarkService) options(ctx context.Context, setting *domain.WaterMarkSetting, text string) (*img.WatermarkOptions, error) {
+	if setting == nil {
+		setting = &domain.DefaultWaterMarkSetting
+	}
+	opts := &img.WatermarkOptions{
+		Text:       text,
+		FontSize:   setting.FontSize,
+		ImageRatio: setting.ImageRatio,
+		Opacity:    setting.Opacity,
+		Margin:     setting.Margin,
+		Rotation:   setting.Rotation,
+		Location:   img.ParseLocation(setting.Location),
+		Tiled:      setting.Tiled,
+	}
+	if setting.Color != "" {
+		c, err := img.ParseHexColor(setting.Color)
+		if err != nil {
+			return nil, err
+		}
+		opts.Color = c
+	}
+	if setting.ImageSign != "" {
+		logo, err := w.logo(ctx, setting.ImageSign)
+		if err != nil {
+			slog.Error("Failed to load watermark logo", "err", err, "sign", setting.ImageSign)
+		}
+		opts.Image = logo
+	}
+	if opts.Image == nil && setting.FontSign != "" {
+		f, err := w.font(ctx, setting.FontSign)
+		if err != nil {
+			slog.Error("Failed to load watermark font", "err", err, "sign", setting.FontSign)
+		}
+		opts.Font = f
+	}
+	return opts, nil
+}
+
+// Invalidate drop cached fonts and logos, used when watermark setting is saved
+func (w *WatermarkService) Invalidate() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fonts = nil
+	w.logos = nil
+}
+
+func (w *WatermarkService) font(ctx context.Context, sign string) (*opentype.Font, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if f, ok := w.fonts[sign]; ok {
+		return f, nil
+	}
+	data, err := w.load(ctx, sign)
+	if err != nil {
+		return nil, err
+	}
+	f, err := img.ParseFont(data)
+	if err != nil {
+		return nil, err
+	}
+	if w.fonts == nil {
+		w.fonts = make(map[string]*opentype.Font)
+	}
+	w.fonts[sign] = f
+	return f, nil
+}
+
+func (w *WatermarkService) logo(ctx context.Context, sign string) (image.Image, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if logo, ok := w.logos[sign]; ok {
+		return logo, nil
+	}
+	data, err := w.load(ctx, sign)
+	if err != nil {
+		return nil, err
+	}
+	logo, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if w.logos == nil {
+		w.logos = make(map[string]image.Image)
+	}
+	w.logos[sign] = logo
+	return logo, nil
+}
+
+// load content of static by sign
+func (w *WatermarkService) load(ctx context.Context, sign string) ([]byte, error) {
+	static, err := w.StaticService.GetBySign(sign)
+	if err != nil {
+		return nil, err
+	}
+	content, err := w.FileService.Download(ctx, static.RealPath)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	return io.ReadAll(content)
+}
@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/goccy/go-json"
+	"io"
+	"os"
+	"time"
+)
+
+const perm = 0644
+
+// Writer write entries into .tar.gz stream
+type Writer struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	gw := gzip.NewWriter(w)
+	return &Writer{gw: gw, tw: tar.NewWriter(gw)}
+}
+
+// WriteFile write entry by name, size must be equal to reader content length
+func (w *Writer) WriteFile(name string, size int64, modTime time.Time, r io.Reader) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     perm,
+		ModTime:  modTime,
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w.tw, r, size)
+	return err
+}
+
+// WriteBytes write entry by name and content
+func (w *Writer) WriteBytes(name string, content []byte) error {
+	return w.WriteFile(name, int64(len(content)), time.Now(), bytes.NewReader(content))
+}
+
+// WriteJson marshal v as json entry
+func (w *Writer) WriteJson(name string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.WriteBytes(name, content)
+}
+
+// NdjsonSpool ndjson entry whose lines are encoded one by one into temporary file, because tar entry size must be
+// known before its content. it keeps memory bounded for large collections
+type NdjsonSpool struct {
+	file    *os.File
+	encoder *json.Encoder
+	count   int
+}
+
+func NewNdjsonSpool() (*NdjsonSpool, error) {
+	file, err := os.CreateTemp("", "fusion-ndjson-*")
+	if err != nil {
+		return nil, err
+	}
+	return &NdjsonSpool{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Encode v as one line
+func (s *NdjsonSpool) Encode(v interface{}) error {
+	if err := s.encoder.Encode(v); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+// Count lines encoded
+func (s *NdjsonSpool) Count() int {
+	return s.count
+}
+
+// Close remove temporary file
+func (s *NdjsonSpool) Close() error {
+	return errors.Join(s.file.Close(), os.Remove(s.file.Name()))
+}
+
+// WriteSpool write spooled lines as entry by name
+func (w *Writer) WriteSpool(name string, s *NdjsonSpool) error {
+	size, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return w.WriteFile(name, size, time.Now(), s.file)
+}
+
+// Close flush tar and gzip stream, not close underlying writer
+func (w *Writer) Close() error {
+	return errors.Join(w.tw.Close(), w.gw.Close())
+}
+
+// Reader read entries from .tar.gz stream
+type Reader struct {
+	gr *gzip.Reader
+	tr *tar.Reader
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{gr: gr, tr: tar.NewReader(gr)}, nil
+}
+
+// Next advance to next regular file entry, returns io.EOF at the end of archive
+func (r *Reader) Next() (*tar.Header, io.Reader, error) {
+	for {
+		header, err := r.tr.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			return header, r.tr, nil
+		}
+	}
+}
+
+func (r *Reader) Close() error {
+	return r.gr.Close()
+}
+
+// ReadNdjson decode each line of json into T
+func ReadNdjson[T interface{}](r io.Reader, new func() T) ([]T, error) {
+	decoder := json.NewDecoder(r)
+	values := make([]T, 0)
+	for {
+		v := new()
+		if err := decoder.Decode(v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return values, nil
+			}
+			return nil, err
+		}
+		values = append(values, v)
+	}
+}
+
+// IsGzip reports whether header bytes is gzip magic number
+func IsGzip(header []byte) bool {
+	return len(header) >= 2 && header[0] == 0x1f && header[1] == 0x8b
+}
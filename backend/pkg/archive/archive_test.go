@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type item struct {
+	Name string `json:"name"`
+}
+
+func TestArchive(t *testing.T) {
+	a := assert.New(t)
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	a.NoError(w.WriteJson("manifest.json", map[string]int{"schemaVersion": 1}))
+	a.NoError(w.WriteBytes("items.ndjson", []byte("{\"name\":\"a\"}\n{\"name\":\"b\"}\n")))
+	a.NoError(w.WriteFile("files/a.txt", 5, time.Now(), strings.NewReader("hello")))
+	a.NoError(w.Close())
+	a.True(IsGzip(buf.Bytes()))
+
+	r, err := NewReader(buf)
+	a.NoError(err)
+	header, _, err := r.Next()
+	a.NoError(err)
+	a.Equal("manifest.json", header.Name)
+
+	header, content, err := r.Next()
+	a.NoError(err)
+	a.Equal("items.ndjson", header.Name)
+	items, err := ReadNdjson(content, func() *item { return &item{} })
+	a.NoError(err)
+	a.Len(items, 2)
+	a.Equal("b", items[1].Name)
+
+	header, content, err = r.Next()
+	a.NoError(err)
+	a.Equal("files/a.txt", header.Name)
+	text, err := io.ReadAll(content)
+	a.NoError(err)
+	a.Equal("hello", string(text))
+
+	_, _, err = r.Next()
+	a.ErrorIs(err, io.EOF)
+}
+
+func TestNdjsonSpool(t *testing.T) {
+	a := assert.New(t)
+	spool, err := NewNdjsonSpool()
+	a.NoError(err)
+	defer spool.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		a.NoError(spool.Encode(&item{Name: name}))
+	}
+	a.Equal(3, spool.Count())
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	a.NoError(w.WriteSpool("items.ndjson", spool))
+	a.NoError(w.Close())
+
+	r, err := NewReader(buf)
+	a.NoError(err)
+	header, content, err := r.Next()
+	a.NoError(err)
+	a.Equal("items.ndjson", header.Name)
+	items, err := ReadNdjson(content, func() *item { return &item{} })
+	a.NoError(err)
+	a.Len(items, 3)
+	a.Equal("c", items[2].Name)
+}
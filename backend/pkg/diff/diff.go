@@ -0,0 +1,202 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mode unit of diff
+type Mode = string
+
+const (
+	// LineMode lines are compared as a whole, like 'git diff'
+	LineMode Mode = "line"
+	// WordMode words, whitespaces and punctuations are compared, each CJK character is a word
+	WordMode Mode = "word"
+)
+
+type Op = string
+
+const (
+	EqualOp  Op = "equal"
+	InsertOp Op = "insert"
+	DeleteOp Op = "delete"
+)
+
+// ErrUnknownMode mode is neither LineMode nor WordMode
+var ErrUnknownMode = errors.New("unknown diff mode")
+
+// ErrTooManyTokens texts have more distinct lines or words than can be diffed
+var ErrTooManyTokens = errors.New("too many distinct tokens to diff")
+
+// Chunk continuous text of the same operation, concatenating equal and delete chunks gives old text, equal and
+// insert chunks gives new text
+type Chunk struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+type Result struct {
+	Mode   Mode     `json:"mode"`
+	Chunks []*Chunk `json:"chunks"`
+	// Insertions lines or words inserted, whitespaces are not counted in WordMode
+	Insertions int `json:"insertions"`
+	// Deletions lines or words deleted, whitespaces are not counted in WordMode
+	Deletions int `json:"deletions"`
+}
+
+// Diff old and new text by lines or words
+func Diff(old string, new string, mode Mode) (*Result, error) {
+	var tokenize func(string) []string
+	switch mode {
+	case LineMode:
+		tokenize = lines
+	case WordMode:
+		tokenize = words
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMode, mode)
+	}
+	// each distinct token is encoded as a rune, so that tokens are diffed as characters
+	encoder := &tokenEncoder{runes: make(map[string]rune)}
+	oldRunes, err := encoder.encode(tokenize(old))
+	if err != nil {
+		return nil, err
+	}
+	newRunes, err := encoder.encode(tokenize(new))
+	if err != nil {
+		return nil, err
+	}
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupMerge(dmp.DiffMainRunes(oldRunes, newRunes, false))
+
+	result := &Result{Mode: mode, Chunks: make([]*Chunk, 0, len(diffs))}
+	for _, d := range diffs {
+		op := EqualOp
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = InsertOp
+		case diffmatchpatch.DiffDelete:
+			op = DeleteOp
+		}
+		text := &strings.Builder{}
+		for _, r := range d.Text {
+			token := encoder.tokens[decodeRune(r)]
+			text.WriteString(token)
+			if mode == LineMode || strings.TrimSpace(token) != "" {
+				switch op {
+				case InsertOp:
+					result.Insertions++
+				case DeleteOp:
+					result.Deletions++
+				}
+			}
+		}
+		if text.Len() == 0 {
+			continue
+		}
+		if last := len(result.Chunks) - 1; last >= 0 && result.Chunks[last].Op == op {
+			result.Chunks[last].Text += text.String()
+		} else {
+			result.Chunks = append(result.Chunks, &Chunk{Op: op, Text: text.String()})
+		}
+	}
+	return result, nil
+}
+
+type tokenEncoder struct {
+	tokens []string
+	runes  map[string]rune
+}
+
+func (e *tokenEncoder) encode(tokens []string) ([]rune, error) {
+	encoded := make([]rune, 0, len(tokens))
+	for _, token := range tokens {
+		r, ok := e.runes[token]
+		if !ok {
+			r = encodeRune(len(e.tokens))
+			if !utf8.ValidRune(r) {
+				return nil, ErrTooManyTokens
+			}
+			e.runes[token] = r
+			e.tokens = append(e.tokens, token)
+		}
+		encoded = append(encoded, r)
+	}
+	return encoded, nil
+}
+
+// encodeRune index of token as valid rune, surrogate halves are skipped because they do not survive conversion
+// between string and runes
+func encodeRune(index int) rune {
+	r := rune(index + 1)
+	if r >= 0xD800 {
+		r += 0x800
+	}
+	return r
+}
+
+func decodeRune(r rune) int {
+	if r >= 0xE000 {
+		r -= 0x800
+	}
+	return int(r - 1)
+}
+
+// lines split text after each '\n'
+func lines(text string) []string {
+	tokens := make([]string, 0, strings.Count(text, "\n")+1)
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			tokens = append(tokens, text)
+			break
+		}
+		tokens = append(tokens, text[:i+1])
+		text = text[i+1:]
+	}
+	return tokens
+}
+
+// words split text into runs of letters and digits, runs of whitespaces and single other characters
+func words(text string) []string {
+	tokens := make([]string, 0)
+	start := -1
+	kind := 0
+	for i, r := range text {
+		k := runeKind(r)
+		if start >= 0 && (k != kind || k == otherKind) {
+			tokens = append(tokens, text[start:i])
+			start = -1
+		}
+		if start < 0 {
+			start, kind = i, k
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+const (
+	wordKind = iota + 1
+	spaceKind
+	otherKind
+)
+
+func runeKind(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return otherKind
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return wordKind
+	case unicode.IsSpace(r):
+		return spaceKind
+	default:
+		return otherKind
+	}
+}
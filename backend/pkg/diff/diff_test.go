@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestDiff_Line(t *testing.T) {
+	old := "# title\nfirst line\nsecond line\nthird line\n"
+	new := "# title\nfirst line\nchanged line\nthird line\nappended\n"
+	result, err := Diff(old, new, LineMode)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Chunk{
+		{Op: EqualOp, Text: "# title\nfirst line\n"},
+		{Op: DeleteOp, Text: "second line\n"},
+		{Op: InsertOp, Text: "changed line\n"},
+		{Op: EqualOp, Text: "third line\n"},
+		{Op: InsertOp, Text: "appended\n"},
+	}, result.Chunks)
+	assert.Equal(t, 2, result.Insertions)
+	assert.Equal(t, 1, result.Deletions)
+	assertRebuild(t, old, new, result)
+}
+
+func TestDiff_Word(t *testing.T) {
+	old := "the quick brown fox jumps"
+	new := "the slow brown fox jumps high"
+	result, err := Diff(old, new, WordMode)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Chunk{
+		{Op: EqualOp, Text: "the "},
+		{Op: DeleteOp, Text: "quick"},
+		{Op: InsertOp, Text: "slow"},
+		{Op: EqualOp, Text: " brown fox jumps"},
+		{Op: InsertOp, Text: " high"},
+	}, result.Chunks)
+	assert.Equal(t, 2, result.Insertions)
+	assert.Equal(t, 1, result.Deletions)
+	assertRebuild(t, old, new, result)
+
+	old, new = "今天天气很好", "今天天气不好"
+	result, err = Diff(old, new, WordMode)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Insertions)
+	assert.Equal(t, 1, result.Deletions)
+	assertRebuild(t, old, new, result)
+}
+
+func TestDiff_ManyTokens(t *testing.T) {
+	builder := &strings.Builder{}
+	for i := 0; i < 60000; i++ {
+		builder.WriteString(strings.Repeat("x", i%7+1))
+		builder.WriteString(string(rune('a' + i%26)))
+		builder.WriteString(" ")
+		builder.WriteString(string(rune(0x4e00 + i%20000)))
+		builder.WriteString("\n")
+	}
+	old := builder.String()
+	new := strings.Replace(old, "\n", "\nnew\n", 1)
+	result, err := Diff(old, new, LineMode)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Insertions)
+	assertRebuild(t, old, new, result)
+
+	_, err = Diff(old, new, "char")
+	assert.ErrorIs(t, err, ErrUnknownMode)
+}
+
+func assertRebuild(t *testing.T, old string, new string, result *Result) {
+	oldBuilder, newBuilder := &strings.Builder{}, &strings.Builder{}
+	for _, chunk := range result.Chunks {
+		if chunk.Op != InsertOp {
+			oldBuilder.WriteString(chunk.Text)
+		}
+		if chunk.Op != DeleteOp {
+			newBuilder.WriteString(chunk.Text)
+		}
+	}
+	assert.Equal(t, old, oldBuilder.String())
+	assert.Equal(t, new, newBuilder.String())
+}
@@ -0,0 +1,97 @@
+package img
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+type ConvertFormat = string
+
+const (
+	WebpFormat ConvertFormat = "webp"
+	AvifFormat ConvertFormat = "avif"
+)
+
+// ErrConverterNotFound encoder command of format not installed
+var ErrConverterNotFound = errors.New("image converter not found")
+
+// converter encode image file by external command, webp by cwebp (libwebp-tools) and avif by avifenc (libavif-apps)
+type converter struct {
+	command  string
+	mimeType string
+	args     func(quality int, in string, out string) []string
+}
+
+var converters = map[ConvertFormat]*converter{
+	WebpFormat: {
+		command:  "cwebp",
+		mimeType: "image/webp",
+		args: func(quality int, in string, out string) []string {
+			return []string{"-quiet", "-q", strconv.Itoa(quality), in, "-o", out}
+		},
+	},
+	AvifFormat: {
+		command:  "avifenc",
+		mimeType: "image/avif",
+		args: func(quality int, in string, out string) []string {
+			return []string{"-q", strconv.Itoa(quality), in, out}
+		},
+	},
+}
+
+// ConverterAvailable whether encoder command of format can be found in PATH
+func ConverterAvailable(format ConvertFormat) bool {
+	c, ok := converters[format]
+	if !ok {
+		return false
+	}
+	_, err := exec.LookPath(c.command)
+	return err == nil
+}
+
+// MimeType of convert format, empty if not supported
+func MimeType(format ConvertFormat) string {
+	if c, ok := converters[format]; ok {
+		return c.mimeType
+	}
+	return ""
+}
+
+// Convert encode jpeg or png data to format, ext is the extension of src like '.png'
+func Convert(ctx context.Context, src []byte, ext string, format ConvertFormat, quality int) ([]byte, error) {
+	c, ok := converters[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported convert format %s", format)
+	}
+	command, err := exec.LookPath(c.command)
+	if err != nil {
+		return nil, ErrConverterNotFound
+	}
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+
+	dir, err := os.MkdirTemp("", "fusion-convert-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "src"+ext)
+	out := filepath.Join(dir, "dst."+format)
+	if err := os.WriteFile(in, src, 0o600); err != nil {
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, command, c.args(quality, in, out)...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", c.command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return os.ReadFile(out)
+}
@@ -0,0 +1,26 @@
+package img
+
+import (
+	"bytes"
+	"context"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	_, err := Convert(context.Background(), nil, ".png", "bmp", 80)
+	assert.Error(t, err)
+
+	if !ConverterAvailable(WebpFormat) {
+		t.Skip("cwebp not found in PATH")
+	}
+	src := imaging.New(64, 64, color.NRGBA{B: 255, A: 255})
+	buf := &bytes.Buffer{}
+	assert.NoError(t, imaging.Encode(buf, src, imaging.PNG))
+	webp, err := Convert(context.Background(), buf.Bytes(), ".png", WebpFormat, 80)
+	assert.NoError(t, err)
+	assert.Equal(t, "RIFF", string(webp[:4]))
+	assert.Equal(t, "WEBP", string(webp[8:12]))
+}
@@ -0,0 +1,107 @@
+package img
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"strings"
+)
+
+const (
+	// MaxResizeDimension max width or height of resized image
+	MaxResizeDimension = 4096
+)
+
+// ErrInvalidResize resize options out of range or source image not supported
+var ErrInvalidResize = errors.New("invalid resize options")
+
+// ResizeOptions describe on-the-fly resized image, zero width or height means not limited by it
+type ResizeOptions struct {
+	Width  int
+	Height int
+	Fit    ThumbMode
+	// Format output format like 'jpeg', 'png', 'gif', 'webp' or 'avif', empty keeps source format
+	Format  string
+	Quality int
+}
+
+// Normalize fill default fit and format of source format, then validate options
+func (o *ResizeOptions) Normalize(source imaging.Format) error {
+	if o.Width < 0 || o.Height < 0 || o.Width > MaxResizeDimension || o.Height > MaxResizeDimension {
+		return fmt.Errorf("%w: width and height must between 0 and %d", ErrInvalidResize, MaxResizeDimension)
+	}
+	if o.Width == 0 && o.Height == 0 {
+		return fmt.Errorf("%w: width or height is required", ErrInvalidResize)
+	}
+	switch o.Fit {
+	case "":
+		o.Fit = FitThumbMode
+	case FitThumbMode, FillThumbMode:
+	default:
+		return fmt.Errorf("%w: unknown fit %s", ErrInvalidResize, o.Fit)
+	}
+	o.Format = strings.ToLower(o.Format)
+	if o.Format == "" {
+		o.Format = strings.ToLower(source.String())
+	}
+	if o.Format == "jpg" {
+		o.Format = "jpeg"
+	}
+	switch o.Format {
+	case "jpeg", "png", "gif":
+	case WebpFormat, AvifFormat:
+		if !ConverterAvailable(o.Format) {
+			return fmt.Errorf("%w: %s", ErrConverterNotFound, o.Format)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported format %s", ErrInvalidResize, o.Format)
+	}
+	return nil
+}
+
+// Key identify resized image of the same source, like '960x0_fit.webp'
+func (o *ResizeOptions) Key() string {
+	return fmt.Sprintf("%dx%d_%s.%s", o.Width, o.Height, o.Fit, o.Format)
+}
+
+// ResizeMimeType mime type of resize output format
+func ResizeMimeType(format string) string {
+	if mimeType := MimeType(format); mimeType != "" {
+		return mimeType
+	}
+	return "image/" + format
+}
+
+// Resize decode data with exif orientation applied, resize it by normalized options then encode to options format.
+// image will not be enlarged
+func Resize(ctx context.Context, data []byte, opts *ResizeOptions) ([]byte, error) {
+	if err := CheckDimension(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResize, err)
+	}
+	src, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, err
+	}
+	resized := Thumbnail(src, ThumbSize{Width: opts.Width, Height: opts.Height, Mode: opts.Fit})
+
+	buf := &bytes.Buffer{}
+	switch opts.Format {
+	case WebpFormat, AvifFormat:
+		// external encoders accept png losslessly
+		if err := Encode(buf, resized, imaging.PNG, opts.Quality); err != nil {
+			return nil, err
+		}
+		return Convert(ctx, buf.Bytes(), ".png", opts.Format, opts.Quality)
+	default:
+		format, err := imaging.FormatFromExtension(opts.Format)
+		if err != nil {
+			return nil, err
+		}
+		if err := Encode(buf, resized, format, opts.Quality); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
@@ -0,0 +1,40 @@
+package img
+
+import (
+	"bytes"
+	"context"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+)
+
+func TestResize(t *testing.T) {
+	src := imaging.New(800, 400, color.NRGBA{G: 255, A: 255})
+	buf := &bytes.Buffer{}
+	assert.NoError(t, imaging.Encode(buf, src, imaging.PNG))
+
+	opts := &ResizeOptions{Width: 200, Height: 200, Fit: FillThumbMode, Format: "jpg"}
+	assert.NoError(t, opts.Normalize(imaging.PNG))
+	assert.Equal(t, "200x200_fill.jpeg", opts.Key())
+	data, err := Resize(context.Background(), buf.Bytes(), opts)
+	assert.NoError(t, err)
+	resized, err := imaging.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resized.Bounds().Dx())
+	assert.Equal(t, 200, resized.Bounds().Dy())
+
+	opts = &ResizeOptions{Width: 400}
+	assert.NoError(t, opts.Normalize(imaging.PNG))
+	assert.Equal(t, "400x0_fit.png", opts.Key())
+
+	invalid := []*ResizeOptions{
+		{},
+		{Width: MaxResizeDimension + 1},
+		{Width: 100, Fit: "stretch"},
+		{Width: 100, Format: "bmp"},
+	}
+	for _, opts := range invalid {
+		assert.ErrorIs(t, opts.Normalize(imaging.PNG), ErrInvalidResize)
+	}
+}
@@ -0,0 +1,75 @@
+package img
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"image"
+	"image/color"
+)
+
+// SanitizeQuality jpeg quality of re-encoded image, high enough to hide generation loss
+const SanitizeQuality = 92
+
+// Sanitizable whether Sanitize can process format without losing content, animated gif would lose frames
+func Sanitizable(format imaging.Format) bool {
+	return format == imaging.JPEG || format == imaging.PNG || format == imaging.TIFF
+}
+
+// Sanitize apply exif orientation then re-encode image, the encoders write no exif, xmp or iptc metadata.
+// returns re-encoded data and decoded image
+func Sanitize(data []byte, format imaging.Format) ([]byte, image.Image, error) {
+	if !Sanitizable(format) {
+		return nil, nil, fmt.Errorf("unsupported sanitize format %s", format)
+	}
+	src, err := Decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := imaging.Encode(buf, src, format, imaging.JPEGQuality(SanitizeQuality)); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), src, nil
+}
+
+// DominantColor most frequent color of image, colors are quantized into buckets and averaged inside the bucket.
+// transparent pixels are ignored
+func DominantColor(src image.Image) color.NRGBA {
+	small := imaging.Resize(src, 32, 0, imaging.Box)
+	type bucket struct {
+		r, g, b, count int
+	}
+	buckets := make(map[int]*bucket)
+	var dominant *bucket
+	for i := 0; i < len(small.Pix); i += 4 {
+		if small.Pix[i+3] < 128 {
+			continue
+		}
+		r, g, b := int(small.Pix[i]), int(small.Pix[i+1]), int(small.Pix[i+2])
+		key := r>>4<<8 | g>>4<<4 | b>>4
+		bk, ok := buckets[key]
+		if !ok {
+			bk = &bucket{}
+			buckets[key] = bk
+		}
+		bk.r, bk.g, bk.b, bk.count = bk.r+r, bk.g+g, bk.b+b, bk.count+1
+		if dominant == nil || bk.count > dominant.count {
+			dominant = bk
+		}
+	}
+	if dominant == nil {
+		return color.NRGBA{}
+	}
+	return color.NRGBA{
+		R: uint8(dominant.r / dominant.count),
+		G: uint8(dominant.g / dominant.count),
+		B: uint8(dominant.b / dominant.count),
+		A: 255,
+	}
+}
+
+// HexColor format color like '#ff8800'
+func HexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
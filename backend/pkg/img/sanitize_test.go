@@ -0,0 +1,71 @@
+package img
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	src := imaging.New(40, 20, color.NRGBA{R: 200, A: 255})
+	buf := &bytes.Buffer{}
+	assert.NoError(t, imaging.Encode(buf, src, imaging.JPEG))
+	data := withOrientation(buf.Bytes(), 6)
+	assert.True(t, bytes.Contains(data, []byte("Exif")))
+
+	sanitized, decoded, err := Sanitize(data, imaging.JPEG)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(sanitized, []byte("Exif")))
+	// orientation 6 means rotate 90 clockwise
+	assert.Equal(t, 20, decoded.Bounds().Dx())
+	assert.Equal(t, 40, decoded.Bounds().Dy())
+
+	_, _, err = Sanitize(data, imaging.GIF)
+	assert.Error(t, err)
+
+	// png header of 20000x20000 without any pixel data
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], 20000)
+	binary.BigEndian.PutUint32(ihdr[8:], 20000)
+	ihdr[12], ihdr[13] = 8, 6
+	bomb := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d"), ihdr...)
+	bomb = binary.BigEndian.AppendUint32(bomb, crc32.ChecksumIEEE(ihdr))
+	_, _, err = Sanitize(bomb, imaging.PNG)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
+
+func TestDominantColor(t *testing.T) {
+	src := imaging.New(100, 100, color.NRGBA{B: 255, A: 255})
+	corner := imaging.New(30, 30, color.NRGBA{R: 255, A: 255})
+	src = imaging.Paste(src, corner, image.Point{})
+	assert.Equal(t, "#0000ff", HexColor(DominantColor(src)))
+	assert.Equal(t, color.NRGBA{}, DominantColor(imaging.New(10, 10, color.NRGBA{})))
+}
+
+// withOrientation insert exif APP1 segment that only contains orientation tag after jpeg SOI
+func withOrientation(jpeg []byte, orientation uint16) []byte {
+	tiff := &bytes.Buffer{}
+	tiff.WriteString("MM")
+	binary.Write(tiff, binary.BigEndian, uint16(42))
+	binary.Write(tiff, binary.BigEndian, uint32(8))
+	binary.Write(tiff, binary.BigEndian, uint16(1))
+	binary.Write(tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(tiff, binary.BigEndian, uint32(1))
+	binary.Write(tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(tiff, binary.BigEndian, uint32(0))
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	out := &bytes.Buffer{}
+	out.Write(jpeg[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(jpeg[2:])
+	return out.Bytes()
+}
@@ -0,0 +1,66 @@
+package img
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrSvgScript svg contains script that will be executed when opened by browser
+var ErrSvgScript = errors.New("svg contains script")
+
+// svgScriptElements elements that can execute script or embed html
+var svgScriptElements = []string{"script", "foreignobject", "iframe", "embed", "object", "handler", "listener"}
+
+// CheckSvg reject svg that contains script element, event handler attribute, javascript url or entity declaration.
+// svg that can not be parsed is rejected too
+func CheckSvg(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid svg: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.Directive:
+			// entity may expand to script
+			if strings.Contains(strings.ToUpper(string(t)), "ENTITY") {
+				return fmt.Errorf("%w: entity declaration", ErrSvgScript)
+			}
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			for _, element := range svgScriptElements {
+				if name == element {
+					return fmt.Errorf("%w: <%s> element", ErrSvgScript, t.Name.Local)
+				}
+			}
+			for _, attr := range t.Attr {
+				if strings.HasPrefix(strings.ToLower(attr.Name.Local), "on") {
+					return fmt.Errorf("%w: %s attribute", ErrSvgScript, attr.Name.Local)
+				}
+				if scriptUrl(attr.Value) {
+					return fmt.Errorf("%w: %s url", ErrSvgScript, attr.Name.Local)
+				}
+			}
+		}
+	}
+}
+
+// scriptUrl whether value is javascript or html data url, browsers ignore whitespace and control characters inside scheme
+func scriptUrl(value string) bool {
+	normalized := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(value))
+	return strings.HasPrefix(normalized, "javascript:") ||
+		strings.HasPrefix(normalized, "vbscript:") ||
+		strings.HasPrefix(normalized, "data:text/html")
+}
@@ -0,0 +1,87 @@
+package img
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"image"
+	"io"
+	"math"
+)
+
+type ThumbMode = string
+
+const (
+	// FitThumbMode scale down image to fit the bounding box, keep aspect ratio
+	FitThumbMode ThumbMode = "fit"
+	// FillThumbMode scale and crop image from center to fill the bounding box
+	FillThumbMode ThumbMode = "fill"
+)
+
+const DefaultQuality = 85
+
+// MaxDecodePixels image larger than it will not be decoded, small compressed file may expand to huge bitmap
+const MaxDecodePixels = 50_000_000
+
+// ErrImageTooLarge image dimension exceeds MaxDecodePixels
+var ErrImageTooLarge = errors.New("image is too large to decode")
+
+// ThumbSize describe thumbnail bounding box, zero width or height means not limited by it
+type ThumbSize struct {
+	Width  int
+	Height int
+	Mode   ThumbMode
+}
+
+// Thumbnail resize src by size, image smaller than size will not be enlarged
+func Thumbnail(src image.Image, size ThumbSize) image.Image {
+	bounds := src.Bounds()
+	width, height := size.Width, size.Height
+	if width <= 0 {
+		width = bounds.Dx()
+	}
+	if height <= 0 {
+		height = bounds.Dy()
+	}
+	if size.Mode == FillThumbMode && size.Width > 0 && size.Height > 0 {
+		if bounds.Dx() < width || bounds.Dy() < height {
+			// not enlarge, crop center area with the same ratio of size
+			scale := math.Min(float64(bounds.Dx())/float64(width), float64(bounds.Dy())/float64(height))
+			width, height = int(float64(width)*scale), int(float64(height)*scale)
+		}
+		return imaging.Fill(src, width, height, imaging.Center, imaging.Lanczos)
+	}
+	if bounds.Dx() <= width && bounds.Dy() <= height {
+		return imaging.Clone(src)
+	}
+	return imaging.Fit(src, width, height, imaging.Lanczos)
+}
+
+// Decode data with exif orientation applied, dimension is checked by image header before decoding
+func Decode(data []byte) (image.Image, error) {
+	if err := CheckDimension(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+}
+
+// CheckDimension read image header from r, returns ErrImageTooLarge if it exceeds MaxDecodePixels
+func CheckDimension(r io.Reader) error {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return err
+	}
+	if config.Width*config.Height > MaxDecodePixels {
+		return fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+	return nil
+}
+
+// Encode img to w by format, quality only take effect on jpeg
+func Encode(w io.Writer, img image.Image, format imaging.Format, quality int) error {
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+	return imaging.Encode(w, img, format, imaging.JPEGQuality(quality))
+}
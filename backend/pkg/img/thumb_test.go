@@ -0,0 +1,53 @@
+package img
+
+import (
+	"bytes"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	src := imaging.New(800, 400, color.NRGBA{R: 255, A: 255})
+
+	fit := Thumbnail(src, ThumbSize{Width: 200, Height: 200, Mode: FitThumbMode})
+	assert.Equal(t, 200, fit.Bounds().Dx())
+	assert.Equal(t, 100, fit.Bounds().Dy())
+
+	fill := Thumbnail(src, ThumbSize{Width: 200, Height: 200, Mode: FillThumbMode})
+	assert.Equal(t, 200, fill.Bounds().Dx())
+	assert.Equal(t, 200, fill.Bounds().Dy())
+
+	// not enlarge
+	small := Thumbnail(src, ThumbSize{Width: 1600, Mode: FitThumbMode})
+	assert.Equal(t, 800, small.Bounds().Dx())
+	crop := Thumbnail(src, ThumbSize{Width: 1000, Height: 1000, Mode: FillThumbMode})
+	assert.Equal(t, 400, crop.Bounds().Dx())
+	assert.Equal(t, 400, crop.Bounds().Dy())
+}
+
+func TestEncode(t *testing.T) {
+	src := imaging.New(64, 64, color.NRGBA{G: 255, A: 255})
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Encode(buf, src, imaging.JPEG, 0))
+	decoded, err := imaging.Decode(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, decoded.Bounds().Dx())
+}
+
+func TestDecode(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, imaging.Encode(buf, imaging.New(40, 20, color.NRGBA{G: 255, A: 255}), imaging.PNG))
+	src, err := Decode(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 40, src.Bounds().Dx())
+
+	// gif header of 65535x65535 logical screen without any frame
+	bomb := []byte{'G', 'I', 'F', '8', '9', 'a', 0xff, 0xff, 0xff, 0xff, 0, 0, 0}
+	_, err = Decode(bomb)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	_, err = Decode([]byte("not image"))
+	assert.Error(t, err)
+}
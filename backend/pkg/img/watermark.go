@@ -1,15 +1,22 @@
 package img
 
 import (
+	"errors"
+	"fmt"
 	"github.com/disintegration/imaging"
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/math/fixed"
 	"image"
 	"image/color"
 	"image/draw"
 	"io"
+	"math"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type Location = byte
@@ -23,6 +30,82 @@ const (
 	Center      Location = 4
 )
 
+var locations = map[string]Location{
+	"topLeft":     TopLeft,
+	"topRight":    TopRight,
+	"bottomLeft":  BottomLeft,
+	"bottomRight": BottomRight,
+	"center":      Center,
+}
+
+// ParseLocation parse location name like 'bottomRight', unknown name is BottomRight
+func ParseLocation(name string) Location {
+	if l, ok := locations[name]; ok {
+		return l
+	}
+	return BottomRight
+}
+
+var (
+	defaultFont     *opentype.Font
+	defaultFontOnce sync.Once
+)
+
+// DefaultFont go regular font, it has no CJK glyph
+func DefaultFont() *opentype.Font {
+	defaultFontOnce.Do(func() {
+		defaultFont, _ = opentype.Parse(goregular.TTF)
+	})
+	return defaultFont
+}
+
+// ParseFont parse TrueType or OpenType font data
+func ParseFont(data []byte) (*opentype.Font, error) {
+	return opentype.Parse(data)
+}
+
+// ParseHexColor parse color like '#fff', '#ffffff' or '#ffffff80'
+func ParseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid hex color %s", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid hex color %s", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// WatermarkOptions describe text or image watermark, Image takes precedence of Text
+type WatermarkOptions struct {
+	Text string
+	// Font of text, nil is DefaultFont
+	Font *opentype.Font
+	// FontSize text size in pixel, zero is relative to source image width
+	FontSize float64
+	Color    color.Color
+	// Image logo of watermark
+	Image image.Image
+	// ImageRatio scale logo width to ratio of source image width, zero keeps logo size
+	ImageRatio float64
+	// Opacity in (0, 1], other value is 1
+	Opacity float64
+	// Margin distance to edge of source image, or spacing between watermarks when tiled
+	Margin int
+	// Rotation counter-clockwise angle in degrees
+	Rotation float64
+	Location Location
+	// Tiled repeat watermark over the whole image, Location is ignored
+	Tiled bool
+}
+
 type Watermark struct {
 	src image.Image
 }
@@ -36,9 +119,13 @@ func From(filepath string) (*Watermark, error) {
 	return &Watermark{src: img}, nil
 }
 
-// NewWatermark from io.Reader assign to input create Watermark
+// NewWatermark from io.Reader assign to input create Watermark, exif orientation is applied
 func NewWatermark(input io.Reader) (*Watermark, error) {
-	img, err := imaging.Decode(input)
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	img, err := Decode(data)
 	if err != nil {
 		return nil, err
 	}
@@ -47,30 +134,7 @@ func NewWatermark(input io.Reader) (*Watermark, error) {
 
 // Encode text mask to src image as watermark and through location adjust watermark place
 func (watermark *Watermark) Encode(w io.Writer, text string, l Location, f imaging.Format) error {
-	inputBounds := watermark.src.Bounds()
-
-	// Create a new RGBA image with the same size as the input image
-	output := imaging.New(inputBounds.Dx(), inputBounds.Dy(), color.NRGBA{})
-
-	// Draw the input image onto the result image
-	draw.Draw(output, inputBounds, watermark.src, image.Point{}, draw.Src)
-
-	// Create a Mask
-	mask := image.NewRGBA(image.Rect(0, 0, inputBounds.Dx(), inputBounds.Dy()))
-	// Draw the input image onto the result image
-	draw.Draw(mask, inputBounds, watermark.src, image.Point{}, draw.Src)
-	point := watermark.calculateLocation(l, inputBounds)
-	d := &font.Drawer{
-		Dst:  mask,
-		Src:  image.Black,
-		Face: basicfont.Face7x13,
-		Dot:  fixed.P(point.X, point.Y),
-	}
-	d.DrawString(text)
-
-	// Draw the text image onto the result image
-	draw.Draw(output, mask.Bounds(), mask, image.Point{}, draw.Over)
-	return imaging.Encode(w, output, f)
+	return watermark.EncodeWith(w, &WatermarkOptions{Text: text, Color: color.Black, Location: l}, f)
 }
 
 // EncodeFromFilepath from path gain image then invoke EncodeFromImage
@@ -89,32 +153,116 @@ func (watermark *Watermark) EncodeFromFilepath(w io.Writer, path string, l Locat
 
 // EncodeFromImage base on mask image to src input image, and through location adjust watermark place
 func (watermark *Watermark) EncodeFromImage(w io.Writer, mask image.Image, l Location, format imaging.Format) error {
-	inputBounds := watermark.src.Bounds()
+	return watermark.EncodeWith(w, &WatermarkOptions{Image: mask, Location: l}, format)
+}
 
-	// Create a new RGBA image with the same size as the input image
-	output := imaging.New(inputBounds.Dx(), inputBounds.Dy(), color.NRGBA{})
-	point := watermark.calculateLocation(l, inputBounds)
+// EncodeWith draw watermark by options then encode to w
+func (watermark *Watermark) EncodeWith(w io.Writer, opts *WatermarkOptions, format imaging.Format) error {
+	output, err := watermark.Draw(opts)
+	if err != nil {
+		return err
+	}
+	return imaging.Encode(w, output, format)
+}
 
-	// Draw maks to output
-	draw.Draw(output, mask.Bounds(), mask, point, draw.Over)
+// Draw watermark by options onto copy of src image
+func (watermark *Watermark) Draw(opts *WatermarkOptions) (*image.NRGBA, error) {
+	output := imaging.Clone(watermark.src)
+	bounds := output.Bounds()
+	mark, err := watermark.mark(opts, bounds)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Opacity > 0 && opts.Opacity < 1 {
+		for i := 3; i < len(mark.Pix); i += 4 {
+			mark.Pix[i] = uint8(float64(mark.Pix[i]) * opts.Opacity)
+		}
+	}
+	if opts.Rotation != 0 {
+		mark = imaging.Rotate(mark, opts.Rotation, color.Transparent)
+	}
 
-	return imaging.Encode(w, output, format)
+	markBounds := mark.Bounds()
+	if !opts.Tiled {
+		point := calculateLocation(opts.Location, bounds, markBounds, opts.Margin)
+		draw.Draw(output, markBounds.Add(point), mark, markBounds.Min, draw.Over)
+		return output, nil
+	}
+	spacing := opts.Margin
+	if spacing <= 0 {
+		spacing = markBounds.Dy()
+	}
+	stepX, stepY := markBounds.Dx()+spacing, markBounds.Dy()+spacing
+	for row, y := 0, bounds.Min.Y; y < bounds.Max.Y; row, y = row+1, y+stepY {
+		// stagger odd rows by half step
+		x := bounds.Min.X - (row%2)*stepX/2
+		for ; x < bounds.Max.X; x += stepX {
+			draw.Draw(output, markBounds.Add(image.Point{X: x, Y: y}), mark, markBounds.Min, draw.Over)
+		}
+	}
+	return output, nil
+}
+
+// mark create watermark image, logo is scaled by ratio and text is rendered by font
+func (watermark *Watermark) mark(opts *WatermarkOptions, bounds image.Rectangle) (*image.NRGBA, error) {
+	if opts.Image != nil {
+		if opts.ImageRatio > 0 {
+			width := int(float64(bounds.Dx()) * opts.ImageRatio)
+			return imaging.Resize(opts.Image, width, 0, imaging.Lanczos), nil
+		}
+		return imaging.Clone(opts.Image), nil
+	}
+	if opts.Text == "" {
+		return nil, errors.New("watermark text and image must not both be empty")
+	}
+	f := opts.Font
+	if f == nil {
+		f = DefaultFont()
+	}
+	size := opts.FontSize
+	if size <= 0 {
+		size = math.Max(12, float64(bounds.Dx())/30)
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+	metrics := face.Metrics()
+	width := font.MeasureString(face, opts.Text).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	textColor := opts.Color
+	if textColor == nil {
+		textColor = color.White
+	}
+	mark := image.NewNRGBA(image.Rect(0, 0, width, height))
+	d := &font.Drawer{
+		Dst:  mark,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.Point26_6{Y: metrics.Ascent},
+	}
+	d.DrawString(opts.Text)
+	return mark, nil
 }
 
-// calculateLocation calculate watermark location base on Location
-func (watermark *Watermark) calculateLocation(l Location, inputBounds image.Rectangle) image.Point {
+// calculateLocation calculate top left point of watermark inside input bounds base on Location
+func calculateLocation(l Location, inputBounds image.Rectangle, markBounds image.Rectangle, margin int) image.Point {
+	right := inputBounds.Max.X - markBounds.Dx() - margin
+	bottom := inputBounds.Max.Y - markBounds.Dy() - margin
 	switch l {
-	case TopLeft:
-		return image.Point{}
 	case TopRight:
-		return image.Point{Y: inputBounds.Dy()}
+		return image.Point{X: right, Y: inputBounds.Min.Y + margin}
 	case BottomLeft:
-		return image.Point{X: inputBounds.Dx()}
+		return image.Point{X: inputBounds.Min.X + margin, Y: bottom}
 	case BottomRight:
-		return image.Point{X: inputBounds.Dx(), Y: inputBounds.Dy()}
+		return image.Point{X: right, Y: bottom}
 	case Center:
-		return image.Point{X: inputBounds.Dx() / 2, Y: inputBounds.Dy() / 2}
+		return image.Point{
+			X: inputBounds.Min.X + (inputBounds.Dx()-markBounds.Dx())/2,
+			Y: inputBounds.Min.Y + (inputBounds.Dy()-markBounds.Dy())/2,
+		}
 	default:
-		return image.Point{}
+		return image.Point{X: inputBounds.Min.X + margin, Y: inputBounds.Min.Y + margin}
 	}
 }
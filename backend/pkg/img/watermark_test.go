@@ -1 +1,69 @@
 package img
+
+import (
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCalculateLocation(t *testing.T) {
+	input := image.Rect(0, 0, 400, 300)
+	mark := image.Rect(0, 0, 100, 50)
+	assert.Equal(t, image.Point{X: 10, Y: 10}, calculateLocation(TopLeft, input, mark, 10))
+	assert.Equal(t, image.Point{X: 290, Y: 10}, calculateLocation(TopRight, input, mark, 10))
+	assert.Equal(t, image.Point{X: 10, Y: 240}, calculateLocation(BottomLeft, input, mark, 10))
+	assert.Equal(t, image.Point{X: 290, Y: 240}, calculateLocation(BottomRight, input, mark, 10))
+	assert.Equal(t, image.Point{X: 150, Y: 125}, calculateLocation(Center, input, mark, 10))
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, err := ParseHexColor("#fff")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, c)
+	c, err = ParseHexColor("#11223380")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0x80}, c)
+	_, err = ParseHexColor("#12")
+	assert.Error(t, err)
+}
+
+func TestWatermarkText(t *testing.T) {
+	src := imaging.New(400, 300, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	watermark := &Watermark{src: src}
+	output, err := watermark.Draw(&WatermarkOptions{Text: "fusion", FontSize: 32, Color: color.Black, Margin: 10, Location: BottomRight})
+	assert.NoError(t, err)
+	assert.Equal(t, src.Bounds(), output.Bounds())
+	assert.False(t, hasDark(output, image.Rect(0, 0, 200, 150)))
+	assert.True(t, hasDark(output, image.Rect(200, 150, 400, 300)))
+}
+
+func TestWatermarkImage(t *testing.T) {
+	src := imaging.New(400, 300, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	logo := imaging.New(100, 100, color.NRGBA{A: 255})
+	watermark := &Watermark{src: src}
+
+	output, err := watermark.Draw(&WatermarkOptions{Image: logo, ImageRatio: 0.1, Location: TopLeft})
+	assert.NoError(t, err)
+	assert.True(t, hasDark(output, image.Rect(0, 0, 40, 40)))
+	assert.False(t, hasDark(output, image.Rect(41, 41, 400, 300)))
+
+	tiled, err := watermark.Draw(&WatermarkOptions{Image: logo, ImageRatio: 0.1, Margin: 20, Tiled: true})
+	assert.NoError(t, err)
+	assert.True(t, hasDark(tiled, image.Rect(360, 240, 400, 300)))
+
+	_, err = watermark.Draw(&WatermarkOptions{})
+	assert.Error(t, err)
+}
+
+func hasDark(img *image.NRGBA, rect image.Rectangle) bool {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if img.NRGBAAt(x, y).R < 128 {
+				return true
+			}
+		}
+	}
+	return false
+}
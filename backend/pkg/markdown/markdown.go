@@ -0,0 +1,138 @@
+package markdown
+
+import (
+	"bytes"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	stdhtml "html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// MoreMarker content before it on its own line is the excerpt
+const MoreMarker = "<!-- more -->"
+
+var (
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+			extension.Strikethrough,
+			extension.Linkify,
+			extension.TaskList,
+			extension.Footnote,
+			highlighting.NewHighlighting(
+				highlighting.WithGuessLanguage(false),
+				highlighting.WithFormatOptions(html.WithClasses(true)),
+			),
+			Math,
+		),
+		goldmark.WithParserOptions(
+			parser.WithAttribute(),
+			parser.WithASTTransformers(util.Prioritized(&headingTransformer{}, 100)),
+		),
+		// raw html of content is allowed, output is sanitized at last
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+
+	policy = newPolicy()
+
+	moreMarkerRegexp = regexp.MustCompile(`(?i)^<!--\s*more\s*-->$`)
+	htmlTagRegexp    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Rendered markdown rendered to sanitized html
+type Rendered struct {
+	Html string     `json:"html"`
+	Toc  []*TocItem `json:"toc"`
+	// Excerpt rendered content before MoreMarker, empty if there is no marker
+	Excerpt string `json:"excerpt"`
+}
+
+// Render CommonMark with GFM tables, strikethrough, autolinks and task lists, footnotes, highlighted code blocks and
+// math passthrough to sanitized html. headings are given ids and collected as table of contents
+func Render(content string) (*Rendered, error) {
+	source := []byte(content)
+	html, doc, pc, err := render(source)
+	if err != nil {
+		return nil, err
+	}
+	rendered := &Rendered{Html: html}
+	if toc, ok := pc.Get(tocKey).([]*TocItem); ok {
+		rendered.Toc = toc
+	}
+	if offset, ok := moreMarker(doc, source); ok {
+		if rendered.Excerpt, _, _, err = render(source[:offset]); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// Summary excerpt of rendered if it has, otherwise escaped plain text truncated to length
+func Summary(rendered *Rendered, length int) string {
+	if rendered.Excerpt != "" {
+		return rendered.Excerpt
+	}
+	text := []rune(PlainText(rendered.Html))
+	if length > 0 && len(text) > length {
+		return stdhtml.EscapeString(string(text[:length])) + "..."
+	}
+	return stdhtml.EscapeString(string(text))
+}
+
+// PlainText of html with tags removed and whitespaces collapsed
+func PlainText(html string) string {
+	return strings.Join(strings.Fields(stdhtml.UnescapeString(htmlTagRegexp.ReplaceAllString(html, " "))), " ")
+}
+
+// HighlightCSS write css of chroma style for highlighted code blocks, like 'github' or 'monokai'
+func HighlightCSS(w io.Writer, style string) error {
+	return html.New(html.WithClasses(true)).WriteCSS(w, styles.Get(style))
+}
+
+func render(source []byte) (string, ast.Node, parser.Context, error) {
+	pc := parser.NewContext()
+	doc := markdown.Parser().Parse(text.NewReader(source), parser.WithContext(pc))
+	buf := &bytes.Buffer{}
+	if err := markdown.Renderer().Render(buf, source, doc); err != nil {
+		return "", nil, nil, err
+	}
+	return policy.Sanitize(buf.String()), doc, pc, nil
+}
+
+// moreMarker offset of MoreMarker that is a top level html block
+func moreMarker(doc ast.Node, source []byte) (int, bool) {
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		block, ok := n.(*ast.HTMLBlock)
+		if !ok || block.Lines().Len() != 1 {
+			continue
+		}
+		line := block.Lines().At(0)
+		if moreMarkerRegexp.Match(bytes.TrimSpace(line.Value(source))) {
+			return line.Start, true
+		}
+	}
+	return 0, false
+}
+
+// newPolicy user generated content policy, with classes of highlighted code and math, heading and footnote ids and
+// task list checkbox allowed
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\- ]+$`)).Globally()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_:\-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "sup", "li")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-[a-z]+$`)).OnElements("a", "div", "section")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
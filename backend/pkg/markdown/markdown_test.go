@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	a := assert.New(t)
+	rendered, err := Render("# 你好 世界\n\n## Usage {#custom}\n\n### Install\n\n## Usage\n\n```go\nfunc main() {}\n```\n\n- [x] done\n\n| a | b |\n|:-|-:|\n| 1 | 2 |\n\n~~old~~ https://example.com\n")
+	a.Nil(err)
+	a.Contains(rendered.Html, `<h1 id="你好-世界">你好 世界</h1>`)
+	a.Contains(rendered.Html, `<h2 id="custom">Usage</h2>`)
+	a.Contains(rendered.Html, `<h2 id="usage">Usage</h2>`)
+	a.Contains(rendered.Html, `<pre class="chroma"><code>`)
+	a.Contains(rendered.Html, `<span class="kd">func</span>`)
+	a.Contains(rendered.Html, `<input checked="" disabled="" type="checkbox"> done`)
+	a.Contains(rendered.Html, `<th align="left">a</th>`)
+	a.Contains(rendered.Html, `<del>old</del>`)
+	a.Contains(rendered.Html, `<a href="https://example.com" rel="nofollow">https://example.com</a>`)
+	a.Empty(rendered.Excerpt)
+
+	a.Len(rendered.Toc, 1)
+	a.Equal("你好-世界", rendered.Toc[0].Id)
+	a.Len(rendered.Toc[0].Children, 2)
+	a.Equal(&TocItem{Level: 2, Id: "custom", Title: "Usage", Children: []*TocItem{{Level: 3, Id: "install", Title: "Install"}}}, rendered.Toc[0].Children[0])
+	a.Equal("usage", rendered.Toc[0].Children[1].Id)
+}
+
+func TestRender_Sanitize(t *testing.T) {
+	a := assert.New(t)
+	rendered, err := Render("<script>alert(1)</script>\n\n<a href=\"javascript:alert(1)\" onclick=\"alert(1)\">link</a> <b onmouseover=\"alert(1)\">bold</b>\n")
+	a.Nil(err)
+	a.NotContains(rendered.Html, "script")
+	a.NotContains(rendered.Html, "javascript")
+	a.NotContains(rendered.Html, "onclick")
+	a.NotContains(rendered.Html, "onmouseover")
+	a.Contains(rendered.Html, "<b>bold</b>")
+}
+
+func TestRender_Footnote(t *testing.T) {
+	a := assert.New(t)
+	rendered, err := Render("text[^1]\n\n[^1]: note\n")
+	a.Nil(err)
+	a.Contains(rendered.Html, `<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref"`)
+	a.Contains(rendered.Html, `<li id="fn:1">`)
+}
+
+func TestRender_Math(t *testing.T) {
+	a := assert.New(t)
+	rendered, err := Render("inline $a_1 * b_2$ costs $5 and $10, display $$x^2$$\n\n$$\n\\frac{a}{b} < c\n$$\n\n$$ e = mc^2 $$\n")
+	a.Nil(err)
+	a.Contains(rendered.Html, `<span class="math math-inline">\(a_1 * b_2\)</span> costs $5 and $10, display <span class="math math-display">\[x^2\]</span>`)
+	a.Contains(rendered.Html, `<div class="math math-display">\[\frac{a}{b} &lt; c`+"\n"+`\]</div>`)
+	a.Contains(rendered.Html, `<div class="math math-display">\[e = mc^2\]</div>`)
+}
+
+func TestRender_Excerpt(t *testing.T) {
+	a := assert.New(t)
+	rendered, err := Render("intro **bold**\n\n<!-- more -->\n\nrest\n\n```html\n<!-- more -->\n```\n")
+	a.Nil(err)
+	a.Equal("<p>intro <strong>bold</strong></p>\n", rendered.Excerpt)
+	a.Contains(rendered.Html, "rest")
+	a.Equal(rendered.Excerpt, Summary(rendered, 3))
+
+	rendered, err = Render("# Title\n\nsome &lt;plain&gt; text")
+	a.Nil(err)
+	a.Empty(rendered.Excerpt)
+	a.Equal("Title some &lt;plain&gt; text", Summary(rendered, 0))
+	a.Equal("Title some...", Summary(rendered, 10))
+}
@@ -0,0 +1,206 @@
+package markdown
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// math passthrough keeps tex of '$...$', '$$...$$' and '$$' fenced blocks untouched by markdown, and renders it
+// with \( \) and \[ \] delimiters that KaTeX or MathJax renders in browser
+
+var (
+	KindMathInline = ast.NewNodeKind("MathInline")
+	KindMathBlock  = ast.NewNodeKind("MathBlock")
+)
+
+// MathInline '$tex$' or '$$tex$$' in paragraph
+type MathInline struct {
+	ast.BaseInline
+	Display bool
+}
+
+func (n *MathInline) Kind() ast.NodeKind {
+	return KindMathInline
+}
+
+func (n *MathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Display": boolString(n.Display)}, nil)
+}
+
+// MathBlock tex between '$$' lines
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool
+}
+
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse inline math on the same line. like pandoc, opening '$' must not be followed by space and closing '$' must not
+// be preceded by space nor followed by digit, so that prices like '$5 and $10' stay text. closing of single '$' must
+// not be part of '$$' either
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	opener := 1
+	if len(line) > 1 && line[1] == '$' {
+		opener = 2
+	}
+	if len(line) <= opener || util.IsSpace(line[opener]) {
+		return nil
+	}
+	for i := opener + 1; i < len(line); i++ {
+		switch {
+		case line[i] == '\\':
+			i++
+		case line[i] != '$':
+		case opener == 2:
+			if i+1 < len(line) && line[i+1] == '$' {
+				return p.node(block, segment, opener, i)
+			}
+		case !util.IsSpace(line[i-1]) && line[i-1] != '$' && (i+1 >= len(line) || !isDigit(line[i+1]) && line[i+1] != '$'):
+			return p.node(block, segment, opener, i)
+		}
+	}
+	return nil
+}
+
+func (p *mathInlineParser) node(block text.Reader, segment text.Segment, opener int, closer int) ast.Node {
+	node := &MathInline{Display: opener == 2}
+	node.AppendChild(node, ast.NewRawTextSegment(text.NewSegment(segment.Start+opener, segment.Start+closer)))
+	block.Advance(closer + opener)
+	return node
+}
+
+type mathBlockParser struct{}
+
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	node := &MathBlock{}
+	rest := bytes.TrimSpace(line[pos+2:])
+	if len(rest) > 0 {
+		// single line block '$$tex$$'
+		if !bytes.HasSuffix(rest, []byte("$$")) || len(rest) < 3 {
+			return nil, parser.NoChildren
+		}
+		start := segment.Start + pos + 2 + util.TrimLeftSpaceLength(line[pos+2:])
+		node.Lines().Append(text.NewSegment(start, start+len(util.TrimRightSpace(rest[:len(rest)-2]))))
+		node.closed = true
+	}
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	block := node.(*MathBlock)
+	if block.closed {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	if bytes.Equal(bytes.TrimSpace(line), []byte("$$")) {
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+}
+
+func (p *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type mathRenderer struct{}
+
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMathInline, r.renderInline)
+	reg.Register(KindMathBlock, r.renderBlock)
+}
+
+func (r *mathRenderer) renderInline(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	open, close := `<span class="math math-inline">\(`, `\)</span>`
+	if n.(*MathInline).Display {
+		open, close = `<span class="math math-display">\[`, `\]</span>`
+	}
+	_, _ = w.WriteString(open)
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		_, _ = w.Write(util.EscapeHTML(c.(*ast.Text).Segment.Value(source)))
+	}
+	_, _ = w.WriteString(close)
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) renderBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	_, _ = w.WriteString(`<div class="math math-display">\[`)
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		_, _ = w.Write(util.EscapeHTML(segment.Value(source)))
+	}
+	_, _ = w.WriteString("\\]</div>\n")
+	return ast.WalkContinue, nil
+}
+
+type mathExtension struct{}
+
+// Math passthrough extension
+var Math goldmark.Extender = &mathExtension{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 90)),
+		parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 90)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&mathRenderer{}, 500)))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,120 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TocItem heading of table of contents, headings of lower level are nested in Children
+type TocItem struct {
+	Level    int        `json:"level"`
+	Id       string     `json:"id"`
+	Title    string     `json:"title"`
+	Children []*TocItem `json:"children,omitempty"`
+}
+
+var tocKey = parser.NewContextKey()
+
+// headingTransformer give headings unique id of their text and collect table of contents. letters of any language
+// are kept, so chinese headings have readable anchors. id specified by '{#id}' attribute is respected
+type headingTransformer struct{}
+
+func (t *headingTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	used := make(map[string]bool)
+	var headings []*ast.Heading
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering {
+			if id, ok := heading.AttributeString("id"); ok {
+				if value, ok := id.([]byte); ok {
+					used[string(value)] = true
+				}
+			}
+			headings = append(headings, heading)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var toc []*TocItem
+	var stack []*TocItem
+	for _, heading := range headings {
+		title := plainText(heading, source)
+		var id string
+		if value, ok := heading.AttributeString("id"); ok {
+			id = string(value.([]byte))
+		} else {
+			id = uniqueId(slug(title), used)
+			heading.SetAttributeString("id", []byte(id))
+		}
+		item := &TocItem{Level: heading.Level, Id: id, Title: title}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= item.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			toc = append(toc, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, item)
+		}
+		stack = append(stack, item)
+	}
+	pc.Set(tocKey, toc)
+}
+
+// plainText of inline node and its children
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// slug lower case words joined by '-'
+func slug(title string) string {
+	var b strings.Builder
+	separate := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			if separate && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			separate = false
+			b.WriteRune(r)
+		} else {
+			separate = true
+		}
+	}
+	if b.Len() == 0 {
+		return "heading"
+	}
+	return b.String()
+}
+
+func uniqueId(id string, used map[string]bool) string {
+	unique := id
+	for i := 1; used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
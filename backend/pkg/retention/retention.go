@@ -0,0 +1,58 @@
+package retention
+
+import (
+	"sort"
+	"time"
+)
+
+// Policy keep-last-N and keep-daily/weekly retention, zero value means not limited by the rule
+type Policy struct {
+	KeepLast   int
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// Unlimited reports whether policy don't remove anything
+func (p Policy) Unlimited() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0
+}
+
+// Keep returns which times should be kept, index same as param times.
+// time kept if any rule match: the newest KeepLast times, the newest time of each latest KeepDaily days,
+// the newest time of each latest KeepWeekly iso weeks.
+func (p Policy) Keep(times []time.Time) []bool {
+	keep := make([]bool, len(times))
+	if p.Unlimited() {
+		for i := range keep {
+			keep[i] = true
+		}
+		return keep
+	}
+	// newest first
+	indexes := make([]int, len(times))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool { return times[indexes[i]].After(times[indexes[j]]) })
+
+	days := make(map[string]bool)
+	weeks := make(map[[2]int]bool)
+	for n, i := range indexes {
+		t := times[i]
+		if n < p.KeepLast {
+			keep[i] = true
+		}
+		day := t.Format(time.DateOnly)
+		if !days[day] && len(days) < p.KeepDaily {
+			days[day] = true
+			keep[i] = true
+		}
+		year, week := t.ISOWeek()
+		weekKey := [2]int{year, week}
+		if !weeks[weekKey] && len(weeks) < p.KeepWeekly {
+			weeks[weekKey] = true
+			keep[i] = true
+		}
+	}
+	return keep
+}
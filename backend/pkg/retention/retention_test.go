@@ -0,0 +1,32 @@
+package retention
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Keep(t *testing.T) {
+	a := assert.New(t)
+	base := time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		base,                     // monday
+		base.Add(-1 * time.Hour), // same day
+		base.AddDate(0, 0, -1),   // sunday, previous week
+		base.AddDate(0, 0, -2),   // saturday
+		base.AddDate(0, 0, -8),   // two weeks ago
+		base.AddDate(0, 0, -15),  // three weeks ago
+	}
+
+	keep := Policy{KeepLast: 1}.Keep(times)
+	a.Equal([]bool{true, false, false, false, false, false}, keep)
+
+	keep = Policy{KeepDaily: 2}.Keep(times)
+	a.Equal([]bool{true, false, true, false, false, false}, keep)
+
+	keep = Policy{KeepWeekly: 3}.Keep(times)
+	a.Equal([]bool{true, false, true, false, true, false}, keep)
+
+	keep = Policy{}.Keep(times)
+	a.Equal([]bool{true, true, true, true, true, true}, keep)
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// Highlight escape text and wrap terms in <mark>. text longer than snippet runes is cut to the snippet that contains
+// most terms, snippet not positive means not cut. it reports false when text contains none of terms
+func Highlight(text string, terms map[string]bool, snippet int) (string, bool) {
+	var spans [][2]int
+	for _, token := range Tokenize(text) {
+		if !terms[token.Term] {
+			continue
+		}
+		// merge overlapped bigrams of CJK text
+		if n := len(spans); n > 0 && token.Start <= spans[n-1][1] {
+			if token.End > spans[n-1][1] {
+				spans[n-1][1] = token.End
+			}
+			continue
+		}
+		spans = append(spans, [2]int{token.Start, token.End})
+	}
+	if len(spans) == 0 {
+		return "", false
+	}
+
+	start, end := 0, len(text)
+	if snippet > 0 && utf8.RuneCountInString(text) > snippet {
+		start, end = window(text, spans, snippet)
+	}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	position := start
+	for _, span := range spans {
+		if span[1] <= start || span[0] >= end {
+			continue
+		}
+		spanStart, spanEnd := span[0], span[1]
+		if spanStart < position {
+			spanStart = position
+		}
+		if spanEnd > end {
+			spanEnd = end
+		}
+		b.WriteString(html.EscapeString(text[position:spanStart]))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(text[spanStart:spanEnd]))
+		b.WriteString(markClose)
+		position = spanEnd
+	}
+	b.WriteString(html.EscapeString(text[position:end]))
+	if end < len(text) {
+		b.WriteString("...")
+	}
+	return b.String(), true
+}
+
+// window byte range of snippet runes that covers most spans, it starts a little before the first covered span
+func window(text string, spans [][2]int, snippet int) (int, int) {
+	best, bestCount := 0, 0
+	for i := range spans {
+		count := 0
+		for j := i; j < len(spans) && utf8.RuneCountInString(text[spans[i][0]:spans[j][1]]) <= snippet; j++ {
+			count++
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	// leading context of a quarter of snippet
+	start := spans[best][0]
+	for context := snippet / 4; context > 0 && start > 0; context-- {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	end := start
+	for runes := 0; runes < snippet && end < len(text); runes++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	return start, end
+}
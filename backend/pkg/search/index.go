@@ -0,0 +1,212 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Hit document matched query
+type Hit struct {
+	Id    uint64
+	Score float64
+	// Highlights field text with matched terms wrapped in <mark>, long field is cut to snippet around matches
+	Highlights map[string]string
+}
+
+// Result hits of page and total matched documents
+type Result struct {
+	Total int
+	Hits  []*Hit
+}
+
+type document struct {
+	fields  map[string]string
+	lengths map[string]int
+	terms   []string
+}
+
+// Index in-memory inverted index of documents with named text fields, ranked by BM25F that weighs term frequency of
+// each field by its boost. it is safe for concurrent use
+type Index struct {
+	mu     sync.RWMutex
+	boosts map[string]float64
+	// snippet runes of fields longer than it
+	snippet  int
+	docs     map[uint64]*document
+	postings map[string]map[uint64]map[string]int
+	totalLen map[string]int
+}
+
+// NewIndex with default boost of each field, fields without boost are stored but not searched. field text longer than
+// snippet runes is cut to snippet when highlighted
+func NewIndex(boosts map[string]float64, snippet int) *Index {
+	return &Index{
+		boosts:   boosts,
+		snippet:  snippet,
+		docs:     make(map[uint64]*document),
+		postings: make(map[string]map[uint64]map[string]int),
+		totalLen: make(map[string]int),
+	}
+}
+
+// Put add or replace document of id
+func (i *Index) Put(id uint64, fields map[string]string) {
+	doc := &document{fields: fields, lengths: make(map[string]int, len(fields))}
+	frequencies := make(map[string]map[string]int)
+	for field, text := range fields {
+		if _, ok := i.boosts[field]; !ok {
+			continue
+		}
+		tokens := Tokenize(text)
+		doc.lengths[field] = len(tokens)
+		for _, token := range tokens {
+			if frequencies[token.Term] == nil {
+				frequencies[token.Term] = make(map[string]int)
+				doc.terms = append(doc.terms, token.Term)
+			}
+			frequencies[token.Term][field]++
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+	i.docs[id] = doc
+	for field, length := range doc.lengths {
+		i.totalLen[field] += length
+	}
+	for term, frequency := range frequencies {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[uint64]map[string]int)
+		}
+		i.postings[term][id] = frequency
+	}
+}
+
+// Remove document of id
+func (i *Index) Remove(id uint64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+}
+
+// Len documents in index
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.docs)
+}
+
+func (i *Index) remove(id uint64) {
+	doc, ok := i.docs[id]
+	if !ok {
+		return
+	}
+	for field, length := range doc.lengths {
+		i.totalLen[field] -= length
+	}
+	for _, term := range doc.terms {
+		delete(i.postings[term], id)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	delete(i.docs, id)
+}
+
+// Search documents that contain every term of query, ordered by score. boosts override default boosts of fields,
+// filter excludes documents when it returns false. hits from offset to limit are highlighted
+func (i *Index) Search(query string, boosts map[string]float64, filter func(id uint64) bool, offset int, limit int) *Result {
+	terms := QueryTerms(query)
+	result := &Result{Hits: make([]*Hit, 0)}
+	if len(terms) == 0 {
+		return result
+	}
+	fieldBoosts := make(map[string]float64, len(i.boosts))
+	for field, boost := range i.boosts {
+		fieldBoosts[field] = boost
+		if override, ok := boosts[field]; ok && override >= 0 {
+			fieldBoosts[field] = override
+		}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	// intersect from the rarest term
+	sort.Slice(terms, func(x, y int) bool {
+		return len(i.postings[terms[x]]) < len(i.postings[terms[y]])
+	})
+	candidates := i.postings[terms[0]]
+	avgLen := make(map[string]float64, len(fieldBoosts))
+	for field := range fieldBoosts {
+		if len(i.docs) > 0 {
+			avgLen[field] = float64(i.totalLen[field]) / float64(len(i.docs))
+		}
+	}
+	var hits []*Hit
+	for id := range candidates {
+		if filter != nil && !filter(id) {
+			continue
+		}
+		doc := i.docs[id]
+		score := 0.0
+		matched := true
+		for _, term := range terms {
+			frequency, ok := i.postings[term][id]
+			if !ok {
+				matched = false
+				break
+			}
+			tf := 0.0
+			for field, count := range frequency {
+				norm := 1.0
+				if avgLen[field] > 0 {
+					norm = 1 - b + b*float64(doc.lengths[field])/avgLen[field]
+				}
+				tf += fieldBoosts[field] * float64(count) / norm
+			}
+			df := float64(len(i.postings[term]))
+			idf := math.Log(1 + (float64(len(i.docs))-df+0.5)/(df+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1)
+		}
+		if matched && score > 0 {
+			hits = append(hits, &Hit{Id: id, Score: score})
+		}
+	}
+	sort.Slice(hits, func(x, y int) bool {
+		if hits[x].Score != hits[y].Score {
+			return hits[x].Score > hits[y].Score
+		}
+		return hits[x].Id > hits[y].Id
+	})
+
+	result.Total = len(hits)
+	if offset >= len(hits) {
+		return result
+	}
+	end := len(hits)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	matchedTerms := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		matchedTerms[term] = true
+	}
+	for _, hit := range hits[offset:end] {
+		hit.Highlights = make(map[string]string)
+		for field, text := range i.docs[hit.Id].fields {
+			if highlighted, ok := Highlight(text, matchedTerms, i.snippet); ok {
+				hit.Highlights[field] = highlighted
+			}
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	return result
+}
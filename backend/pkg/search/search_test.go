@@ -0,0 +1,80 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	a := assert.New(t)
+	terms := func(tokens []Token) []string {
+		result := make([]string, 0, len(tokens))
+		for _, token := range tokens {
+			result = append(result, token.Term)
+		}
+		return result
+	}
+	a.Equal([]string{"hello", "go_lang", "1", "20"}, terms(Tokenize("Hello, Go_Lang 1.20!")))
+	a.Equal([]string{"全", "全文", "文", "文检", "检", "检索", "索", "go"}, terms(Tokenize("全文检索Go")))
+	a.Equal(Token{Term: "文检", Start: 3, End: 9}, Tokenize("全文检索")[3])
+
+	a.Equal([]string{"全文", "文检", "检索", "go"}, QueryTerms("全文检索 go GO"))
+	a.Equal([]string{"检", "mongo"}, QueryTerms("检 Mongo"))
+	a.Empty(QueryTerms(" ,. "))
+}
+
+func TestIndex_Search(t *testing.T) {
+	a := assert.New(t)
+	index := NewIndex(map[string]float64{"title": 3, "content": 1}, 20)
+	index.Put(1, map[string]string{"title": "Mongo index", "content": "how to build index in mongo"})
+	index.Put(2, map[string]string{"title": "Go tips", "content": "mongo driver of go, and mongo index"})
+	index.Put(3, map[string]string{"title": "全文检索", "content": "基于倒排索引实现中文全文检索"})
+	index.Put(4, map[string]string{"title": "unrelated", "content": "nothing", "author": "mongo"})
+	a.Equal(4, index.Len())
+
+	result := index.Search("mongo index", nil, nil, 0, 10)
+	a.Equal(2, result.Total)
+	a.Equal(uint64(1), result.Hits[0].Id)
+	a.Equal(uint64(2), result.Hits[1].Id)
+	a.Equal("<mark>Mongo</mark> <mark>index</mark>", result.Hits[0].Highlights["title"])
+	a.NotContains(result.Hits[1].Highlights, "title")
+
+	// title boost is overridden, so that content frequency wins
+	result = index.Search("mongo index", map[string]float64{"title": 0}, nil, 0, 10)
+	a.Equal(uint64(2), result.Hits[0].Id)
+
+	result = index.Search("mongo", nil, func(id uint64) bool { return id != 1 }, 0, 10)
+	a.Equal(1, result.Total)
+	a.Equal(uint64(2), result.Hits[0].Id)
+
+	result = index.Search("检索", nil, nil, 0, 10)
+	a.Equal(1, result.Total)
+	a.Equal("全文<mark>检索</mark>", result.Hits[0].Highlights["title"])
+	a.Equal("基于倒排索引实现中文全文<mark>检索</mark>", result.Hits[0].Highlights["content"])
+
+	result = index.Search("index", nil, nil, 1, 1)
+	a.Equal(2, result.Total)
+	a.Len(result.Hits, 1)
+
+	index.Put(1, map[string]string{"title": "replaced", "content": "replaced"})
+	a.Equal(1, index.Search("mongo index", nil, nil, 0, 10).Total)
+	index.Remove(2)
+	a.Equal(0, index.Search("mongo index", nil, nil, 0, 10).Total)
+	a.Equal(3, index.Len())
+}
+
+func TestHighlight(t *testing.T) {
+	a := assert.New(t)
+	terms := map[string]bool{"go": true, "中文": true}
+
+	highlighted, ok := Highlight("<b>Go</b> & 中文", terms, 0)
+	a.True(ok)
+	a.Equal("&lt;b&gt;<mark>Go</mark>&lt;/b&gt; &amp; <mark>中文</mark>", highlighted)
+
+	_, ok = Highlight("nothing", terms, 0)
+	a.False(ok)
+
+	highlighted, ok = Highlight("a b c d e f g h i j k l m n o p q r s t u v w x y z go go end", terms, 16)
+	a.True(ok)
+	a.Equal("...y z <mark>go</mark> <mark>go</mark> end", highlighted)
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token term of text with byte offsets of it
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize text into lower case words of letters and digits. CJK characters have no spaces between words, so each
+// character and each two adjacent characters are terms, then both single character and longer queries match
+func Tokenize(text string) []Token {
+	return tokenize(text, false)
+}
+
+// QueryTerms distinct terms of query. CJK text longer than one character is split into bigrams only, as they are
+// more selective than single characters
+func QueryTerms(query string) []string {
+	tokens := tokenize(query, true)
+	terms := make([]string, 0, len(tokens))
+	seen := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		if !seen[token.Term] {
+			seen[token.Term] = true
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+func tokenize(text string, query bool) []Token {
+	var tokens []Token
+	wordStart := -1
+	// byte offsets of characters of current CJK run
+	var run []int
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			tokens = append(tokens, Token{Term: strings.ToLower(text[wordStart:end]), Start: wordStart, End: end})
+			wordStart = -1
+		}
+	}
+	flushRun := func(end int) {
+		if len(run) == 0 {
+			return
+		}
+		run = append(run, end)
+		chars := len(run) - 1
+		for i := 0; i < chars; i++ {
+			if !query || chars == 1 {
+				tokens = append(tokens, Token{Term: text[run[i]:run[i+1]], Start: run[i], End: run[i+1]})
+			}
+			if i+1 < chars {
+				tokens = append(tokens, Token{Term: text[run[i]:run[i+2]], Start: run[i], End: run[i+2]})
+			}
+		}
+		run = run[:0]
+	}
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord(i)
+			run = append(run, i)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			flushRun(i)
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushWord(i)
+			flushRun(i)
+		}
+	}
+	flushWord(len(text))
+	flushRun(len(text))
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,130 @@
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+// MaxURLs max urls of one sitemap file by sitemaps.org protocol
+const MaxURLs = 50000
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL entry of sitemap, zero LastMod is omitted
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	URLs    []entry  `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Sitemaps []entry  `xml:"sitemap"`
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap generated files of urls
+type Sitemap struct {
+	// Index sitemap index when urls are split, otherwise the only url set
+	Index []byte
+	// Parts url sets referenced by index, empty when urls are not split
+	Parts [][]byte
+}
+
+// Generate sitemap of urls. urls exceed maxURLs are split into parts, and index references each part by
+// partLoc of part number starting from 1. maxURLs not positive means MaxURLs
+func Generate(urls []URL, maxURLs int, partLoc func(number int) string) (*Sitemap, error) {
+	if maxURLs <= 0 || maxURLs > MaxURLs {
+		maxURLs = MaxURLs
+	}
+	if len(urls) <= maxURLs {
+		index, err := encode(urls)
+		if err != nil {
+			return nil, err
+		}
+		return &Sitemap{Index: index}, nil
+	}
+	sitemap := &Sitemap{}
+	index := sitemapIndex{Xmlns: namespace}
+	for start, number := 0, 1; start < len(urls); start, number = start+maxURLs, number+1 {
+		end := start + maxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+		part, err := encode(urls[start:end])
+		if err != nil {
+			return nil, err
+		}
+		sitemap.Parts = append(sitemap.Parts, part)
+		index.Sitemaps = append(index.Sitemaps, entry{Loc: partLoc(number), LastMod: lastMod(urls[start:end])})
+	}
+	var err error
+	sitemap.Index, err = marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	return sitemap, nil
+}
+
+// Part obtain url set by part number starting from 1
+func (s *Sitemap) Part(number int) ([]byte, bool) {
+	if number < 1 || number > len(s.Parts) {
+		return nil, false
+	}
+	return s.Parts[number-1], true
+}
+
+// PartLoc default part location like 'https://example.com/sitemap/sitemap-1.xml'
+func PartLoc(baseUrl string) func(number int) string {
+	return func(number int) string {
+		return baseUrl + "/sitemap/sitemap-" + strconv.Itoa(number) + ".xml"
+	}
+}
+
+func encode(urls []URL) ([]byte, error) {
+	set := urlSet{Xmlns: namespace, URLs: make([]entry, 0, len(urls))}
+	for _, url := range urls {
+		e := entry{Loc: url.Loc}
+		if !url.LastMod.IsZero() {
+			e.LastMod = url.LastMod.UTC().Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, e)
+	}
+	return marshal(set)
+}
+
+// lastMod the latest modification of urls
+func lastMod(urls []URL) string {
+	var latest time.Time
+	for _, url := range urls {
+		if url.LastMod.After(latest) {
+			latest = url.LastMod
+		}
+	}
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.UTC().Format(time.RFC3339)
+}
+
+func marshal(v any) ([]byte, error) {
+	buf := bytes.NewBufferString(xml.Header)
+	encoder := xml.NewEncoder(buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+package sitemap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	a := assert.New(t)
+	modified := time.Date(2024, 3, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	urls := []URL{
+		{Loc: "https://example.com/"},
+		{Loc: "https://example.com/post/a&b", LastMod: modified},
+	}
+	sitemap, err := Generate(urls, 0, PartLoc("https://example.com"))
+	a.Nil(err)
+	a.Empty(sitemap.Parts)
+	index := string(sitemap.Index)
+	a.Contains(index, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	a.Contains(index, "<loc>https://example.com/post/a&amp;b</loc>")
+	a.Contains(index, "<lastmod>2024-03-01T00:00:00Z</lastmod>")
+	a.Equal(1, strings.Count(index, "<lastmod>"))
+}
+
+func TestGenerate_Split(t *testing.T) {
+	a := assert.New(t)
+	modified := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	urls := make([]URL, 0, 5)
+	for i := 0; i < 5; i++ {
+		urls = append(urls, URL{Loc: "https://example.com/page/" + strconv.Itoa(i), LastMod: modified.AddDate(0, 0, i)})
+	}
+	sitemap, err := Generate(urls, 2, PartLoc("https://example.com"))
+	a.Nil(err)
+	a.Len(sitemap.Parts, 3)
+	index := string(sitemap.Index)
+	a.Contains(index, "<sitemapindex")
+	a.Contains(index, "<loc>https://example.com/sitemap/sitemap-3.xml</loc>")
+	a.Contains(index, "<lastmod>2024-03-02T00:00:00Z</lastmod>")
+	a.Contains(index, "<lastmod>2024-03-05T00:00:00Z</lastmod>")
+
+	part, ok := sitemap.Part(3)
+	a.True(ok)
+	a.Contains(string(part), "https://example.com/page/4")
+	a.NotContains(string(part), "https://example.com/page/3")
+	_, ok = sitemap.Part(4)
+	a.False(ok)
+}
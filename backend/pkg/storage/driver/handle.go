@@ -3,6 +3,8 @@ package driver
 import (
 	"cc.allio/fusion/pkg/storage"
 	"context"
+	"errors"
+	"io/fs"
 )
 
 // Handler 存储策略适配器
@@ -21,6 +23,9 @@ type Handler interface {
 	// Download get file content
 	Download(ctx context.Context, path string) (storage.RSCloser, error)
 
+	// Content obtain file content response, object store response signed url that should be redirected
+	Content(ctx context.Context, path string) (*storage.ContentResponse, error)
+
 	// Thumb obtain thumb about file
 	Thumb(ctx context.Context, file *storage.FileHeader) (*storage.ContentResponse, error)
 
@@ -30,3 +35,49 @@ type Handler interface {
 	// List specific path file list, recursive is true then recursion dir obtain file
 	List(ctx context.Context, path string, recursive bool) ([]storage.Object, error)
 }
+
+// MultipartHandler stage parts of large file then assemble them, object store maps it onto multipart upload
+type MultipartHandler interface {
+
+	// InitMultipart start multipart upload to dest path, returns upload id
+	InitMultipart(ctx context.Context, path string) (string, error)
+
+	// UploadPart upload part by number that starts from 1, returns etag of part.
+	// object store requires every part except the last one not less than its min part size
+	UploadPart(ctx context.Context, path string, uploadId string, number int, part []byte) (string, error)
+
+	// CompleteMultipart assemble parts that sorted by number to dest path
+	CompleteMultipart(ctx context.Context, path string, uploadId string, parts []storage.Part) error
+
+	// AbortMultipart discard staged parts
+	AbortMultipart(ctx context.Context, path string, uploadId string) error
+}
+
+// DirHandler manage directories, object store simulates directory by zero-byte object whose key ends with slash
+type DirHandler interface {
+
+	// Mkdir create directory and its parents
+	Mkdir(ctx context.Context, path string) error
+
+	// Move file from src path to dest path, dest file is overwritten
+	Move(ctx context.Context, src string, dest string) error
+
+	// RemoveDir remove directory and all files under it
+	RemoveDir(ctx context.Context, path string) error
+}
+
+// PermanentError error that will not be recovered by retrying the same request
+type PermanentError interface {
+	error
+	Permanent() bool
+}
+
+// IsPermanent whether err will not be recovered by retrying, like multipart upload has gone or part is invalid.
+// network failure and server error are temporary
+func IsPermanent(err error) bool {
+	var permanent PermanentError
+	if errors.As(err, &permanent) {
+		return permanent.Permanent()
+	}
+	return errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,36 @@
+package driver_test
+
+import (
+	"cc.allio/fusion/pkg/storage/driver"
+	"cc.allio/fusion/pkg/storage/driver/objectstore"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"net/http"
+	"testing"
+)
+
+func TestIsPermanent(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"no such upload", &objectstore.Error{StatusCode: http.StatusNotFound, Code: "NoSuchUpload"}, true},
+		{"invalid part", fmt.Errorf("complete: %w", &objectstore.Error{StatusCode: http.StatusBadRequest, Code: "InvalidPart"}), true},
+		{"server error", &objectstore.Error{StatusCode: http.StatusServiceUnavailable, Code: "SlowDown"}, false},
+		{"error body with ok", &objectstore.Error{StatusCode: http.StatusOK, Code: "InternalError"}, false},
+		{"throttled", &objectstore.Error{StatusCode: http.StatusTooManyRequests}, false},
+		{"timeout", &objectstore.Error{StatusCode: http.StatusRequestTimeout}, false},
+		{"staging removed", fs.ErrNotExist, true},
+		{"canceled", context.Canceled, false},
+		{"network", errors.New("connection reset by peer"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.permanent, driver.IsPermanent(c.err))
+		})
+	}
+}
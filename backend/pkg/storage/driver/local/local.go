@@ -6,12 +6,15 @@ import (
 	"cc.allio/fusion/pkg/storage/driver"
 	"cc.allio/fusion/pkg/util"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"github.com/google/uuid"
 	"golang.org/x/exp/slog"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -36,15 +39,9 @@ func (l *Driver) Sign(ctx context.Context, file *storage.FileHeader) (string, er
 }
 
 func (l *Driver) Upload(ctx context.Context, file *storage.FileStream) error {
-	// obtain specifies save path about file system absolute path
-	dest := util.AbsolutePath(file.SavePath, l.Policy.BaseDir)
+	dest := l.resolve(file.SavePath)
 	// create dir if not exist
 	dir := filepath.Dir(dest)
-	if strings.Index(dir, "/") == 0 {
-		// make relevant current dir
-		dir = dir[1:]
-		dest = dest[1:]
-	}
 	if !util.ExistFile(dir) {
 		err := os.MkdirAll(dir, perm)
 		if err != nil {
@@ -53,7 +50,7 @@ func (l *Driver) Upload(ctx context.Context, file *storage.FileStream) error {
 		}
 	}
 
-	openMode := os.O_CREATE | os.O_RDWR
+	openMode := os.O_CREATE | os.O_RDWR | os.O_TRUNC
 	out, err := os.OpenFile(dest, openMode, perm)
 	if err != nil {
 		slog.Error("Failed to Open file. ", "err", err, "dir", dir)
@@ -69,7 +66,7 @@ func (l *Driver) Remove(ctx context.Context, files []string) ([]string, error) {
 	failedFiles := make([]string, 0, len(files))
 	var err error
 	for _, file := range files {
-		filePath := util.AbsolutePath(file, l.Policy.BaseDir)
+		filePath := l.resolve(file)
 		if util.ExistFile(filePath) {
 			err = os.Remove(filePath)
 			if err != nil {
@@ -82,8 +79,8 @@ func (l *Driver) Remove(ctx context.Context, files []string) ([]string, error) {
 }
 
 func (l *Driver) Download(ctx context.Context, path string) (storage.RSCloser, error) {
-	dest := util.AbsolutePath(path, l.Policy.BaseDir)
-	file, err := os.OpenFile(dest, os.O_RDWR, perm)
+	dest := l.resolve(path)
+	file, err := os.Open(dest)
 	if err != nil {
 		slog.Error("Failed to Open file. ", "err", err, "dest", dest)
 		return nil, err
@@ -91,21 +88,38 @@ func (l *Driver) Download(ctx context.Context, path string) (storage.RSCloser, e
 	return file, err
 }
 
+func (l *Driver) Content(ctx context.Context, path string) (*storage.ContentResponse, error) {
+	file, err := os.Open(l.resolve(path))
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+	return &storage.ContentResponse{Redirect: false, Content: file, LastModified: info.ModTime()}, nil
+}
+
 func (l *Driver) Thumb(ctx context.Context, file *storage.FileHeader) (*storage.ContentResponse, error) {
 	thumbFile := file.ThumbFile()
 
-	rsCloser, err := l.Download(ctx, thumbFile)
+	content, err := l.Content(ctx, thumbFile)
 	if err != nil {
 		slog.Error("Failed to Download Thumb file. ", "err", err, "thumb", thumbFile)
 		return nil, err
 	}
-	return &storage.ContentResponse{Redirect: false, Content: rsCloser}, nil
+	return content, nil
 }
 
 func (l *Driver) List(ctx context.Context, path string, recursive bool) ([]storage.Object, error) {
 	var obj []storage.Object
 
-	root := util.AbsolutePath(filepath.FromSlash(path), l.Policy.BaseDir)
+	root := l.resolve(path)
 
 	err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
 		// skip root path
@@ -140,3 +154,96 @@ func (l *Driver) List(ctx context.Context, path string, recursive bool) ([]stora
 	})
 	return obj, err
 }
+
+func (l *Driver) Mkdir(ctx context.Context, path string) error {
+	return os.MkdirAll(l.resolve(path), perm)
+}
+
+func (l *Driver) Move(ctx context.Context, src string, dest string) error {
+	target := l.resolve(dest)
+	if err := os.MkdirAll(filepath.Dir(target), perm); err != nil {
+		return err
+	}
+	return os.Rename(l.resolve(src), target)
+}
+
+func (l *Driver) RemoveDir(ctx context.Context, path string) error {
+	return os.RemoveAll(l.resolve(path))
+}
+
+// multipartDir staging dir of parts that not yet completed
+const multipartDir = "/.multipart"
+
+func (l *Driver) InitMultipart(ctx context.Context, path string) (string, error) {
+	uploadId := uuid.NewString()
+	if err := os.MkdirAll(l.resolve(multipartDir+"/"+uploadId), perm); err != nil {
+		return "", err
+	}
+	return uploadId, nil
+}
+
+func (l *Driver) UploadPart(ctx context.Context, path string, uploadId string, number int, part []byte) (string, error) {
+	staging, err := l.staging(uploadId)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(staging, strconv.Itoa(number)), part, perm); err != nil {
+		return "", err
+	}
+	sum := md5.Sum(part)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (l *Driver) CompleteMultipart(ctx context.Context, path string, uploadId string, parts []storage.Part) error {
+	staging, err := l.staging(uploadId)
+	if err != nil {
+		return err
+	}
+	dest := l.resolve(path)
+	if err := os.MkdirAll(filepath.Dir(dest), perm); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_RDWR|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	for _, part := range parts {
+		if err := appendFile(out, filepath.Join(staging, strconv.Itoa(part.Number))); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(staging)
+}
+
+func (l *Driver) AbortMultipart(ctx context.Context, path string, uploadId string) error {
+	staging, err := l.staging(uploadId)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(staging)
+}
+
+// staging resolve staging dir of upload id, upload id must not escape from it
+func (l *Driver) staging(uploadId string) (string, error) {
+	if _, err := uuid.Parse(uploadId); err != nil {
+		return "", fs.ErrNotExist
+	}
+	return l.resolve(multipartDir + "/" + uploadId), nil
+}
+
+func appendFile(out io.Writer, name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// resolve storage path (like '/static/img/a.png') to file system path relevant BaseDir,
+// upload, download, remove and list must be resolved same way
+func (l *Driver) resolve(path string) string {
+	return filepath.Join(l.Policy.BaseDir, filepath.FromSlash(strings.TrimPrefix(path, "/")))
+}
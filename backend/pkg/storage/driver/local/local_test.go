@@ -86,3 +86,43 @@ func TestHandle_List(t *testing.T) {
 		asserts.Len(res, 7)
 	}
 }
+
+func TestHandle_Multipart(t *testing.T) {
+	ctx := context.Background()
+	asserts := assert.New(t)
+	d := &Driver{Policy: &storage.Policy{Mode: storage.LocalMode, BaseDir: t.TempDir()}}
+
+	uploadId, err := d.InitMultipart(ctx, "/fusion/a.bin")
+	asserts.NoError(err)
+	var parts []storage.Part
+	for i, content := range []string{"hello ", "multipart"} {
+		etag, err := d.UploadPart(ctx, "/fusion/a.bin", uploadId, i+1, []byte(content))
+		asserts.NoError(err)
+		parts = append(parts, storage.Part{Number: i + 1, ETag: etag, Size: int64(len(content))})
+	}
+	asserts.NoError(d.CompleteMultipart(ctx, "/fusion/a.bin", uploadId, parts))
+
+	content, err := os.ReadFile(d.resolve("/fusion/a.bin"))
+	asserts.NoError(err)
+	asserts.Equal("hello multipart", string(content))
+	asserts.NoDirExists(d.resolve(multipartDir + "/" + uploadId))
+
+	_, err = d.UploadPart(ctx, "/fusion/a.bin", "../../etc", 1, []byte("x"))
+	asserts.Error(err)
+}
+
+func TestHandle_Dir(t *testing.T) {
+	ctx := context.Background()
+	asserts := assert.New(t)
+	d := &Driver{Policy: &storage.Policy{Mode: storage.LocalMode, BaseDir: t.TempDir()}}
+
+	asserts.NoError(d.Mkdir(ctx, "/dir/empty"))
+	asserts.DirExists(d.resolve("/dir/empty"))
+	asserts.NoError(d.Upload(ctx, &storage.FileStream{SavePath: "/dir/a.txt", File: io.NopCloser(strings.NewReader("a"))}))
+	asserts.NoError(d.Move(ctx, "/dir/a.txt", "/dir/moved/c.txt"))
+	asserts.NoFileExists(d.resolve("/dir/a.txt"))
+	asserts.FileExists(d.resolve("/dir/moved/c.txt"))
+
+	asserts.NoError(d.RemoveDir(ctx, "/dir"))
+	asserts.NoDirExists(d.resolve("/dir"))
+}
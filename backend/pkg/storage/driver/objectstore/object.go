@@ -0,0 +1,71 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// object lazy read object content, each seek will re-request by range header
+type object struct {
+	ctx    context.Context
+	driver *Driver
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (o *object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		header := http.Header{"Range": {"bytes=" + strconv.FormatInt(o.offset, 10) + "-"}}
+		resp, err := o.driver.do(o.ctx, http.MethodGet, o.key, nil, header, nil)
+		if err != nil {
+			return 0, err
+		}
+		o.body = resp.Body
+	}
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *object) Seek(offset int64, whence int) (int64, error) {
+	var position int64
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = o.offset + offset
+	case io.SeekEnd:
+		position = o.size + offset
+	default:
+		return 0, errors.New(o.driver.Dialect.Name + ": invalid whence")
+	}
+	if position < 0 {
+		return 0, errors.New(o.driver.Dialect.Name + ": negative position")
+	}
+	if position != o.offset {
+		o.closeBody()
+		o.offset = position
+	}
+	return position, nil
+}
+
+func (o *object) Close() error {
+	return o.closeBody()
+}
+
+func (o *object) closeBody() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
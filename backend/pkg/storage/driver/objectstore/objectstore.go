@@ -0,0 +1,456 @@
+package objectstore
+
+import (
+	"bytes"
+	"cc.allio/fusion/internal/token"
+	"cc.allio/fusion/pkg/storage"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slog"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PartSize multipart upload part size, s3 requires at least 5MB and oss 100KB except last part
+	PartSize = 8 << 20
+	// SignExpires expiration of signed url that response by Content and Thumb
+	SignExpires = time.Hour
+)
+
+// Signer sign request and address bucket, each object store implements it by its own signature version
+type Signer interface {
+	// ObjectURL url of object key, empty key is bucket itself
+	ObjectURL(key string, query url.Values) *url.URL
+	// SignRequest add authorization into request of object key
+	SignRequest(req *http.Request, key string, body []byte)
+	// Presign url of object key that can be got without credential until expires
+	Presign(key string, expires time.Duration) string
+}
+
+// Dialect names of rest api that differ between object stores
+type Dialect struct {
+	// Name prefix of error and log, like 's3'
+	Name string
+	// CopySourceHeader header of copy object source, like 'X-Amz-Copy-Source'
+	CopySourceHeader string
+	// RequestIdHeader header of request id that attached to error
+	RequestIdHeader string
+	// ListV2 list objects by ListObjectsV2 with continuation token, otherwise by ListObjects with marker
+	ListV2 bool
+}
+
+// Driver object store rest api shared by s3 and oss, vendor driver embeds it, implements Init and provides Signer
+type Driver struct {
+	Policy  *storage.Policy
+	Dialect Dialect
+	Signer  Signer
+	Client  *http.Client
+}
+
+func NewDriver(policy *storage.Policy, dialect Dialect) *Driver {
+	return &Driver{Policy: policy, Dialect: dialect, Client: http.DefaultClient}
+}
+
+// Endpoint parse endpoint of policy, https is used when scheme is absent
+func (d *Driver) Endpoint() (*url.URL, error) {
+	if d.Policy.Endpoint == "" || d.Policy.Bucket == "" {
+		return nil, errors.New(d.Dialect.Name + " storage endpoint and bucket must not be empty")
+	}
+	endpoint := d.Policy.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	return url.Parse(endpoint)
+}
+
+func (d *Driver) Sign(ctx context.Context, file *storage.FileHeader) (string, error) {
+	key := uuid.NewString()
+	return token.Encrypt(key), nil
+}
+
+// Upload put object directly when content less than one part, otherwise use multipart upload
+func (d *Driver) Upload(ctx context.Context, file *storage.FileStream) error {
+	key := d.key(file.SavePath)
+	part := make([]byte, PartSize)
+	n, err := io.ReadFull(file, part)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if n < PartSize {
+		resp, err := d.do(ctx, http.MethodPut, key, nil, nil, part[:n])
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	return d.multipartUpload(ctx, key, part, file)
+}
+
+func (d *Driver) multipartUpload(ctx context.Context, key string, first []byte, r io.Reader) error {
+	uploadId, err := d.initMultipart(ctx, key)
+	if err != nil {
+		return err
+	}
+	var parts []storage.Part
+	part := first
+	for number := 1; len(part) > 0; number++ {
+		etag, err := d.uploadPart(ctx, key, uploadId, number, part)
+		if err != nil {
+			d.abortMultipartUpload(ctx, key, uploadId)
+			return err
+		}
+		parts = append(parts, storage.Part{Number: number, ETag: etag, Size: int64(len(part))})
+
+		part = make([]byte, PartSize)
+		n, err := io.ReadFull(r, part)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			d.abortMultipartUpload(ctx, key, uploadId)
+			return err
+		}
+		part = part[:n]
+	}
+	if err := d.completeMultipart(ctx, key, uploadId, parts); err != nil {
+		d.abortMultipartUpload(ctx, key, uploadId)
+		return err
+	}
+	return nil
+}
+
+func (d *Driver) InitMultipart(ctx context.Context, path string) (string, error) {
+	return d.initMultipart(ctx, d.key(path))
+}
+
+func (d *Driver) UploadPart(ctx context.Context, path string, uploadId string, number int, part []byte) (string, error) {
+	return d.uploadPart(ctx, d.key(path), uploadId, number, part)
+}
+
+func (d *Driver) CompleteMultipart(ctx context.Context, path string, uploadId string, parts []storage.Part) error {
+	return d.completeMultipart(ctx, d.key(path), uploadId, parts)
+}
+
+func (d *Driver) AbortMultipart(ctx context.Context, path string, uploadId string) error {
+	resp, err := d.do(ctx, http.MethodDelete, d.key(path), url.Values{"uploadId": {uploadId}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (d *Driver) initMultipart(ctx context.Context, key string) (string, error) {
+	resp, err := d.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	initiate := &initiateMultipartUploadResult{}
+	if err := decodeXml(resp, initiate); err != nil {
+		return "", err
+	}
+	return initiate.UploadId, nil
+}
+
+func (d *Driver) uploadPart(ctx context.Context, key string, uploadId string, number int, part []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadId}}
+	resp, err := d.do(ctx, http.MethodPut, key, query, nil, part)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+func (d *Driver) completeMultipart(ctx context.Context, key string, uploadId string, parts []storage.Part) error {
+	complete := &completeMultipartUpload{}
+	for _, part := range parts {
+		complete.Parts = append(complete.Parts, completePart{PartNumber: part.Number, ETag: part.ETag})
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	resp, err := d.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadId}}, nil, body)
+	if err != nil {
+		return err
+	}
+	return d.checkResult(resp)
+}
+
+func (d *Driver) abortMultipartUpload(ctx context.Context, key string, uploadId string) {
+	resp, err := d.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadId}}, nil, nil)
+	if err != nil {
+		slog.Error("Failed to abort "+d.Dialect.Name+" multipart upload", "err", err, "key", key, "uploadId", uploadId)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (d *Driver) Remove(ctx context.Context, files []string) ([]string, error) {
+	failedFiles := make([]string, 0, len(files))
+	var err error
+	for _, file := range files {
+		resp, removeErr := d.do(ctx, http.MethodDelete, d.key(file), nil, nil, nil)
+		if removeErr != nil {
+			slog.Error("Failed to remove "+d.Dialect.Name+" object. ", "err", removeErr, "file", file)
+			failedFiles = append(failedFiles, file)
+			err = removeErr
+			continue
+		}
+		resp.Body.Close()
+	}
+	return failedFiles, err
+}
+
+// Download returns seekable object content, content is fetched by range request when read
+func (d *Driver) Download(ctx context.Context, path string) (storage.RSCloser, error) {
+	key := d.key(path)
+	resp, err := d.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &object{ctx: ctx, driver: d, key: key, size: resp.ContentLength}, nil
+}
+
+// Content redirect to signed url of file
+func (d *Driver) Content(ctx context.Context, path string) (*storage.ContentResponse, error) {
+	signed := d.Signer.Presign(d.key(path), SignExpires)
+	return &storage.ContentResponse{Redirect: true, URL: signed, MaxAge: int(SignExpires / time.Second)}, nil
+}
+
+// Thumb redirect to signed url of thumb file
+func (d *Driver) Thumb(ctx context.Context, file *storage.FileHeader) (*storage.ContentResponse, error) {
+	return d.Content(ctx, file.ThumbFile())
+}
+
+// List objects page by page, not recursive list contains common prefixes as dir
+func (d *Driver) List(ctx context.Context, p string, recursive bool) ([]storage.Object, error) {
+	prefix := d.key(p)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	query := url.Values{"prefix": {prefix}, "max-keys": {"1000"}}
+	if d.Dialect.ListV2 {
+		query.Set("list-type", "2")
+	}
+	if !recursive {
+		query.Set("delimiter", "/")
+	}
+	var obj []storage.Object
+	for {
+		resp, err := d.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		result := &listBucketResult{}
+		if err := decodeXml(resp, result); err != nil {
+			return nil, err
+		}
+		for _, commonPrefix := range result.CommonPrefixes {
+			rel := strings.TrimSuffix(strings.TrimPrefix(commonPrefix.Prefix, prefix), "/")
+			obj = append(obj, storage.Object{Name: path.Base(rel), RelativePath: rel, Source: commonPrefix.Prefix, IsDir: true})
+		}
+		for _, content := range result.Contents {
+			rel := strings.TrimPrefix(content.Key, prefix)
+			if rel == "" {
+				continue
+			}
+			// directory marker of nested directory
+			rel, isDir := strings.CutSuffix(rel, "/")
+			obj = append(obj, storage.Object{
+				Name:         path.Base(rel),
+				RelativePath: rel,
+				Source:       content.Key,
+				Size:         uint64(content.Size),
+				IsDir:        isDir,
+				LastModify:   content.LastModified,
+			})
+		}
+		next, param := result.NextMarker, "marker"
+		if d.Dialect.ListV2 {
+			next, param = result.NextContinuationToken, "continuation-token"
+		}
+		if !result.IsTruncated || next == "" {
+			return obj, nil
+		}
+		query.Set(param, next)
+	}
+}
+
+// Mkdir put zero-byte object whose key ends with slash as directory marker
+func (d *Driver) Mkdir(ctx context.Context, path string) error {
+	resp, err := d.do(ctx, http.MethodPut, d.key(path)+"/", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Move copy object inside bucket then delete source
+func (d *Driver) Move(ctx context.Context, src string, dest string) error {
+	header := http.Header{d.Dialect.CopySourceHeader: {"/" + d.Policy.Bucket + "/" + escapeKey(d.key(src))}}
+	resp, err := d.do(ctx, http.MethodPut, d.key(dest), nil, header, nil)
+	if err != nil {
+		return err
+	}
+	if err := d.checkResult(resp); err != nil {
+		return err
+	}
+	resp, err = d.do(ctx, http.MethodDelete, d.key(src), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// RemoveDir delete all objects under directory prefix and its marker
+func (d *Driver) RemoveDir(ctx context.Context, path string) error {
+	objects, err := d.List(ctx, path, true)
+	if err != nil {
+		return err
+	}
+	keys := []string{d.key(path) + "/"}
+	for _, object := range objects {
+		keys = append(keys, object.Source)
+	}
+	for _, key := range keys {
+		resp, err := d.do(ctx, http.MethodDelete, key, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// key convert storage path (like '/fusion/2023-01-01/a.png') to object key that under BaseDir
+func (d *Driver) key(p string) string {
+	return strings.TrimPrefix(path.Join("/", d.Policy.BaseDir, p), "/")
+}
+
+// escapeKey escape every segment of object key for copy source
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// do send signed request, response not 2xx will be converted to *Error
+func (d *Driver) do(ctx context.Context, method string, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.Signer.ObjectURL(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(body))
+	d.Signer.SignRequest(req, key, body)
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		return nil, d.parseError(resp)
+	}
+	return resp, nil
+}
+
+// checkResult copy object and complete multipart upload may response 200 with error body
+func (d *Driver) checkResult(resp *http.Response) error {
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	result := &Error{Dialect: d.Dialect.Name, StatusCode: resp.StatusCode, RequestId: resp.Header.Get(d.Dialect.RequestIdHeader)}
+	if len(content) > 0 {
+		if err := xml.Unmarshal(content, result); err != nil {
+			return err
+		}
+	}
+	if result.Code != "" {
+		return result
+	}
+	return nil
+}
+
+// Error object store error response
+type Error struct {
+	Dialect    string `xml:"-"`
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	RequestId  string `xml:"RequestId"`
+}
+
+func (e *Error) Error() string {
+	if e.RequestId == "" {
+		return fmt.Sprintf("%s: %d %s %s", e.Dialect, e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %d %s %s (request id: %s)", e.Dialect, e.StatusCode, e.Code, e.Message, e.RequestId)
+}
+
+// Permanent client error except timeout and throttling, like NoSuchUpload or InvalidPart
+func (e *Error) Permanent() bool {
+	return e.StatusCode >= http.StatusBadRequest && e.StatusCode < http.StatusInternalServerError &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
+func (d *Driver) parseError(resp *http.Response) error {
+	storeErr := &Error{Dialect: d.Dialect.Name, StatusCode: resp.StatusCode}
+	content, err := io.ReadAll(resp.Body)
+	if err == nil && len(content) > 0 {
+		_ = xml.Unmarshal(content, storeErr)
+	}
+	if storeErr.Code == "" {
+		storeErr.Code = http.StatusText(resp.StatusCode)
+	}
+	if storeErr.RequestId == "" {
+		storeErr.RequestId = resp.Header.Get(d.Dialect.RequestIdHeader)
+	}
+	return storeErr
+}
+
+func decodeXml(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []completePart `xml:"Part"`
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	NextMarker            string `xml:"NextMarker"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
@@ -0,0 +1,61 @@
+package oss
+
+import (
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/driver"
+	"cc.allio/fusion/pkg/storage/driver/objectstore"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var dialect = objectstore.Dialect{Name: "oss", CopySourceHeader: "X-Oss-Copy-Source", RequestIdHeader: "X-Oss-Request-Id"}
+
+// Driver aliyun oss driver, bucket addressed by virtual host like 'bucket.oss-cn-hangzhou.aliyuncs.com'
+type Driver struct {
+	*objectstore.Driver
+	endpoint *url.URL
+	signer   *signer
+}
+
+func NewOssDriver(policy *storage.Policy) driver.Handler {
+	d := &Driver{Driver: objectstore.NewDriver(policy, dialect)}
+	d.Signer = d
+	return d
+}
+
+func (d *Driver) Init() error {
+	u, err := d.Endpoint()
+	if err != nil {
+		return err
+	}
+	d.endpoint = &url.URL{Scheme: u.Scheme, Host: d.Policy.Bucket + "." + u.Host}
+	d.signer = &signer{accessKey: d.Policy.AccessKeyID, secretKey: d.Policy.SecretAccessKey}
+	return nil
+}
+
+// ObjectURL virtual host url like 'bucket.endpoint/key'
+func (d *Driver) ObjectURL(key string, query url.Values) *url.URL {
+	u := *d.endpoint
+	u.Path = "/" + key
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	return &u
+}
+
+// SignRequest sign by oss header signature over canonicalized resource
+func (d *Driver) SignRequest(req *http.Request, key string, body []byte) {
+	d.signer.sign(req, d.resource(key), time.Now())
+}
+
+func (d *Driver) Presign(key string, expires time.Duration) string {
+	u := d.ObjectURL(key, nil)
+	u.RawQuery = d.signer.presign(http.MethodGet, d.resource(key), nil, time.Now().Add(expires)).Encode()
+	return u.String()
+}
+
+// resource canonicalized resource of key, like '/bucket/key'
+func (d *Driver) resource(key string) string {
+	return "/" + d.Policy.Bucket + "/" + key
+}
@@ -0,0 +1,290 @@
+package oss
+
+import (
+	"bytes"
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/driver/objectstore"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOss in-process oss server of one bucket, verify header signature and signed url
+type fakeOss struct {
+	signer  *signer
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	// internalError copy object and complete multipart upload response 200 with error body
+	internalError bool
+}
+
+func newFakeOss(bucket string, s *signer) *fakeOss {
+	return &fakeOss{signer: s, bucket: bucket, objects: make(map[string][]byte), uploads: make(map[string]map[int][]byte)}
+}
+
+func (f *fakeOss) verify(r *http.Request, key string) bool {
+	resource := "/" + f.bucket + "/" + key
+	query := r.URL.Query()
+	if signature := query.Get("Signature"); signature != "" {
+		stringToSign := strings.Join([]string{r.Method, "", "", query.Get("Expires"), canonicalResource(resource, query)}, "\n")
+		return signature == f.signer.signature(stringToSign)
+	}
+	stringToSign := strings.Join([]string{
+		r.Method, r.Header.Get("Content-MD5"), r.Header.Get("Content-Type"), r.Header.Get("Date"),
+		canonicalHeaders(r.Header) + canonicalResource(resource, query),
+	}, "\n")
+	return r.Header.Get("Authorization") == "OSS "+f.signer.accessKey+":"+f.signer.signature(stringToSign)
+}
+
+func (f *fakeOss) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Host, f.bucket+".") {
+		f.error(w, http.StatusBadRequest, "InvalidBucketName")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if !f.verify(r, key) {
+		f.error(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, query.Get("prefix"), query.Get("delimiter"))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadId := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[uploadId] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadId)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		f.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", `"`+strconv.Itoa(number)+`"`)
+	case f.internalError && (r.Method == http.MethodPost && query.Has("uploadId") || r.Header.Get("X-Oss-Copy-Source") != ""):
+		fmt.Fprint(w, "<Error><Code>InternalError</Code><Message>We encountered an internal error</Message><RequestId>fake</RequestId></Error>")
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.uploads[query.Get("uploadId")]
+		content := make([]byte, 0)
+		for number := 1; number <= len(parts); number++ {
+			content = append(content, parts[number]...)
+		}
+		f.objects[key] = content
+		delete(f.uploads, query.Get("uploadId"))
+	case r.Method == http.MethodPut && r.Header.Get("X-Oss-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Oss-Copy-Source"))
+		content, ok := f.objects[strings.TrimPrefix(source, "/"+f.bucket+"/")]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		f.objects[key] = content
+		fmt.Fprint(w, "<CopyObjectResult></CopyObjectResult>")
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		content, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			content = content[start:]
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeOss) list(w http.ResponseWriter, prefix string, delimiter string) {
+	keys := make([]string, 0)
+	prefixes := make(map[string]bool)
+	for key := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, prefix)
+		if delimiter != "" && strings.Contains(rest, delimiter) {
+			prefixes[prefix+rest[:strings.Index(rest, delimiter)+1]] = true
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf := &bytes.Buffer{}
+	buf.WriteString("<ListBucketResult><IsTruncated>false</IsTruncated>")
+	for _, key := range keys {
+		fmt.Fprintf(buf, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>%s</LastModified></Contents>", key, len(f.objects[key]), time.Now().UTC().Format(time.RFC3339))
+	}
+	for commonPrefix := range prefixes {
+		fmt.Fprintf(buf, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", commonPrefix)
+	}
+	buf.WriteString("</ListBucketResult>")
+	w.Write(buf.Bytes())
+}
+
+func (f *fakeOss) error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(&objectstore.Error{Code: code, Message: code, RequestId: "fake"})
+}
+
+// newTestDriver dial every virtual host to in-process fake server
+func newTestDriver(t *testing.T) (*Driver, *fakeOss) {
+	policy := &storage.Policy{Mode: storage.AliyunMode, AccessKeyID: "ak", SecretAccessKey: "sk", Bucket: "fusion", BaseDir: "blog"}
+	fake := newFakeOss("fusion", &signer{accessKey: "ak", secretKey: "sk"})
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	policy.Endpoint = server.URL
+	d := NewOssDriver(policy).(*Driver)
+	assert.NoError(t, d.Init())
+	dialer := &net.Dialer{}
+	d.Client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+	return d, fake
+}
+
+func TestDriver_UploadDownload(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newTestDriver(t)
+	asserts := assert.New(t)
+
+	err := d.Upload(ctx, &storage.FileStream{SavePath: "/fusion/a.txt", File: io.NopCloser(strings.NewReader("hello oss"))})
+	asserts.NoError(err)
+	asserts.Equal("hello oss", string(fake.objects["blog/fusion/a.txt"]))
+
+	content, err := d.Download(ctx, "/fusion/a.txt")
+	asserts.NoError(err)
+	size, err := content.Seek(0, io.SeekEnd)
+	asserts.NoError(err)
+	asserts.Equal(int64(9), size)
+	_, err = content.Seek(6, io.SeekStart)
+	asserts.NoError(err)
+	text, err := io.ReadAll(content)
+	asserts.NoError(err)
+	asserts.Equal("oss", string(text))
+	asserts.NoError(content.Close())
+
+	_, err = d.Download(ctx, "/fusion/not-exist.txt")
+	asserts.Error(err)
+}
+
+func TestDriver_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newTestDriver(t)
+	large := bytes.Repeat([]byte("fusion"), objectstore.PartSize/2)
+	err := d.Upload(ctx, &storage.FileStream{SavePath: "/large.bin", File: io.NopCloser(bytes.NewReader(large))})
+	assert.NoError(t, err)
+	assert.Equal(t, large, fake.objects["blog/large.bin"])
+	assert.Empty(t, fake.uploads)
+}
+
+func TestDriver_ListRemove(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newTestDriver(t)
+	asserts := assert.New(t)
+	for _, path := range []string{"/list/a.txt", "/list/sub/b.txt", "/list/sub/inner/c.txt"} {
+		asserts.NoError(d.Upload(ctx, &storage.FileStream{SavePath: path, File: io.NopCloser(strings.NewReader(path))}))
+	}
+
+	objects, err := d.List(ctx, "/list", false)
+	asserts.NoError(err)
+	asserts.Len(objects, 2)
+
+	objects, err = d.List(ctx, "/list", true)
+	asserts.NoError(err)
+	asserts.Len(objects, 3)
+
+	failed, err := d.Remove(ctx, []string{"/list/a.txt", "/list/sub/b.txt"})
+	asserts.NoError(err)
+	asserts.Empty(failed)
+	objects, err = d.List(ctx, "/list", true)
+	asserts.NoError(err)
+	asserts.Len(objects, 1)
+	asserts.Equal("sub/inner/c.txt", objects[0].RelativePath)
+}
+
+func TestDriver_Dir(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newTestDriver(t)
+	asserts := assert.New(t)
+
+	asserts.NoError(d.Mkdir(ctx, "/dir/empty"))
+	asserts.Contains(fake.objects, "blog/dir/empty/")
+	asserts.NoError(d.Upload(ctx, &storage.FileStream{SavePath: "/dir/a.txt", File: io.NopCloser(strings.NewReader("a"))}))
+	asserts.NoError(d.Move(ctx, "/dir/a.txt", "/dir/empty/c.txt"))
+	asserts.NotContains(fake.objects, "blog/dir/a.txt")
+	asserts.Equal("a", string(fake.objects["blog/dir/empty/c.txt"]))
+
+	objects, err := d.List(ctx, "/dir", true)
+	asserts.NoError(err)
+	asserts.Len(objects, 2)
+
+	asserts.NoError(d.RemoveDir(ctx, "/dir"))
+	asserts.Empty(fake.objects)
+}
+
+func TestDriver_Thumb(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newTestDriver(t)
+	asserts := assert.New(t)
+	asserts.NoError(d.Upload(ctx, &storage.FileStream{SavePath: "/fusion/a.png._thumb", File: io.NopCloser(strings.NewReader("thumb"))}))
+
+	resp, err := d.Thumb(ctx, &storage.FileHeader{FilePath: "/fusion/a.png"})
+	asserts.NoError(err)
+	asserts.True(resp.Redirect)
+	asserts.Contains(resp.URL, "Signature=")
+
+	signed, err := d.Client.Get(resp.URL)
+	asserts.NoError(err)
+	defer signed.Body.Close()
+	asserts.Equal(http.StatusOK, signed.StatusCode)
+	content, _ := io.ReadAll(signed.Body)
+	asserts.Equal("thumb", string(content))
+}
+
+func TestDriver_ErrorBodyWithOk(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newTestDriver(t)
+	asserts := assert.New(t)
+	asserts.NoError(d.Upload(ctx, &storage.FileStream{SavePath: "/a.txt", File: io.NopCloser(strings.NewReader("a"))}))
+	fake.internalError = true
+
+	err := d.Move(ctx, "/a.txt", "/b.txt")
+	storeErr := &objectstore.Error{}
+	asserts.ErrorAs(err, &storeErr)
+	asserts.Equal("InternalError", storeErr.Code)
+	asserts.Contains(fake.objects, "blog/a.txt")
+
+	uploadId, err := d.InitMultipart(ctx, "/large.bin")
+	asserts.NoError(err)
+	etag, err := d.UploadPart(ctx, "/large.bin", uploadId, 1, []byte("part"))
+	asserts.NoError(err)
+	err = d.CompleteMultipart(ctx, "/large.bin", uploadId, []storage.Part{{Number: 1, ETag: etag, Size: 4}})
+	asserts.ErrorAs(err, &storeErr)
+	asserts.Equal("InternalError", storeErr.Code)
+}
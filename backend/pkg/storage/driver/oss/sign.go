@@ -0,0 +1,108 @@
+package oss
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subResources query parameters that must be included in canonicalized resource
+var subResources = map[string]bool{
+	"acl": true, "uploads": true, "location": true, "cors": true, "logging": true, "website": true,
+	"referer": true, "lifecycle": true, "delete": true, "append": true, "tagging": true, "objectMeta": true,
+	"uploadId": true, "partNumber": true, "security-token": true, "position": true, "img": true, "style": true,
+	"styleName": true, "replication": true, "replicationProgress": true, "replicationLocation": true,
+	"cname": true, "bucketInfo": true, "comp": true, "qos": true, "live": true, "status": true, "vod": true,
+	"startTime": true, "endTime": true, "symlink": true, "x-oss-process": true,
+	"response-content-type": true, "response-content-language": true, "response-expires": true,
+	"response-cache-control": true, "response-content-disposition": true, "response-content-encoding": true,
+}
+
+// signer implement oss header signature and signed url
+type signer struct {
+	accessKey string
+	secretKey string
+}
+
+// sign add date and authorization header into req, resource is '/bucket/key'
+func (s *signer) sign(req *http.Request, resource string, t time.Time) {
+	req.Header.Set("Date", t.UTC().Format(http.TimeFormat))
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		req.Header.Get("Date"),
+		canonicalHeaders(req.Header) + canonicalResource(resource, req.URL.Query()),
+	}, "\n")
+	req.Header.Set("Authorization", "OSS "+s.accessKey+":"+s.signature(stringToSign))
+}
+
+// presign returns signed url query that can be requested without credential until expires
+func (s *signer) presign(method string, resource string, query url.Values, expires time.Time) url.Values {
+	signed := url.Values{}
+	for key, values := range query {
+		signed[key] = values
+	}
+	expiresValue := strconv.FormatInt(expires.Unix(), 10)
+	stringToSign := strings.Join([]string{method, "", "", expiresValue, canonicalResource(resource, query)}, "\n")
+	signed.Set("OSSAccessKeyId", s.accessKey)
+	signed.Set("Expires", expiresValue)
+	signed.Set("Signature", s.signature(stringToSign))
+	return signed
+}
+
+func (s *signer) signature(stringToSign string) string {
+	h := hmac.New(sha1.New, []byte(s.secretKey))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// canonicalHeaders all x-oss-* headers sorted by lower case name
+func canonicalHeaders(header http.Header) string {
+	headers := make(map[string]string)
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-oss-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	builder := strings.Builder{}
+	for _, name := range names {
+		builder.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return builder.String()
+}
+
+// canonicalResource append sorted sub resources to resource
+func canonicalResource(resource string, query url.Values) string {
+	keys := make([]string, 0)
+	for key := range query {
+		if subResources[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return resource
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if value := query.Get(key); value != "" {
+			pairs = append(pairs, key+"="+value)
+		} else {
+			pairs = append(pairs, key)
+		}
+	}
+	return resource + "?" + strings.Join(pairs, "&")
+}
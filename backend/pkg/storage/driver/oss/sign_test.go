@@ -0,0 +1,28 @@
+package oss
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// example from https://help.aliyun.com/document_detail/31951.html
+func TestSigner_Sign(t *testing.T) {
+	s := &signer{accessKey: "44CF9590006BF252F707", secretKey: "OtxrzxIsfpFjA7SwPzILwy8Bw21TLhquhboDYROV"}
+	req, err := http.NewRequest(http.MethodPut, "https://oss-example.oss-cn-hangzhou.aliyuncs.com/nelson", strings.NewReader(""))
+	assert.NoError(t, err)
+	req.Header.Set("Content-MD5", "ODBGOERFMDMzQTczRUY3NUE3NzA5QzdFNUYzMDQxNEM=")
+	req.Header.Set("Content-Type", "text/html")
+	req.Header.Set("X-OSS-Meta-Author", "foo@bar.com")
+	req.Header.Set("X-OSS-Magic", "abracadabra")
+	s.sign(req, "/oss-example/nelson", time.Date(2005, 11, 17, 18, 49, 58, 0, time.UTC))
+	assert.Equal(t, "Thu, 17 Nov 2005 18:49:58 GMT", req.Header.Get("Date"))
+	assert.Equal(t, "OSS 44CF9590006BF252F707:26NBxoKdsyly4EDv6inkoDft/yA=", req.Header.Get("Authorization"))
+}
+
+func TestCanonicalResource(t *testing.T) {
+	query := map[string][]string{"uploadId": {"0004B999EF"}, "partNumber": {"1"}, "prefix": {"a/"}, "uploads": {""}}
+	assert.Equal(t, "/bucket/key?partNumber=1&uploadId=0004B999EF&uploads", canonicalResource("/bucket/key", query))
+}
@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/driver"
+	"cc.allio/fusion/pkg/storage/driver/objectstore"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const defaultRegion = "us-east-1"
+
+var awsHostRegexp = regexp.MustCompile(`^s3[.-]([a-z0-9-]+)\.amazonaws\.com$`)
+
+var dialect = objectstore.Dialect{Name: "s3", CopySourceHeader: "X-Amz-Copy-Source", RequestIdHeader: "X-Amz-Request-Id", ListV2: true}
+
+// Driver speak s3 rest api with path-style addressing, compatible with minio and other object stores
+type Driver struct {
+	*objectstore.Driver
+	endpoint *url.URL
+	signer   *signer
+}
+
+func NewS3Driver(policy *storage.Policy) driver.Handler {
+	d := &Driver{Driver: objectstore.NewDriver(policy, dialect)}
+	d.Signer = d
+	return d
+}
+
+func (d *Driver) Init() error {
+	u, err := d.Endpoint()
+	if err != nil {
+		return err
+	}
+	region := d.Policy.Region
+	if region == "" {
+		region = defaultRegion
+		if matches := awsHostRegexp.FindStringSubmatch(u.Hostname()); matches != nil {
+			region = matches[1]
+		}
+	}
+	d.endpoint = &url.URL{Scheme: u.Scheme, Host: u.Host}
+	d.signer = &signer{accessKey: d.Policy.AccessKeyID, secretKey: d.Policy.SecretAccessKey, region: region}
+	return nil
+}
+
+// ObjectURL path-style url like 'endpoint/bucket/key'
+func (d *Driver) ObjectURL(key string, query url.Values) *url.URL {
+	u := *d.endpoint
+	u.Path = "/" + d.Policy.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	return &u
+}
+
+// SignRequest sign by AWS Signature Version 4 with payload hash
+func (d *Driver) SignRequest(req *http.Request, key string, body []byte) {
+	d.signer.sign(req, hashHex(body), time.Now())
+}
+
+func (d *Driver) Presign(key string, expires time.Duration) string {
+	return d.signer.presign(http.MethodGet, d.ObjectURL(key, nil), expires, time.Now())
+}
@@ -0,0 +1,257 @@
+package s3
+
+import (
+	"bytes"
+	"cc.allio/fusion/pkg/storage"
+	"cc.allio/fusion/pkg/storage/driver/objectstore"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 in-process s3 server that support object, multipart and ListObjectsV2 api of one bucket
+type fakeS3 struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: make(map[string][]byte), uploads: make(map[string]map[int][]byte)}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), algorithm) && r.URL.Query().Get("X-Amz-Signature") == "" {
+		f.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"+f.bucket), "/")
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, query.Get("prefix"), query.Get("delimiter"))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadId := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[uploadId] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadId)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		f.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", `"`+strconv.Itoa(number)+`"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.uploads[query.Get("uploadId")]
+		content := make([]byte, 0)
+		for number := 1; number <= len(parts); number++ {
+			content = append(content, parts[number]...)
+		}
+		f.objects[key] = content
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		content, ok := f.objects[strings.TrimPrefix(source, "/"+f.bucket+"/")]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		f.objects[key] = content
+		fmt.Fprint(w, "<CopyObjectResult></CopyObjectResult>")
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		content, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			content = content[start:]
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string, delimiter string) {
+	keys := make([]string, 0)
+	prefixes := make(map[string]bool)
+	for key := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, prefix)
+		if delimiter != "" && strings.Contains(rest, delimiter) {
+			prefixes[prefix+rest[:strings.Index(rest, delimiter)+1]] = true
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf := &bytes.Buffer{}
+	buf.WriteString("<ListBucketResult><IsTruncated>false</IsTruncated>")
+	for _, key := range keys {
+		fmt.Fprintf(buf, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>%s</LastModified></Contents>", key, len(f.objects[key]), time.Now().UTC().Format(time.RFC3339))
+	}
+	for commonPrefix := range prefixes {
+		fmt.Fprintf(buf, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", commonPrefix)
+	}
+	buf.WriteString("</ListBucketResult>")
+	w.Write(buf.Bytes())
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(&objectstore.Error{Code: code, Message: code})
+}
+
+func newTestDriver(t *testing.T) (*Driver, *fakeS3) {
+	fake := newFakeS3("fusion")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	policy := &storage.Policy{Mode: storage.MinioMode, Endpoint: server.URL, AccessKeyID: "minio", SecretAccessKey: "minio123", Bucket: "fusion", BaseDir: "blog"}
+	d := NewS3Driver(policy).(*Driver)
+	assert.NoError(t, d.Init())
+	return d, fake
+}
+
+func TestDriver_UploadDownload(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newTestDriver(t)
+	asserts := assert.New(t)
+
+	err := d.Upload(ctx, &storage.FileStream{SavePath: "/fusion/a.txt", File: io.NopCloser(strings.NewReader("hello s3"))})
+	asserts.NoError(err)
+	asserts.Equal("hello s3", string(fake.objects["blog/fusion/a.txt"]))
+
+	content, err := d.Download(ctx, "/fusion/a.txt")
+	asserts.NoError(err)
+	size, err := content.Seek(0, io.SeekEnd)
+	asserts.NoError(err)
+	asserts.Equal(int64(8), size)
+	_, err = content.Seek(6, io.SeekStart)
+	asserts.NoError(err)
+	text, err := io.ReadAll(content)
+	asserts.NoError(err)
+	asserts.Equal("s3", string(text))
+	asserts.NoError(content.Close())
+
+	_, err = d.Download(ctx, "/fusion/not-exist.txt")
+	asserts.Error(err)
+}
+
+func TestDriver_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newTestDriver(t)
+	large := bytes.Repeat([]byte("fusion"), objectstore.PartSize/3)
+	err := d.Upload(ctx, &storage.FileStream{SavePath: "/large.bin", File: io.NopCloser(bytes.NewReader(large))})
+	assert.NoError(t, err)
+	assert.Equal(t, large, fake.objects["blog/large.bin"])
+	assert.Empty(t, fake.uploads)
+}
+
+func TestDriver_ListRemove(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newTestDriver(t)
+	asserts := assert.New(t)
+	for _, path := range []string{"/list/a.txt", "/list/sub/b.txt", "/list/sub/inner/c.txt"} {
+		asserts.NoError(d.Upload(ctx, &storage.FileStream{SavePath: path, File: io.NopCloser(strings.NewReader(path))}))
+	}
+
+	objects, err := d.List(ctx, "/list", false)
+	asserts.NoError(err)
+	asserts.Len(objects, 2)
+
+	objects, err = d.List(ctx, "/list", true)
+	asserts.NoError(err)
+	asserts.Len(objects, 3)
+
+	failed, err := d.Remove(ctx, []string{"/list/a.txt", "/list/sub/b.txt"})
+	asserts.NoError(err)
+	asserts.Empty(failed)
+	objects, err = d.List(ctx, "/list", true)
+	asserts.NoError(err)
+	asserts.Len(objects, 1)
+	asserts.Equal("sub/inner/c.txt", objects[0].RelativePath)
+}
+
+func TestDriver_Dir(t *testing.T) {
+	ctx := context.Background()
+	d, fake := newTestDriver(t)
+	asserts := assert.New(t)
+
+	asserts.NoError(d.Mkdir(ctx, "/dir/empty"))
+	asserts.Contains(fake.objects, "blog/dir/empty/")
+	asserts.NoError(d.Upload(ctx, &storage.FileStream{SavePath: "/dir/a b.txt", File: io.NopCloser(strings.NewReader("a"))}))
+	asserts.NoError(d.Move(ctx, "/dir/a b.txt", "/dir/empty/c.txt"))
+	asserts.NotContains(fake.objects, "blog/dir/a b.txt")
+	asserts.Equal("a", string(fake.objects["blog/dir/empty/c.txt"]))
+
+	objects, err := d.List(ctx, "/dir", true)
+	asserts.NoError(err)
+	asserts.Len(objects, 2)
+	dirs := lo.Filter(objects, func(object storage.Object, _ int) bool { return object.IsDir })
+	asserts.Len(dirs, 1)
+	asserts.Equal("empty", dirs[0].RelativePath)
+
+	asserts.NoError(d.RemoveDir(ctx, "/dir"))
+	asserts.Empty(fake.objects)
+}
+
+func TestDriver_Thumb(t *testing.T) {
+	d, _ := newTestDriver(t)
+	resp, err := d.Thumb(context.Background(), &storage.FileHeader{FilePath: "/fusion/a.png"})
+	assert.NoError(t, err)
+	assert.True(t, resp.Redirect)
+	assert.Contains(t, resp.URL, "/fusion/blog/fusion/a.png._thumb?")
+	assert.Contains(t, resp.URL, "X-Amz-Signature=")
+
+	presigned, err := http.Get(resp.URL)
+	assert.NoError(t, err)
+	presigned.Body.Close()
+	assert.Equal(t, http.StatusNotFound, presigned.StatusCode)
+}
+
+func TestDriver_Region(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		region   string
+		expected string
+	}{
+		{"http://127.0.0.1:9000", "", defaultRegion},
+		{"http://127.0.0.1:9000", "cn-north-1", "cn-north-1"},
+		{"https://s3.eu-west-2.amazonaws.com", "", "eu-west-2"},
+		{"https://s3.eu-west-2.amazonaws.com", "eu-central-1", "eu-central-1"},
+	}
+	for _, c := range cases {
+		d := NewS3Driver(&storage.Policy{Mode: storage.MinioMode, Endpoint: c.endpoint, Bucket: "fusion", Region: c.region}).(*Driver)
+		assert.NoError(t, d.Init())
+		assert.Equal(t, c.expected, d.signer.region, c.endpoint)
+	}
+}
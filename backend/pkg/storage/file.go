@@ -3,7 +3,13 @@ package storage
 import "io"
 
 type FileHeader struct {
+	// Thumb thumbnail size name, empty is default thumbnail
+	Thumb string
+	// Hash hex sha256 of file content, file path will be addressed by it when not empty
+	Hash     string
 	FilePath string
+	// SavePath reality path that file saved to, it is generated by storage.Policy when empty
+	SavePath string
 	Filename string
 	Header   map[string][]string
 	Size     uint64
@@ -13,5 +19,8 @@ type FileHeader struct {
 
 // ThumbFile returns thumb file name
 func (f *FileHeader) ThumbFile() string {
-	return f.FilePath + "._thumb"
+	if f.Thumb == "" {
+		return f.FilePath + "._thumb"
+	}
+	return f.FilePath + "._thumb_" + f.Thumb
 }
@@ -4,6 +4,8 @@ import (
 	"cc.allio/fusion/pkg/storage"
 	"cc.allio/fusion/pkg/storage/driver"
 	"cc.allio/fusion/pkg/storage/driver/local"
+	"cc.allio/fusion/pkg/storage/driver/oss"
+	"cc.allio/fusion/pkg/storage/driver/s3"
 	"errors"
 )
 
@@ -22,6 +24,10 @@ func (fs *FileSystem) LoadHandler() error {
 	switch fs.Policy.Mode {
 	case storage.LocalMode:
 		handler = local.NewLocalDriver(fs.Policy)
+	case storage.MinioMode:
+		handler = s3.NewS3Driver(fs.Policy)
+	case storage.AliyunMode:
+		handler = oss.NewOssDriver(fs.Policy)
 	}
 	if handler == nil {
 		return errors.New("not found any policy load handler")
@@ -26,12 +26,20 @@ type RSCloser interface {
 	io.Closer
 }
 
-// ContentResponse file content
+// ContentResponse file content, when Redirect is true client should be redirected to URL
 type ContentResponse struct {
-	Redirect bool
-	Content  RSCloser
-	URL      string
-	MaxAge   int
+	Redirect     bool
+	Content      RSCloser
+	URL          string
+	MaxAge       int
+	LastModified time.Time
+}
+
+// Part uploaded part of multipart upload, number starts from 1
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
 }
 
 // Object list file object
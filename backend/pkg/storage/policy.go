@@ -10,7 +10,8 @@ const (
 	AliyunMode = "aliyun"
 )
 
-const osPathPrefix = "/fusion"
+// OsPathPrefix prefix of all uploaded file path
+const OsPathPrefix = "/fusion"
 
 type Policy struct {
 	Mode            PolicyMode `json:"mode"`
@@ -19,11 +20,18 @@ type Policy struct {
 	SecretAccessKey string     `json:"secretAccessKey"`
 	Bucket          string     `json:"bucket"`
 	BaseDir         string     `json:"baseDir"`
+	Region          string     `json:"region"`
 }
 
-// GenerateOsPath on FileHeader, the path will be os reality path
+// hashPathLen length of hash prefix directory, same named files on one day will not overwrite each other
+const hashPathLen = 16
+
+// GenerateOsPath on FileHeader, the path will be os reality path like '/fusion/2023-01-01/<hash prefix>/a.png'
 func (p *Policy) GenerateOsPath(f *FileHeader) string {
 	path := f.FilePath
 	date := time.Now().Format(time.DateOnly)
-	return osPathPrefix + "/" + date + path
+	if len(f.Hash) >= hashPathLen {
+		return OsPathPrefix + "/" + date + "/" + f.Hash[:hashPathLen] + path
+	}
+	return OsPathPrefix + "/" + date + path
 }